@@ -0,0 +1,140 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderClientID = "X-Client-ID"
+	// HeaderUserID names the user a request acts for. It counts as part of
+	// the request, so that one user's response is never replayed to another.
+	HeaderUserID = "X-User-ID"
+	// HeaderReplayed is set on responses served from a stored record.
+	HeaderReplayed = "Idempotent-Replayed"
+)
+
+// Record is the first response to a request carrying an Idempotency-Key,
+// scoped to the client that sent it.
+type Record struct {
+	ClientID    string
+	Key         string
+	RequestHash string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	Completed   bool
+}
+
+type Storer interface {
+	// ReserveIdempotencyKey claims r.ClientID and r.Key for a new request.
+	// When the key is already taken it returns the existing record and false.
+	ReserveIdempotencyKey(r Record) (Record, bool, error)
+	// CompleteIdempotencyKey stores the response of a reserved request.
+	CompleteIdempotencyKey(r Record) error
+	// ReleaseIdempotencyKey drops a reservation whose request failed so that
+	// the client can retry it.
+	ReleaseIdempotencyKey(clientID, key string) error
+}
+
+// Middleware makes the wrapped handler safe to retry. The first response to
+// a key is stored and replayed verbatim for repeats; reusing a key with a
+// different request is rejected with 422 and a repeat of a request that is
+// still running, or never finished, with 409. Server errors are not stored.
+func Middleware(store Storer) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderKey)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > 255 {
+				return c.JSON(http.StatusBadRequest, echo.Map{"message": HeaderKey + " must be at most 255 characters"})
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			r := Record{ClientID: clientID(c), Key: key, RequestHash: requestHash(c.Request(), body)}
+			existing, reserved, err := store.ReserveIdempotencyKey(r)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+			}
+			if !reserved {
+				return replay(c, r, existing)
+			}
+
+			rec := &recorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = rec
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			if status >= http.StatusInternalServerError {
+				if err := store.ReleaseIdempotencyKey(r.ClientID, r.Key); err != nil {
+					c.Logger().Error(err)
+				}
+				return nil
+			}
+			r.StatusCode = status
+			r.ContentType = c.Response().Header().Get(echo.HeaderContentType)
+			r.Body = rec.body.Bytes()
+			if err := store.CompleteIdempotencyKey(r); err != nil {
+				c.Logger().Error(err)
+			}
+			return nil
+		}
+	}
+}
+
+func replay(c echo.Context, r, existing Record) error {
+	if existing.RequestHash != r.RequestHash {
+		return c.JSON(http.StatusUnprocessableEntity, echo.Map{"message": HeaderKey + " was already used for a different request"})
+	}
+	if !existing.Completed {
+		return c.JSON(http.StatusConflict, echo.Map{"message": "a request with this " + HeaderKey + " is still in progress"})
+	}
+	c.Response().Header().Set(HeaderReplayed, "true")
+	if existing.ContentType == "" {
+		return c.NoContent(existing.StatusCode)
+	}
+	return c.Blob(existing.StatusCode, existing.ContentType, existing.Body)
+}
+
+// clientID scopes keys so that two clients cannot see each other's
+// responses. Callers without an X-Client-ID are told apart by address.
+func clientID(c echo.Context) string {
+	if id := c.Request().Header.Get(HeaderClientID); id != "" {
+		return id
+	}
+	return c.RealIP()
+}
+
+func requestHash(req *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, req.Method+" "+req.URL.RequestURI()+"\n")
+	io.WriteString(h, HeaderUserID+": "+req.Header.Get(HeaderUserID)+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder keeps a copy of the response body while it is written out.
+type recorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
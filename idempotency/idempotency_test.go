@@ -0,0 +1,145 @@
+//go:build unit
+
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type StubStore struct {
+	records map[string]Record
+}
+
+func (s *StubStore) ReserveIdempotencyKey(r Record) (Record, bool, error) {
+	if existing, ok := s.records[r.ClientID+r.Key]; ok {
+		return existing, false, nil
+	}
+	s.records[r.ClientID+r.Key] = r
+	return Record{}, true, nil
+}
+
+func (s *StubStore) CompleteIdempotencyKey(r Record) error {
+	r.Completed = true
+	s.records[r.ClientID+r.Key] = r
+	return nil
+}
+
+func (s *StubStore) ReleaseIdempotencyKey(clientID, key string) error {
+	delete(s.records, clientID+key)
+	return nil
+}
+
+func TestMiddleware(t *testing.T) {
+	serve := func(store Storer, calls *int, status int, key, body string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderClientID, "client-1")
+		if key != "" {
+			req.Header.Set(HeaderKey, key)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := Middleware(store)(func(c echo.Context) error {
+			*calls++
+			return c.JSON(status, echo.Map{"call": *calls})
+		})
+		h(c)
+		return rec
+	}
+
+	t.Run("given repeated key should replay first response without calling handler", func(t *testing.T) {
+		store := &StubStore{records: map[string]Record{}}
+		calls := 0
+
+		first := serve(store, &calls, http.StatusCreated, "k1", `{"amount": 100}`)
+		second := serve(store, &calls, http.StatusCreated, "k1", `{"amount": 100}`)
+
+		if calls != 1 {
+			t.Errorf("expected handler to run once but ran %d times", calls)
+		}
+		if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+			t.Errorf("expected replay of %d %s but got %d %s", first.Code, first.Body, second.Code, second.Body)
+		}
+		if second.Header().Get(HeaderReplayed) != "true" {
+			t.Errorf("expected %s header on replay", HeaderReplayed)
+		}
+	})
+
+	t.Run("given key reused with different body should return 422", func(t *testing.T) {
+		store := &StubStore{records: map[string]Record{}}
+		calls := 0
+
+		serve(store, &calls, http.StatusCreated, "k1", `{"amount": 100}`)
+		rec := serve(store, &calls, http.StatusCreated, "k1", `{"amount": 200}`)
+
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d but got %d", http.StatusUnprocessableEntity, rec.Code)
+		}
+	})
+
+	t.Run("given key reused by another user should return 422 without replaying", func(t *testing.T) {
+		store := &StubStore{records: map[string]Record{}}
+		calls := 0
+		h := Middleware(store)(func(c echo.Context) error {
+			calls++
+			return c.JSON(http.StatusCreated, echo.Map{"user": c.Request().Header.Get(HeaderUserID)})
+		})
+		var rec *httptest.ResponseRecorder
+		for _, user := range []string{"1", "2"} {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", strings.NewReader(`{"amount": 100}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(HeaderClientID, "client-1")
+			req.Header.Set(HeaderKey, "k1")
+			req.Header.Set(HeaderUserID, user)
+			rec = httptest.NewRecorder()
+			h(echo.New().NewContext(req, rec))
+		}
+
+		if rec.Code != http.StatusUnprocessableEntity || calls != 1 {
+			t.Errorf("expected status code %d after 1 call but got %d after %d calls", http.StatusUnprocessableEntity, rec.Code, calls)
+		}
+	})
+
+	t.Run("given request still in progress should return 409", func(t *testing.T) {
+		store := &StubStore{records: map[string]Record{}}
+		calls := 0
+		store.ReserveIdempotencyKey(Record{ClientID: "client-1", Key: "k1", RequestHash: requestHash(httptest.NewRequest(http.MethodPost, "/api/v1/transfers", nil), []byte(`{}`))})
+
+		rec := serve(store, &calls, http.StatusCreated, "k1", `{}`)
+
+		if rec.Code != http.StatusConflict || calls != 0 {
+			t.Errorf("expected status code %d without calling handler but got %d after %d calls", http.StatusConflict, rec.Code, calls)
+		}
+	})
+
+	t.Run("given server error should release key for retry", func(t *testing.T) {
+		store := &StubStore{records: map[string]Record{}}
+		calls := 0
+
+		serve(store, &calls, http.StatusInternalServerError, "k1", `{}`)
+		serve(store, &calls, http.StatusCreated, "k1", `{}`)
+
+		if calls != 2 {
+			t.Errorf("expected handler to run twice but ran %d times", calls)
+		}
+	})
+
+	t.Run("given no key should always call handler", func(t *testing.T) {
+		store := &StubStore{records: map[string]Record{}}
+		calls := 0
+
+		serve(store, &calls, http.StatusCreated, "", `{}`)
+		serve(store, &calls, http.StatusCreated, "", `{}`)
+
+		if calls != 2 {
+			t.Errorf("expected handler to run twice but ran %d times", calls)
+		}
+	})
+}
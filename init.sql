@@ -1,21 +1,409 @@
 -- Creation of product table
 CREATE TYPE wallet_type AS ENUM ('Savings', 'Credit Card', 'Crypto Wallet');
 
+-- Savings rates. annual_rate is a fraction, e.g. 0.015 for 1.5% a year.
+CREATE TABLE IF NOT EXISTS interest_products (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	annual_rate NUMERIC(9, 6) NOT NULL CHECK (annual_rate >= 0 AND annual_rate < 1),
+	day_count VARCHAR(8) NOT NULL DEFAULT 'ACT/365' CHECK (day_count IN ('ACT/365', 'ACT/360', 'ACT/ACT')),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO interest_products (name, annual_rate, day_count) VALUES ('Standard Savings', 0.015, 'ACT/365');
+
+-- Wallet owners. A user with wallets, even deleted ones, cannot be deleted.
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- House is the user holding the wallets fees are credited to.
+INSERT INTO users (name) VALUES ('John Doe'), ('Jane Doe'), ('House');
+
 CREATE TABLE IF NOT EXISTS user_wallet (
 	id SERIAL PRIMARY KEY,
-	user_id INT NOT NULL,
-	user_name VARCHAR(255) NOT NULL,
+	user_id INT NOT NULL REFERENCES users(id),
 	wallet_name VARCHAR(255) NOT NULL,
 	wallet_type wallet_type NOT NULL,
-	balance DECIMAL(10, 2) NOT NULL,
+	currency VARCHAR(10) NOT NULL DEFAULT 'THB',
+	-- Amounts are exact to 18 decimal places, the smallest unit of any
+	-- supported asset (wei for ETH); the API enforces each asset's precision.
+	balance NUMERIC(38, 18) NOT NULL,
+	-- How far below zero a Credit Card wallet may go; always 0 for other types.
+	credit_limit NUMERIC(38, 18) NOT NULL DEFAULT 0 CHECK (credit_limit >= 0),
+	interest_product_id INT REFERENCES interest_products(id),
+	-- Caps on money going out per transaction, UTC day and UTC month; NULL
+	-- means no limit.
+	limit_per_transaction NUMERIC(38, 18) CHECK (limit_per_transaction > 0),
+	limit_daily NUMERIC(38, 18) CHECK (limit_daily > 0),
+	limit_monthly NUMERIC(38, 18) CHECK (limit_monthly > 0),
+	-- Frozen wallets take no debits and closed wallets no movement at all.
+	status VARCHAR(16) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'frozen', 'closed')),
+	status_reason VARCHAR(255) NOT NULL DEFAULT '',
+	-- Soft delete: deleted wallets are hidden and can be restored until the
+	-- purge job removes them for good.
+	deleted_at TIMESTAMP,
+	deleted_by VARCHAR(255) NOT NULL DEFAULT '',
+	-- Bumped by every change to the row and served as the wallet's ETag.
+	version INT NOT NULL DEFAULT 1,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CHECK (credit_limit = 0 OR wallet_type = 'Credit Card'),
+	CHECK (interest_product_id IS NULL OR wallet_type = 'Savings')
+);
+
+CREATE INDEX IF NOT EXISTS user_wallet_user_idx ON user_wallet (user_id);
+
+INSERT INTO user_wallet (user_id, wallet_name, wallet_type, currency, balance, credit_limit, interest_product_id) VALUES
+(1, 'John Savings', 'Savings', 'THB', 1000.00, 0, 1),
+(1, 'John Credit Card', 'Credit Card', 'THB', 500.00, 50000.00, NULL),
+(1, 'John Crypto Wallet', 'Crypto Wallet', 'BTC', 0.00150000, 0, NULL),
+(2, 'Jane Savings', 'Savings', 'THB', 2000.00, 0, 1),
+(2, 'Jane Credit Card', 'Credit Card', 'THB', 1000.00, 50000.00, NULL),
+(2, 'Jane Crypto Wallet', 'Crypto Wallet', 'ETH', 0.250000000000000001, 0, NULL),
+(3, 'House THB Fees', 'Savings', 'THB', 0, 0, NULL),
+(3, 'House USD Fees', 'Savings', 'USD', 0, 0, NULL),
+(3, 'House EUR Fees', 'Savings', 'EUR', 0, 0, NULL),
+(3, 'House GBP Fees', 'Savings', 'GBP', 0, 0, NULL),
+(3, 'House SGD Fees', 'Savings', 'SGD', 0, 0, NULL),
+(3, 'House BTC Fees', 'Crypto Wallet', 'BTC', 0, 0, NULL),
+(3, 'House ETH Fees', 'Crypto Wallet', 'ETH', 0, 0, NULL),
+(3, 'House USDT Fees', 'Crypto Wallet', 'USDT', 0, 0, NULL);
+
+-- Users sharing a wallet. The wallet's user_id is always an owner; owners
+-- manage members, spenders may also move money out and viewers only look.
+CREATE TABLE IF NOT EXISTS wallet_members (
+	wallet_id INT NOT NULL REFERENCES user_wallet(id) ON DELETE CASCADE,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	role VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'spender', 'viewer')),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (wallet_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS wallet_members_user_idx ON wallet_members (user_id);
+
+INSERT INTO wallet_members (wallet_id, user_id, role) SELECT id, user_id, 'owner' FROM user_wallet;
+
+
+CREATE OR REPLACE FUNCTION bump_wallet_version() RETURNS trigger AS $$
+BEGIN
+	NEW.version := OLD.version + 1;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER user_wallet_version
+	BEFORE UPDATE ON user_wallet
+	FOR EACH ROW EXECUTE FUNCTION bump_wallet_version();
+
+-- Double-entry ledger. user_wallet.balance is a projection of ledger_entries
+-- and is only changed by posting a journal entry.
+CREATE TABLE IF NOT EXISTS journal_entries (
+	id SERIAL PRIMARY KEY,
+	type VARCHAR(32) NOT NULL,
+	reference VARCHAR(255) NOT NULL DEFAULT '',
+	description VARCHAR(255) NOT NULL,
+	-- Set on reversals to the entry they undo, in full or in part.
+	reversal_of INT REFERENCES journal_entries(id),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS journal_entries_reversal_of_idx ON journal_entries (reversal_of) WHERE reversal_of IS NOT NULL;
+
+-- wallet_id has no foreign key on purpose: ledger history outlives wallet rows.
+CREATE TABLE IF NOT EXISTS ledger_entries (
+	id SERIAL PRIMARY KEY,
+	journal_id INT NOT NULL REFERENCES journal_entries(id),
+	account VARCHAR(64) NOT NULL,
+	wallet_id INT,
+	currency VARCHAR(10) NOT NULL,
+	amount NUMERIC(38, 18) NOT NULL CHECK (amount <> 0),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CHECK ((account = 'wallet') = (wallet_id IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS ledger_entries_wallet_id_idx ON ledger_entries (wallet_id, journal_id DESC);
+-- Serves balances as of a past moment, which sum a wallet's lines up to it.
+CREATE INDEX IF NOT EXISTS ledger_entries_wallet_created_idx ON ledger_entries (wallet_id, created_at);
+
+CREATE OR REPLACE FUNCTION reject_ledger_change() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'ledger entries are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ledger_entries_immutable
+	BEFORE UPDATE OR DELETE ON ledger_entries
+	FOR EACH ROW EXECUTE FUNCTION reject_ledger_change();
+
+CREATE OR REPLACE FUNCTION check_journal_balanced() RETURNS trigger AS $$
+BEGIN
+	IF EXISTS (
+		SELECT 1 FROM ledger_entries
+		WHERE journal_id = NEW.journal_id
+		GROUP BY currency
+		HAVING SUM(amount) <> 0
+	) THEN
+		RAISE EXCEPTION 'journal entry % does not balance', NEW.journal_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Checked at commit so that all lines of a journal entry can be inserted first.
+CREATE CONSTRAINT TRIGGER ledger_entries_balanced
+	AFTER INSERT ON ledger_entries
+	DEFERRABLE INITIALLY DEFERRED
+	FOR EACH ROW EXECUTE FUNCTION check_journal_balanced();
+
+CREATE OR REPLACE VIEW wallet_ledger_balance AS
+	SELECT wallet_id, currency, SUM(amount) AS balance
+	FROM ledger_entries
+	WHERE account = 'wallet'
+	GROUP BY wallet_id, currency;
+
+INSERT INTO journal_entries (type, description) VALUES ('opening', 'Opening balance');
+
+INSERT INTO ledger_entries (journal_id, account, wallet_id, currency, amount)
+	SELECT 1, 'wallet', id, currency, balance FROM user_wallet WHERE balance <> 0;
+
+INSERT INTO ledger_entries (journal_id, account, currency, amount)
+	SELECT 1, 'equity', currency, -SUM(balance) FROM user_wallet GROUP BY currency HAVING SUM(balance) <> 0;
+
+-- First response to each Idempotency-Key, replayed for retries of the same request.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	client_id VARCHAR(255) NOT NULL,
+	key VARCHAR(255) NOT NULL,
+	request_hash CHAR(64) NOT NULL,
+	status_code INT,
+	content_type VARCHAR(255),
+	response_body BYTEA,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	completed_at TIMESTAMP,
+	PRIMARY KEY (client_id, key)
+);
+
+CREATE TYPE hold_status AS ENUM ('active', 'captured', 'released');
+
+-- Authorizations reserving part of a wallet's balance. A hold still 'active'
+-- after expires_at is expired and no longer reserves anything.
+CREATE TABLE IF NOT EXISTS holds (
+	id SERIAL PRIMARY KEY,
+	wallet_id INT NOT NULL REFERENCES user_wallet(id) ON DELETE CASCADE,
+	amount NUMERIC(38, 18) NOT NULL CHECK (amount > 0),
+	captured NUMERIC(38, 18) NOT NULL DEFAULT 0 CHECK (captured >= 0 AND captured <= amount),
+	currency VARCHAR(10) NOT NULL,
+	status hold_status NOT NULL DEFAULT 'active',
+	reference VARCHAR(255) NOT NULL DEFAULT '',
+	expires_at TIMESTAMP NOT NULL,
+	-- When the hold was captured or released, so past available balances
+	-- can tell which holds were reserving money at the time.
+	settled_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS holds_wallet_id_idx ON holds (wallet_id) WHERE status = 'active';
+
+CREATE TYPE schedule_frequency AS ENUM ('once', 'daily', 'weekly', 'monthly');
+CREATE TYPE schedule_status AS ENUM ('active', 'paused', 'completed', 'failed');
+
+-- Standing orders run as transfers by the server's scheduler. A scheduler
+-- claims a due row by setting claimed_until, so that other servers leave it
+-- alone until the run is recorded or the claim lapses.
+CREATE TABLE IF NOT EXISTS transfer_schedules (
+	id SERIAL PRIMARY KEY,
+	from_wallet_id INT NOT NULL REFERENCES user_wallet(id) ON DELETE CASCADE,
+	to_wallet_id INT NOT NULL REFERENCES user_wallet(id) ON DELETE CASCADE,
+	amount NUMERIC(38, 18) NOT NULL CHECK (amount > 0),
+	currency VARCHAR(10) NOT NULL,
+	reference VARCHAR(255) NOT NULL DEFAULT '',
+	frequency schedule_frequency NOT NULL,
+	start_at TIMESTAMP NOT NULL,
+	next_run_at TIMESTAMP NOT NULL,
+	status schedule_status NOT NULL DEFAULT 'active',
+	attempts INT NOT NULL DEFAULT 0,
+	max_retries INT NOT NULL DEFAULT 3,
+	-- Who created or last updated the order; every run needs them to be
+	-- allowed to spend from from_wallet_id.
+	created_by INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	claimed_until TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CHECK (from_wallet_id <> to_wallet_id)
+);
+
+CREATE INDEX IF NOT EXISTS transfer_schedules_due_idx ON transfer_schedules (next_run_at) WHERE status = 'active';
+
+CREATE TABLE IF NOT EXISTS schedule_executions (
+	id SERIAL PRIMARY KEY,
+	schedule_id INT NOT NULL REFERENCES transfer_schedules(id) ON DELETE CASCADE,
+	due_at TIMESTAMP NOT NULL,
+	attempt INT NOT NULL,
+	status VARCHAR(10) NOT NULL CHECK (status IN ('succeeded', 'retrying', 'failed')),
+	journal_id INT REFERENCES journal_entries(id),
+	error TEXT NOT NULL DEFAULT '',
+	executed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS schedule_executions_schedule_id_idx ON schedule_executions (schedule_id);
+
+-- The transfer booked for each run of a schedule, written in the transfer's
+-- own transaction so that a run recorded late, or claimed twice, is never
+-- paid twice.
+CREATE TABLE IF NOT EXISTS schedule_payments (
+	schedule_id INT NOT NULL REFERENCES transfer_schedules(id) ON DELETE CASCADE,
+	due_at TIMESTAMP NOT NULL,
+	journal_id INT NOT NULL REFERENCES journal_entries(id),
+	PRIMARY KEY (schedule_id, due_at)
+);
+
+-- Monthly credit card statements. closed_at is set once the due date has
+-- passed and any interest on the unpaid part has been charged.
+CREATE TABLE IF NOT EXISTS statements (
+	id SERIAL PRIMARY KEY,
+	wallet_id INT NOT NULL REFERENCES user_wallet(id) ON DELETE CASCADE,
+	currency VARCHAR(10) NOT NULL,
+	period_start TIMESTAMP NOT NULL,
+	period_end TIMESTAMP NOT NULL,
+	opening_balance NUMERIC(38, 18) NOT NULL,
+	purchases NUMERIC(38, 18) NOT NULL,
+	payments NUMERIC(38, 18) NOT NULL,
+	closing_balance NUMERIC(38, 18) NOT NULL,
+	minimum_payment NUMERIC(38, 18) NOT NULL,
+	due_date TIMESTAMP NOT NULL,
+	interest NUMERIC(38, 18) NOT NULL DEFAULT 0,
+	closed_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (wallet_id, period_start)
+);
+
+CREATE INDEX IF NOT EXISTS statements_due_idx ON statements (due_date) WHERE closed_at IS NULL;
+
+-- One row per wallet and day of interest, which makes accrual idempotent.
+-- Amounts keep 18 decimal places and are rounded only when capitalized.
+CREATE TABLE IF NOT EXISTS interest_accruals (
+	wallet_id INT NOT NULL REFERENCES user_wallet(id) ON DELETE CASCADE,
+	accrual_date DATE NOT NULL,
+	product_id INT NOT NULL REFERENCES interest_products(id),
+	currency VARCHAR(10) NOT NULL,
+	balance NUMERIC(38, 18) NOT NULL,
+	annual_rate NUMERIC(9, 6) NOT NULL,
+	amount NUMERIC(38, 18) NOT NULL,
+	capitalized_at TIMESTAMP,
+	journal_id INT REFERENCES journal_entries(id),
+	PRIMARY KEY (wallet_id, accrual_date)
+);
+
+CREATE INDEX IF NOT EXISTS interest_accruals_unpaid_idx ON interest_accruals (wallet_id) WHERE capitalized_at IS NULL;
+
+-- Tickets opened by reconciliation for wallets whose stored balance
+-- disagrees with their ledger. A wallet has at most one open ticket until
+-- someone has looked into it and marked it resolved, which books the
+-- difference to the suspense account in journal_id.
+CREATE TABLE IF NOT EXISTS balance_corrections (
+	id SERIAL PRIMARY KEY,
+	wallet_id INT NOT NULL REFERENCES user_wallet(id) ON DELETE CASCADE,
+	currency VARCHAR(10) NOT NULL,
+	stored_balance NUMERIC(38, 18) NOT NULL,
+	ledger_balance NUMERIC(38, 18) NOT NULL,
+	difference NUMERIC(38, 18) NOT NULL,
+	foreign_lines INT NOT NULL DEFAULT 0,
+	status VARCHAR(16) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+	journal_id INT REFERENCES journal_entries(id),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	resolved_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS balance_corrections_open_idx ON balance_corrections (wallet_id) WHERE status = 'open';
+
+-- Audit trail of wallet status changes and the reasons given for them.
+CREATE TABLE IF NOT EXISTS wallet_status_changes (
+	id SERIAL PRIMARY KEY,
+	wallet_id INT NOT NULL REFERENCES user_wallet(id) ON DELETE CASCADE,
+	from_status VARCHAR(16) NOT NULL,
+	to_status VARCHAR(16) NOT NULL,
+	reason VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS wallet_status_changes_wallet_id_idx ON wallet_status_changes (wallet_id, id DESC);
+
+-- Pricing of transfers and withdrawals. The newest rule for the wallet's
+-- type, or failing that for any type (wallet_type ''), applies. tiers is a
+-- JSON array of {from, flat, rate} in increasing order of from.
+CREATE TABLE IF NOT EXISTS fee_rules (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	operation VARCHAR(16) NOT NULL CHECK (operation IN ('transfer', 'withdrawal')),
+	wallet_type VARCHAR(32) NOT NULL DEFAULT '',
+	currency VARCHAR(10) NOT NULL,
+	flat NUMERIC(38, 18) NOT NULL DEFAULT 0 CHECK (flat >= 0),
+	rate NUMERIC(9, 6) CHECK (rate >= 0 AND rate < 1),
+	tiers JSONB NOT NULL DEFAULT '[]',
+	min_fee NUMERIC(38, 18) CHECK (min_fee >= 0),
+	max_fee NUMERIC(38, 18) CHECK (max_fee >= min_fee),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The house wallet fees in each currency are credited to. A fee in a
+-- currency without one cannot be charged.
+CREATE TABLE IF NOT EXISTS fee_wallets (
+	currency VARCHAR(10) PRIMARY KEY,
+	wallet_id INT NOT NULL REFERENCES user_wallet(id)
+);
+
+INSERT INTO fee_wallets (currency, wallet_id)
+	SELECT w.currency, w.id FROM user_wallet w JOIN users u ON u.id = w.user_id WHERE u.name = 'House';
+
+-- Categories of transactions. Those without a user are the default set
+-- every user shares; names are unique per user and against the defaults,
+-- ignoring case.
+CREATE TABLE IF NOT EXISTS categories (
+	id SERIAL PRIMARY KEY,
+	user_id INT REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(64) NOT NULL,
 	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
-INSERT INTO user_wallet (user_id, user_name, wallet_name, wallet_type, balance) VALUES
-(1, 'John Doe', 'John Savings', 'Savings', 1000.00),
-(1, 'John Doe', 'John Credit Card', 'Credit Card', 500.00),
-(1, 'John Doe', 'John Crypto Wallet', 'Crypto Wallet', 100.00),
-(2, 'Jane Doe', 'Jane Savings', 'Savings', 2000.00),
-(2, 'Jane Doe', 'Jane Credit Card', 'Credit Card', 1000.00),
-(2, 'Jane Doe', 'Jane Crypto Wallet', 'Crypto Wallet', 200.00);
+CREATE UNIQUE INDEX IF NOT EXISTS categories_name_idx ON categories (COALESCE(user_id, 0), lower(name));
+
+INSERT INTO categories (name) VALUES
+('Food'), ('Rent'), ('Salary'), ('Bills'), ('Shopping'), ('Transport'),
+('Transfers'), ('Fees'), ('Interest'), ('Refunds'), ('Other');
 
+-- Rules filing transactions nobody has categorized. Empty conditions match
+-- anything but a rule needs at least one. Rules without a user are defaults.
+CREATE TABLE IF NOT EXISTS category_rules (
+	id SERIAL PRIMARY KEY,
+	user_id INT REFERENCES users(id) ON DELETE CASCADE,
+	category_id INT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+	type VARCHAR(32) NOT NULL DEFAULT '',
+	counterparty_wallet_id INT,
+	description_contains VARCHAR(255) NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CHECK (type <> '' OR counterparty_wallet_id IS NOT NULL OR description_contains <> '')
+);
+
+CREATE INDEX IF NOT EXISTS category_rules_user_idx ON category_rules (user_id);
+
+INSERT INTO category_rules (category_id, type, description_contains)
+SELECT c.id, r.type, r.description_contains FROM (VALUES
+	('Transfers', 'transfer', ''),
+	('Fees', 'fee', ''),
+	('Interest', 'interest', ''),
+	('Refunds', 'reversal', ''),
+	('Rent', '', 'rent'),
+	('Salary', '', 'salary')
+) AS r (category, type, description_contains)
+JOIN categories c ON c.user_id IS NULL AND c.name = r.category;
+
+-- What the user of a wallet filed their side of a journal entry under.
+-- wallet_id has no foreign key for the same reason as on ledger_entries.
+CREATE TABLE IF NOT EXISTS transaction_labels (
+	journal_id INT NOT NULL REFERENCES journal_entries(id),
+	wallet_id INT NOT NULL,
+	category_id INT REFERENCES categories(id) ON DELETE SET NULL,
+	tags TEXT[] NOT NULL DEFAULT '{}',
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (journal_id, wallet_id)
+);
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/KKGo-Software-engineering/fun-exercise-api/idempotency"
 	"github.com/KKGo-Software-engineering/fun-exercise-api/postgres"
+	"github.com/KKGo-Software-engineering/fun-exercise-api/rates"
 	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
 	"github.com/labstack/echo/v4"
 
@@ -33,13 +38,107 @@ func main() {
 		panic(err)
 	}
 
+	var opts []wallet.Option
+	if s := os.Getenv("WALLET_RETENTION"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			panic(err)
+		}
+		opts = append(opts, wallet.WithRetention(d))
+	}
+	if s := os.Getenv("ADMIN_USER_IDS"); s != "" {
+		var admins []int
+		for _, f := range strings.Split(s, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil {
+				panic(err)
+			}
+			admins = append(admins, id)
+		}
+		opts = append(opts, wallet.WithAdmins(admins...))
+	}
+	if path := os.Getenv("RATES_FILE"); path != "" {
+		r, err := rates.NewFile(path)
+		if err != nil {
+			panic(err)
+		}
+		opts = append(opts, wallet.WithRates(r))
+	}
+
+	handler := wallet.New(p, opts...)
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		os.Exit(reconcile(handler, os.Args[2:], os.Stdout))
+	}
+
 	e := echo.New()
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
-	handler := wallet.New(p)
 	e.GET("/api/v1/wallets", handler.GetAllWalletsHandler)
+	e.GET("/api/v1/assets", handler.AssetsHandler)
+	e.POST("/api/v1/users", handler.CreateUserHandler)
+	e.GET("/api/v1/users", handler.UsersHandler)
+	e.GET("/api/v1/users/:id", handler.UserHandler)
+	e.PUT("/api/v1/users/:id", handler.UpdateUserHandler)
+	e.DELETE("/api/v1/users/:id", handler.DeleteUserHandler)
 	e.GET("/api/v1/users/:id/wallets", handler.GetWalletByIDHandler)
-	e.POST("/api/v1/wallets", handler.CreateWalletHandler)
+	e.GET("/api/v1/users/:id/categories", handler.CategoriesHandler)
+	e.POST("/api/v1/users/:id/categories", handler.CreateCategoryHandler)
+	e.DELETE("/api/v1/users/:id/categories/:category_id", handler.DeleteCategoryHandler)
+	e.GET("/api/v1/users/:id/category-rules", handler.CategoryRulesHandler)
+	e.POST("/api/v1/users/:id/category-rules", handler.CreateCategoryRuleHandler)
+	e.DELETE("/api/v1/users/:id/category-rules/:rule_id", handler.DeleteCategoryRuleHandler)
+	idempotent := idempotency.Middleware(p)
+	e.POST("/api/v1/wallets", handler.CreateWalletHandler, idempotent)
+	e.GET("/api/v1/wallets/:id", handler.GetWalletHandler)
 	e.PUT("/api/v1/wallets/:id", handler.UpdateWalletHandler)
+	e.PATCH("/api/v1/wallets/:id", handler.PatchWalletHandler)
+	e.DELETE("/api/v1/wallets/:id", handler.DeleteWalletHandler)
 	e.DELETE("/api/v1/users/:id/wallets", handler.DeleteWalletByIDHandler)
+	e.POST("/api/v1/transfers", handler.TransferHandler, idempotent)
+	e.POST("/api/v1/wallets/:id/deposits", handler.DepositHandler, idempotent)
+	e.POST("/api/v1/wallets/:id/withdrawals", handler.WithdrawalHandler, idempotent)
+	e.GET("/api/v1/wallets/:id/balance", handler.WalletBalanceHandler)
+	e.GET("/api/v1/wallets/:id/members", handler.MembersHandler)
+	e.PUT("/api/v1/wallets/:id/members/:user_id", handler.SetMemberHandler)
+	e.DELETE("/api/v1/wallets/:id/members/:user_id", handler.RemoveMemberHandler)
+	e.GET("/api/v1/wallets/:id/limits", handler.WalletLimitsHandler)
+	e.PUT("/api/v1/wallets/:id/limits", handler.SetWalletLimitsHandler)
+	e.POST("/api/v1/wallets/:id/freeze", handler.FreezeWalletHandler)
+	e.POST("/api/v1/wallets/:id/unfreeze", handler.UnfreezeWalletHandler)
+	e.POST("/api/v1/wallets/:id/close", handler.CloseWalletHandler)
+	e.POST("/api/v1/wallets/:id/restore", handler.RestoreWalletHandler)
+	e.GET("/api/v1/wallets/:id/status-changes", handler.StatusChangesHandler)
+	e.GET("/api/v1/wallets/:id/transactions", handler.TransactionsHandler)
+	e.PUT("/api/v1/wallets/:id/transactions/:transaction_id/category", handler.LabelTransactionHandler)
+	e.POST("/api/v1/wallets/:id/holds", handler.PlaceHoldHandler, idempotent)
+	e.GET("/api/v1/wallets/:id/holds", handler.HoldsHandler)
+	e.POST("/api/v1/holds/:id/capture", handler.CaptureHoldHandler, idempotent)
+	e.POST("/api/v1/transactions/:id/reversal", handler.ReverseTransactionHandler, idempotent)
+	e.POST("/api/v1/holds/:id/release", handler.ReleaseHoldHandler, idempotent)
+	e.POST("/api/v1/schedules", handler.CreateScheduleHandler, idempotent)
+	e.GET("/api/v1/schedules/:id", handler.ScheduleHandler)
+	e.PUT("/api/v1/schedules/:id", handler.UpdateScheduleHandler)
+	e.DELETE("/api/v1/schedules/:id", handler.DeleteScheduleHandler)
+	e.GET("/api/v1/schedules/:id/executions", handler.ScheduleExecutionsHandler)
+	e.GET("/api/v1/wallets/:id/schedules", handler.WalletSchedulesHandler)
+	e.GET("/api/v1/wallets/:id/statements", handler.StatementsHandler)
+	e.POST("/api/v1/interest-products", handler.CreateInterestProductHandler)
+	e.GET("/api/v1/interest-products", handler.InterestProductsHandler)
+	e.GET("/api/v1/wallets/:id/interest", handler.AccruedInterestHandler)
+	e.GET("/api/v1/fees/quote", handler.FeeQuoteHandler)
+	e.POST("/api/v1/fee-rules", handler.CreateFeeRuleHandler)
+	e.GET("/api/v1/fee-rules", handler.FeeRulesHandler)
+	e.PUT("/api/v1/fee-rules/:id", handler.UpdateFeeRuleHandler)
+	e.DELETE("/api/v1/fee-rules/:id", handler.DeleteFeeRuleHandler)
+	e.GET("/api/v1/reconciliation", handler.ReconciliationHandler)
+	e.POST("/api/v1/reconciliation/corrections", handler.ReconciliationCorrectionsHandler, idempotent)
+	e.GET("/api/v1/reconciliation/corrections", handler.CorrectionsHandler)
+	e.POST("/api/v1/reconciliation/corrections/:id/resolve", handler.ResolveCorrectionHandler, idempotent)
+
+	go wallet.NewScheduler(handler).Run(context.Background())
+	go wallet.NewBilling(handler).Run(context.Background())
+	go wallet.NewInterestAccrual(handler).Run(context.Background())
+	openCorrections, _ := strconv.ParseBool(os.Getenv("RECONCILE_OPEN_CORRECTIONS"))
+	go wallet.NewReconciler(handler, openCorrections).Run(context.Background())
+	go wallet.NewPurger(handler).Run(context.Background())
 	e.Logger.Fatal(e.Start(":1323"))
 }
@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+// walletColumnsAt is walletColumns with the balances as they stood at the
+// timestamp in parameter arg, worked out from the ledger and the holds that
+// were reserving money then. The other columns are current.
+func walletColumnsAt(arg string) string {
+	balance := fmt.Sprintf(`(SELECT COALESCE(SUM(l.amount), 0) FROM ledger_entries l
+		WHERE l.account = 'wallet' AND l.wallet_id = user_wallet.id AND l.created_at < %s::timestamp)`, arg)
+	held := fmt.Sprintf(`(SELECT COALESCE(SUM(holds.amount), 0) FROM holds
+		WHERE holds.wallet_id = user_wallet.id AND holds.created_at < %[1]s::timestamp AND holds.expires_at > %[1]s::timestamp
+			AND (holds.settled_at IS NULL OR holds.settled_at >= %[1]s::timestamp))`, arg)
+	return "id, user_id, " + ownerColumns + ", wallet_name, wallet_type, currency, " + balance + ", " + balance + " - " + held + ", credit_limit, interest_product_id, " + limitColumns + ", status, status_reason, deleted_at, deleted_by, version, created_at"
+}
+
+// existedAt matches wallets that had been created and not yet deleted at
+// the timestamp in parameter arg.
+func existedAt(arg string) string {
+	return fmt.Sprintf("created_at < %[1]s::timestamp AND (deleted_at IS NULL OR deleted_at > %[1]s::timestamp)", arg)
+}
+
+// WalletAt returns the wallet with its balances as they were at at, before
+// anything booked at that instant. A wallet created since, or deleted by
+// then, is not found.
+func (p *Postgres) WalletAt(id int, at time.Time) (wallet.Wallet, error) {
+	w, err := scanWallet(p.Db.QueryRow("SELECT "+walletColumnsAt("$2")+" FROM user_wallet WHERE id = $1 AND "+existedAt("$2"), id, at.UTC()))
+	if err == sql.ErrNoRows {
+		return wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	return w, err
+}
+
+// WalletByUserIDAt is WalletByUserID as it was at at, leaving out wallets
+// created since or deleted by then. Memberships have no history, so the
+// wallets are those shared with the user now.
+func (p *Postgres) WalletByUserIDAt(id int, at time.Time) ([]wallet.Wallet, error) {
+	rows, err := p.Db.Query("SELECT "+walletColumnsAt("$2")+", "+memberRole+memberWallets+" AND "+existedAt("$2")+" ORDER BY id", id, at.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return scanMemberWallets(rows)
+}
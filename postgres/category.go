@@ -0,0 +1,224 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+	"github.com/lib/pq"
+)
+
+const (
+	categoryColumns     = "id, user_id, name, created_at"
+	categoryRuleColumns = "r.id, r.user_id, r.category_id, c.name, r.type, r.counterparty_wallet_id, r.description_contains, r.created_at"
+)
+
+// uniqueViolation is the Postgres error code for a duplicate key.
+const uniqueViolation = "23505"
+
+func nullableInt(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	id := int(n.Int64)
+	return &id
+}
+
+func scanCategory(row rowScanner) (wallet.Category, error) {
+	var c wallet.Category
+	var userID sql.NullInt64
+	err := row.Scan(&c.ID, &userID, &c.Name, &c.CreatedAt)
+	if err == sql.ErrNoRows {
+		return wallet.Category{}, wallet.ErrCategoryNotFound
+	}
+	c.UserID = nullableInt(userID)
+	return c, err
+}
+
+func scanCategoryRule(row rowScanner) (wallet.CategoryRule, error) {
+	var r wallet.CategoryRule
+	var userID, counterparty sql.NullInt64
+	err := row.Scan(&r.ID, &userID, &r.CategoryID, &r.Category, &r.Type, &counterparty, &r.DescriptionContains, &r.CreatedAt)
+	if err == sql.ErrNoRows {
+		return wallet.CategoryRule{}, wallet.ErrCategoryRuleNotFound
+	}
+	r.UserID, r.CounterpartyWalletID = nullableInt(userID), nullableInt(counterparty)
+	return r, err
+}
+
+// checkCategory fails with ErrUnknownCategory unless id names a default
+// category or one of userID's own.
+func checkCategory(q querier, userID, id int) error {
+	var found int
+	err := q.QueryRow("SELECT id FROM categories WHERE id = $1 AND (user_id IS NULL OR user_id = $2) FOR SHARE", id, userID).Scan(&found)
+	if err == sql.ErrNoRows {
+		return wallet.ErrUnknownCategory
+	}
+	return err
+}
+
+// Categories returns the default categories and then userID's own, each by
+// name.
+func (p *Postgres) Categories(userID int) ([]wallet.Category, error) {
+	rows, err := p.Db.Query("SELECT "+categoryColumns+" FROM categories WHERE user_id IS NULL OR user_id = $1 ORDER BY user_id NULLS FIRST, lower(name)", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []wallet.Category{}
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// CreateCategory adds a category of c's user. Its name may not repeat a
+// default or another of theirs, whatever the case.
+func (p *Postgres) CreateCategory(c wallet.Category) (wallet.Category, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Category{}, err
+	}
+	defer tx.Rollback()
+
+	if err := checkUser(tx, *c.UserID); errors.Is(err, wallet.ErrUnknownUser) {
+		return wallet.Category{}, wallet.ErrUserNotFound
+	} else if err != nil {
+		return wallet.Category{}, err
+	}
+	var exists bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM categories WHERE (user_id IS NULL OR user_id = $1) AND lower(name) = lower($2))", *c.UserID, c.Name).Scan(&exists)
+	if err != nil {
+		return wallet.Category{}, err
+	}
+	if exists {
+		return wallet.Category{}, wallet.ErrCategoryExists
+	}
+	c, err = scanCategory(tx.QueryRow("INSERT INTO categories (user_id, name) VALUES ($1, $2) RETURNING "+categoryColumns, *c.UserID, c.Name))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return wallet.Category{}, wallet.ErrCategoryExists
+	}
+	if err != nil {
+		return wallet.Category{}, err
+	}
+	return c, tx.Commit()
+}
+
+// DeleteCategory deletes one of userID's own categories. Its rules go with
+// it and the transactions filed under it lose their category.
+func (p *Postgres) DeleteCategory(userID, id int) error {
+	res, err := p.Db.Exec("DELETE FROM categories WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return wallet.ErrCategoryNotFound
+	}
+	return nil
+}
+
+func (p *Postgres) CategoryRules(userID int) ([]wallet.CategoryRule, error) {
+	rows, err := p.Db.Query("SELECT "+categoryRuleColumns+" FROM category_rules r JOIN categories c ON c.id = r.category_id WHERE r.user_id IS NULL OR r.user_id = $1 ORDER BY r.id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []wallet.CategoryRule{}
+	for rows.Next() {
+		r, err := scanCategoryRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+func (p *Postgres) CreateCategoryRule(r wallet.CategoryRule) (wallet.CategoryRule, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.CategoryRule{}, err
+	}
+	defer tx.Rollback()
+
+	if err := checkCategory(tx, *r.UserID, r.CategoryID); err != nil {
+		return wallet.CategoryRule{}, err
+	}
+	var id int
+	err = tx.QueryRow("INSERT INTO category_rules (user_id, category_id, type, counterparty_wallet_id, description_contains) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		*r.UserID, r.CategoryID, r.Type, r.CounterpartyWalletID, r.DescriptionContains).Scan(&id)
+	if err != nil {
+		return wallet.CategoryRule{}, err
+	}
+	r, err = scanCategoryRule(tx.QueryRow("SELECT "+categoryRuleColumns+" FROM category_rules r JOIN categories c ON c.id = r.category_id WHERE r.id = $1", id))
+	if err != nil {
+		return wallet.CategoryRule{}, err
+	}
+	return r, tx.Commit()
+}
+
+func (p *Postgres) DeleteCategoryRule(userID, id int) error {
+	res, err := p.Db.Exec("DELETE FROM category_rules WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return wallet.ErrCategoryRuleNotFound
+	}
+	return nil
+}
+
+// LabelTransaction files the wallet's side of a transaction under l's
+// category, which must be a default or one of the wallet user's own, and
+// replaces its tags.
+func (p *Postgres) LabelTransaction(walletID, transactionID int, l wallet.Labels) (wallet.Transaction, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Transaction{}, err
+	}
+	defer tx.Rollback()
+
+	w, err := walletByID(tx, walletID)
+	if err != nil {
+		return wallet.Transaction{}, err
+	}
+	if l.CategoryID != nil {
+		if err := checkCategory(tx, w.UserID, *l.CategoryID); err != nil {
+			return wallet.Transaction{}, err
+		}
+	}
+	var booked bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM ledger_entries WHERE journal_id = $1 AND account = 'wallet' AND wallet_id = $2)", transactionID, walletID).Scan(&booked)
+	if err != nil {
+		return wallet.Transaction{}, err
+	}
+	if !booked {
+		return wallet.Transaction{}, wallet.ErrTransactionNotFound
+	}
+	_, err = tx.Exec(`INSERT INTO transaction_labels (journal_id, wallet_id, category_id, tags) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (journal_id, wallet_id) DO UPDATE SET category_id = EXCLUDED.category_id, tags = EXCLUDED.tags, updated_at = CURRENT_TIMESTAMP`,
+		transactionID, walletID, l.CategoryID, pq.Array(l.Tags))
+	if err != nil {
+		return wallet.Transaction{}, err
+	}
+	t, err := scanTransaction(tx.QueryRow(transactionQuery+" AND j.id = $2 ORDER BY l.id LIMIT 1", walletID, transactionID))
+	if err != nil {
+		return wallet.Transaction{}, err
+	}
+	return t, tx.Commit()
+}
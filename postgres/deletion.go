@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+// RestoreWallet undoes the deletion of a wallet deleted at or after
+// deletedSince.
+func (p *Postgres) RestoreWallet(id int, deletedSince time.Time) (wallet.Wallet, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	defer tx.Rollback()
+
+	var deletedAt sql.NullTime
+	err = tx.QueryRow("SELECT deleted_at FROM user_wallet WHERE id = $1 FOR UPDATE", id).Scan(&deletedAt)
+	if err == sql.ErrNoRows {
+		return wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if !deletedAt.Valid {
+		return wallet.Wallet{}, wallet.ErrWalletNotDeleted
+	}
+	if deletedAt.Time.Before(deletedSince.UTC()) {
+		return wallet.Wallet{}, wallet.ErrRestoreExpired
+	}
+	if _, err := tx.Exec("UPDATE user_wallet SET deleted_at = NULL, deleted_by = '' WHERE id = $1", id); err != nil {
+		return wallet.Wallet{}, err
+	}
+	w, err := walletByID(tx, id)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	return w, tx.Commit()
+}
+
+// PurgeWallets deletes the rows of wallets deleted before deletedBefore,
+// along with their holds, schedules, statements and other records. Their
+// ledger lines are kept. Wallets that still hold money are skipped, so that
+// no balance disappears with them, and so are house fee wallets, which
+// fee_wallets still points at.
+func (p *Postgres) PurgeWallets(deletedBefore time.Time) (int, error) {
+	res, err := p.Db.Exec(`DELETE FROM user_wallet WHERE deleted_at < $1 AND balance = 0 AND `+heldAmount+` = 0
+		AND id NOT IN (SELECT wallet_id FROM fee_wallets)`, deletedBefore.UTC())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
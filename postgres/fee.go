@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+const feeRuleColumns = "id, name, operation, wallet_type, currency, flat, rate::text, tiers, min_fee, max_fee, created_at"
+
+func scanFeeRule(row rowScanner) (wallet.FeeRule, error) {
+	var r wallet.FeeRule
+	var flat string
+	var rate, min, max sql.NullString
+	var tiers []byte
+	err := row.Scan(&r.ID, &r.Name, &r.Operation, &r.WalletType, &r.Currency, &flat, &rate, &tiers, &min, &max, &r.CreatedAt)
+	if err == sql.ErrNoRows {
+		return wallet.FeeRule{}, wallet.ErrFeeRuleNotFound
+	}
+	if err != nil {
+		return wallet.FeeRule{}, err
+	}
+	if r.Flat, err = wallet.ParseMoney(flat, r.Currency); err != nil {
+		return wallet.FeeRule{}, err
+	}
+	r.Rate = rate.String
+	if err := json.Unmarshal(tiers, &r.Tiers); err != nil {
+		return wallet.FeeRule{}, err
+	}
+	for i, t := range r.Tiers {
+		if r.Tiers[i].From, err = t.From.WithCurrency(r.Currency); err != nil {
+			return wallet.FeeRule{}, err
+		}
+		if r.Tiers[i].Flat, err = t.Flat.WithCurrency(r.Currency); err != nil {
+			return wallet.FeeRule{}, err
+		}
+	}
+	for _, b := range []struct {
+		col sql.NullString
+		to  **wallet.Money
+	}{{min, &r.Min}, {max, &r.Max}} {
+		if !b.col.Valid {
+			continue
+		}
+		m, err := wallet.ParseMoney(b.col.String, r.Currency)
+		if err != nil {
+			return wallet.FeeRule{}, err
+		}
+		*b.to = &m
+	}
+	return r, nil
+}
+
+// feeRuleArgs are the values of the columns a fee rule is written to, from
+// name to max_fee.
+func feeRuleArgs(r wallet.FeeRule) ([]any, error) {
+	tiers, err := json.Marshal(r.Tiers)
+	if err != nil {
+		return nil, err
+	}
+	if r.Tiers == nil {
+		tiers = []byte("[]")
+	}
+	var rate sql.NullString
+	if r.Rate != "" {
+		rate = sql.NullString{String: r.Rate, Valid: true}
+	}
+	return []any{r.Name, r.Operation, r.WalletType, r.Currency, r.Flat, rate, string(tiers), r.Min, r.Max}, nil
+}
+
+func (p *Postgres) CreateFeeRule(r wallet.FeeRule) (wallet.FeeRule, error) {
+	args, err := feeRuleArgs(r)
+	if err != nil {
+		return wallet.FeeRule{}, err
+	}
+	return scanFeeRule(p.Db.QueryRow(`INSERT INTO fee_rules (name, operation, wallet_type, currency, flat, rate, tiers, min_fee, max_fee)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING `+feeRuleColumns, args...))
+}
+
+func (p *Postgres) FeeRules() ([]wallet.FeeRule, error) {
+	rows, err := p.Db.Query("SELECT " + feeRuleColumns + " FROM fee_rules ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []wallet.FeeRule{}
+	for rows.Next() {
+		r, err := scanFeeRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+func (p *Postgres) UpdateFeeRule(id int, r wallet.FeeRule) (wallet.FeeRule, error) {
+	args, err := feeRuleArgs(r)
+	if err != nil {
+		return wallet.FeeRule{}, err
+	}
+	return scanFeeRule(p.Db.QueryRow(`UPDATE fee_rules SET name = $1, operation = $2, wallet_type = $3, currency = $4, flat = $5, rate = $6, tiers = $7, min_fee = $8, max_fee = $9
+		WHERE id = $10 RETURNING `+feeRuleColumns, append(args, id)...))
+}
+
+func (p *Postgres) DeleteFeeRule(id int) error {
+	res, err := p.Db.Exec("DELETE FROM fee_rules WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return wallet.ErrFeeRuleNotFound
+	}
+	return nil
+}
+
+// checkFeeWallet fails with wallet.ErrFeeWallet if a wallet is the house
+// fee wallet of a currency.
+func checkFeeWallet(q querier, walletID int) error {
+	var collects bool
+	err := q.QueryRow("SELECT EXISTS (SELECT 1 FROM fee_wallets WHERE wallet_id = $1)", walletID).Scan(&collects)
+	if err != nil {
+		return err
+	}
+	if collects {
+		return wallet.ErrFeeWallet
+	}
+	return nil
+}
+
+// postFee moves fee from a wallet to the house fee wallet of its currency
+// within tx, as an entry of its own next to the movement it was charged
+// for. A zero fee books nothing.
+func postFee(tx *sql.Tx, walletID int, fee wallet.Money, kind string, movementID int, reference string) error {
+	if fee.IsZero() {
+		return nil
+	}
+	var houseID int
+	err := tx.QueryRow("SELECT wallet_id FROM fee_wallets WHERE currency = $1", fee.Currency()).Scan(&houseID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w %s", wallet.ErrNoFeeWallet, fee.Currency())
+	}
+	if err != nil {
+		return err
+	}
+	_, err = postJournal(tx, wallet.JournalEntry{
+		Type:        wallet.TransactionFee,
+		Reference:   reference,
+		Description: fmt.Sprintf("Fee for %s %d", kind, movementID),
+		Lines: []wallet.LedgerLine{
+			wallet.WalletLine(walletID, fee.Neg()),
+			wallet.WalletLine(houseID, fee),
+		},
+	})
+	return err
+}
@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+// holdColumns reports holds past their expiry as expired without waiting
+// for anything to rewrite the row.
+const holdColumns = `id, wallet_id, amount, captured, currency,
+	CASE WHEN status = 'active' AND expires_at <= CURRENT_TIMESTAMP THEN 'expired' ELSE status::text END,
+	reference, expires_at, created_at`
+
+func scanHold(row rowScanner) (wallet.Hold, error) {
+	var h wallet.Hold
+	var amount, captured string
+	err := row.Scan(&h.ID, &h.WalletID, &amount, &captured, &h.Currency, &h.Status, &h.Reference, &h.ExpiresAt, &h.CreatedAt)
+	if err != nil {
+		return wallet.Hold{}, err
+	}
+	if h.Amount, err = wallet.ParseMoney(amount, h.Currency); err != nil {
+		return wallet.Hold{}, err
+	}
+	if h.Captured, err = wallet.ParseMoney(captured, h.Currency); err != nil {
+		return wallet.Hold{}, err
+	}
+	return h, nil
+}
+
+func (p *Postgres) PlaceHold(walletID int, req wallet.HoldRequest) (wallet.Hold, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Hold{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockWallets(tx, walletID); err != nil {
+		return wallet.Hold{}, err
+	}
+	w, err := walletByID(tx, walletID)
+	if err != nil {
+		return wallet.Hold{}, err
+	}
+	if err := wallet.CheckMovement(w.Status, req.Amount.Neg(), ""); err != nil {
+		return wallet.Hold{}, err
+	}
+	if w.Spendable().Cmp(req.Amount) < 0 {
+		return wallet.Hold{}, wallet.ErrInsufficientFunds
+	}
+	if err := checkLimits(tx, walletID, w.Limits, req.Amount); err != nil {
+		return wallet.Hold{}, err
+	}
+	hold, err := scanHold(tx.QueryRow(`INSERT INTO holds (wallet_id, amount, currency, reference, expires_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP + make_interval(secs => $5))
+		RETURNING `+holdColumns, walletID, req.Amount, req.Amount.Currency(), req.Reference, req.Duration().Seconds()))
+	if err != nil {
+		return wallet.Hold{}, err
+	}
+	return hold, tx.Commit()
+}
+
+func (p *Postgres) Holds(walletID int) ([]wallet.Hold, error) {
+	rows, err := p.Db.Query("SELECT "+holdColumns+" FROM holds WHERE wallet_id = $1 ORDER BY id DESC", walletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := []wallet.Hold{}
+	for rows.Next() {
+		h, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, h)
+	}
+	return holds, rows.Err()
+}
+
+func (p *Postgres) HoldByID(id int) (wallet.Hold, error) {
+	h, err := scanHold(p.Db.QueryRow("SELECT "+holdColumns+" FROM holds WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return wallet.Hold{}, wallet.ErrHoldNotFound
+	}
+	return h, err
+}
+
+// activeHold locks the hold and fails unless it can still be captured or
+// released.
+func activeHold(tx *sql.Tx, id int) (wallet.Hold, error) {
+	h, err := scanHold(tx.QueryRow("SELECT "+holdColumns+" FROM holds WHERE id = $1 FOR UPDATE", id))
+	if err == sql.ErrNoRows {
+		return wallet.Hold{}, wallet.ErrHoldNotFound
+	}
+	if err != nil {
+		return wallet.Hold{}, err
+	}
+	if h.Status != wallet.HoldActive {
+		return wallet.Hold{}, wallet.ErrHoldNotActive
+	}
+	return h, nil
+}
+
+// CaptureHold settles the hold and debits the captured amount in one
+// transaction. The hold stops counting against the balance before the debit
+// is posted, so the debit may use the money it reserved.
+func (p *Postgres) CaptureHold(id int, amount *wallet.Money) (wallet.Hold, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Hold{}, err
+	}
+	defer tx.Rollback()
+
+	h, err := activeHold(tx, id)
+	if err != nil {
+		return wallet.Hold{}, err
+	}
+	captured := h.Amount
+	if amount != nil {
+		if captured, err = amount.WithCurrency(h.Currency); err != nil {
+			return wallet.Hold{}, err
+		}
+	}
+	if !captured.IsPositive() || captured.Cmp(h.Amount) > 0 {
+		return wallet.Hold{}, wallet.ErrCaptureAmount
+	}
+
+	_, err = tx.Exec("UPDATE holds SET status = 'captured', captured = $1, settled_at = CURRENT_TIMESTAMP WHERE id = $2", captured, id)
+	if err != nil {
+		return wallet.Hold{}, err
+	}
+	_, err = postJournal(tx, wallet.JournalEntry{
+		Type:        wallet.TransactionCapture,
+		Reference:   h.Reference,
+		Description: fmt.Sprintf("Capture of hold %d", id),
+		Lines: []wallet.LedgerLine{
+			wallet.WalletLine(h.WalletID, captured.Neg()),
+			wallet.AccountLine(wallet.AccountExternal, captured),
+		},
+	})
+	if err != nil {
+		return wallet.Hold{}, err
+	}
+	h, err = scanHold(tx.QueryRow("SELECT "+holdColumns+" FROM holds WHERE id = $1", id))
+	if err != nil {
+		return wallet.Hold{}, err
+	}
+	return h, tx.Commit()
+}
+
+func (p *Postgres) ReleaseHold(id int) (wallet.Hold, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Hold{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := activeHold(tx, id); err != nil {
+		return wallet.Hold{}, err
+	}
+	h, err := scanHold(tx.QueryRow("UPDATE holds SET status = 'released', settled_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING "+holdColumns, id))
+	if err != nil {
+		return wallet.Hold{}, err
+	}
+	return h, tx.Commit()
+}
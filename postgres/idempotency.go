@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/idempotency"
+)
+
+// ReserveIdempotencyKey inserts a pending record for the key. A pending
+// record is never taken over, however old: the request that left it may
+// have moved money before it crashed, so its key keeps answering 409 and
+// the client has to check the outcome and retry with a new key.
+func (p *Postgres) ReserveIdempotencyKey(r idempotency.Record) (idempotency.Record, bool, error) {
+	var clientID string
+	err := p.Db.QueryRow(`INSERT INTO idempotency_keys (client_id, key, request_hash) VALUES ($1, $2, $3)
+		ON CONFLICT (client_id, key) DO NOTHING
+		RETURNING client_id`, r.ClientID, r.Key, r.RequestHash).Scan(&clientID)
+	if err == nil {
+		return idempotency.Record{}, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return idempotency.Record{}, false, err
+	}
+
+	existing := idempotency.Record{ClientID: r.ClientID, Key: r.Key}
+	var status sql.NullInt64
+	var contentType sql.NullString
+	err = p.Db.QueryRow(`SELECT request_hash, status_code, content_type, response_body, completed_at IS NOT NULL
+		FROM idempotency_keys WHERE client_id = $1 AND key = $2`, r.ClientID, r.Key).
+		Scan(&existing.RequestHash, &status, &contentType, &existing.Body, &existing.Completed)
+	if err != nil {
+		return idempotency.Record{}, false, err
+	}
+	existing.StatusCode = int(status.Int64)
+	existing.ContentType = contentType.String
+	return existing, false, nil
+}
+
+func (p *Postgres) CompleteIdempotencyKey(r idempotency.Record) error {
+	_, err := p.Db.Exec(`UPDATE idempotency_keys SET status_code = $1, content_type = $2, response_body = $3, completed_at = CURRENT_TIMESTAMP
+		WHERE client_id = $4 AND key = $5`, r.StatusCode, r.ContentType, r.Body, r.ClientID, r.Key)
+	return err
+}
+
+func (p *Postgres) ReleaseIdempotencyKey(clientID, key string) error {
+	_, err := p.Db.Exec("DELETE FROM idempotency_keys WHERE client_id = $1 AND key = $2 AND completed_at IS NULL", clientID, key)
+	return err
+}
@@ -0,0 +1,203 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+const interestProductColumns = "id, name, annual_rate::text, day_count, created_at"
+
+func scanInterestProduct(row rowScanner) (wallet.InterestProduct, error) {
+	var p wallet.InterestProduct
+	err := row.Scan(&p.ID, &p.Name, &p.AnnualRate, &p.DayCount, &p.CreatedAt)
+	return p, err
+}
+
+// checkInterestProduct fails with ErrInterestProductNotFound unless id is
+// nil or names an existing product.
+func checkInterestProduct(q querier, id *int) error {
+	if id == nil {
+		return nil
+	}
+	var exists bool
+	err := q.QueryRow("SELECT EXISTS (SELECT 1 FROM interest_products WHERE id = $1)", *id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return wallet.ErrInterestProductNotFound
+	}
+	return nil
+}
+
+func (p *Postgres) CreateInterestProduct(product wallet.InterestProduct) (wallet.InterestProduct, error) {
+	return scanInterestProduct(p.Db.QueryRow("INSERT INTO interest_products (name, annual_rate, day_count) VALUES ($1, $2, $3) RETURNING "+interestProductColumns,
+		product.Name, product.AnnualRate, product.DayCount))
+}
+
+func (p *Postgres) InterestProducts() ([]wallet.InterestProduct, error) {
+	rows, err := p.Db.Query("SELECT " + interestProductColumns + " FROM interest_products ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []wallet.InterestProduct{}
+	for rows.Next() {
+		product, err := scanInterestProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	return products, rows.Err()
+}
+
+// extraScanner scans the columns of a row that follow those scanWallet reads.
+type extraScanner struct {
+	row   rowScanner
+	extra []any
+}
+
+func (s extraScanner) Scan(dest ...any) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
+
+func (p *Postgres) InterestAccounts() ([]wallet.InterestAccount, error) {
+	products, err := p.InterestProducts()
+	if err != nil {
+		return nil, err
+	}
+	byID := map[int]wallet.InterestProduct{}
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	rows, err := p.Db.Query(`SELECT `+walletColumns+`,
+			(SELECT MAX(accrual_date) FROM interest_accruals a WHERE a.wallet_id = user_wallet.id)
+		FROM user_wallet
+		WHERE wallet_type = $1 AND interest_product_id IS NOT NULL AND status <> 'closed' AND deleted_at IS NULL
+		ORDER BY id`, wallet.WalletTypeSavings)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []wallet.InterestAccount
+	for rows.Next() {
+		var last sql.NullTime
+		w, err := scanWallet(extraScanner{row: rows, extra: []any{&last}})
+		if err != nil {
+			return nil, err
+		}
+		account := wallet.InterestAccount{Wallet: w, Product: byID[*w.InterestProductID]}
+		if last.Valid {
+			account.LastAccrual = last.Time
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// BalanceAt sums the wallet's ledger lines booked before at.
+func (p *Postgres) BalanceAt(walletID int, at time.Time) (wallet.Money, error) {
+	var currency, balance string
+	err := p.Db.QueryRow(`SELECT w.currency, COALESCE(SUM(l.amount), 0)
+		FROM user_wallet w
+		LEFT JOIN ledger_entries l ON l.account = 'wallet' AND l.wallet_id = w.id AND l.created_at < $2
+		WHERE w.id = $1
+		GROUP BY w.currency`, walletID, at.UTC()).Scan(&currency, &balance)
+	if err == sql.ErrNoRows {
+		return wallet.Money{}, wallet.ErrWalletNotFound
+	}
+	if err != nil {
+		return wallet.Money{}, err
+	}
+	return wallet.ParseMoney(balance, currency)
+}
+
+// RecordAccrual stores a day's interest unless that day has been accrued
+// already.
+func (p *Postgres) RecordAccrual(a wallet.Accrual) error {
+	_, err := p.Db.Exec(`INSERT INTO interest_accruals (wallet_id, accrual_date, product_id, currency, balance, annual_rate, amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (wallet_id, accrual_date) DO NOTHING`,
+		a.WalletID, a.Date.Format("2006-01-02"), a.ProductID, a.Amount.Currency(), a.Balance, a.AnnualRate, a.Amount)
+	return err
+}
+
+func (p *Postgres) UnpaidAccruals(walletID int) ([]wallet.Accrual, error) {
+	rows, err := p.Db.Query(`SELECT wallet_id, accrual_date, product_id, currency, balance, annual_rate::text, amount
+		FROM interest_accruals
+		WHERE wallet_id = $1 AND capitalized_at IS NULL
+		ORDER BY accrual_date`, walletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accruals := []wallet.Accrual{}
+	for rows.Next() {
+		var a wallet.Accrual
+		var currency, balance, amount string
+		if err := rows.Scan(&a.WalletID, &a.Date, &a.ProductID, &currency, &balance, &a.AnnualRate, &amount); err != nil {
+			return nil, err
+		}
+		if a.Balance, err = wallet.ParseMoney(balance, currency); err != nil {
+			return nil, err
+		}
+		if a.Amount, err = wallet.ParseFractionalMoney(amount, currency); err != nil {
+			return nil, err
+		}
+		accruals = append(accruals, a)
+	}
+	return accruals, rows.Err()
+}
+
+// CapitalizeInterest pays amount into the wallet for its unpaid accruals
+// dated before before, and marks them paid. It fails without paying if the
+// number of those accruals is not the one amount was worked out from, which
+// means another run has capitalized them first.
+func (p *Postgres) CapitalizeInterest(walletID int, before time.Time, accruals int, amount wallet.Money) error {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockWallets(tx, walletID); err != nil {
+		return err
+	}
+	var journalID sql.NullInt64
+	if amount.IsPositive() {
+		entry, err := postJournal(tx, wallet.JournalEntry{
+			Type:        wallet.TransactionInterest,
+			Reference:   "INTEREST-" + before.AddDate(0, -1, 0).Format("2006-01"),
+			Description: fmt.Sprintf("Interest to %s", before.AddDate(0, 0, -1).Format("2006-01-02")),
+			Lines: []wallet.LedgerLine{
+				wallet.WalletLine(walletID, amount),
+				wallet.AccountLine(wallet.AccountInterest, amount.Neg()),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		journalID = sql.NullInt64{Int64: int64(entry.ID), Valid: true}
+	}
+	res, err := tx.Exec(`UPDATE interest_accruals SET capitalized_at = CURRENT_TIMESTAMP, journal_id = $1
+		WHERE wallet_id = $2 AND accrual_date < $3 AND capitalized_at IS NULL`, journalID, walletID, before.Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(n) != accruals {
+		return fmt.Errorf("wallet %d: %w", walletID, wallet.ErrCapitalizedConcurrently)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+	"github.com/lib/pq"
+)
+
+type querier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func walletByID(q querier, id int) (wallet.Wallet, error) {
+	w, err := scanWallet(q.QueryRow("SELECT "+walletColumns+" FROM user_wallet WHERE id = $1 AND deleted_at IS NULL", id))
+	if err == sql.ErrNoRows {
+		return wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	return w, err
+}
+
+// lockedWallet is what postings need to know of a wallet they have locked.
+type lockedWallet struct {
+	currency string
+	status   string
+	limits   wallet.SpendingLimits
+}
+
+// lockWallets takes row locks on the given wallets in id order so that
+// concurrent postings touching the same wallets cannot deadlock. It returns
+// the currency, status and spending limits of each locked wallet. Deleted
+// wallets are not found.
+func lockWallets(tx *sql.Tx, ids ...int) (map[int]lockedWallet, error) {
+	seen := map[int]bool{}
+	var unique []int
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	sort.Ints(unique)
+
+	rows, err := tx.Query("SELECT id, currency, status, "+limitColumns+" FROM user_wallet WHERE id = ANY($1) AND deleted_at IS NULL ORDER BY id FOR UPDATE", pq.Array(unique))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	locked := map[int]lockedWallet{}
+	for rows.Next() {
+		var id int
+		var w lockedWallet
+		var perTransaction, daily, monthly sql.NullString
+		if err := rows.Scan(&id, &w.currency, &w.status, &perTransaction, &daily, &monthly); err != nil {
+			return nil, err
+		}
+		if w.limits, err = parseLimits(w.currency, perTransaction, daily, monthly); err != nil {
+			return nil, err
+		}
+		locked[id] = w
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(locked) != len(unique) {
+		return nil, wallet.ErrWalletNotFound
+	}
+	return locked, nil
+}
+
+// postJournal writes entry to the ledger and applies its wallet lines to
+// user_wallet.balance within tx. Balances are only ever changed here, which
+// keeps the stored balance equal to the sum of the wallet's ledger lines.
+func postJournal(tx *sql.Tx, entry wallet.JournalEntry) (wallet.JournalEntry, error) {
+	if err := entry.Validate(); err != nil {
+		return wallet.JournalEntry{}, err
+	}
+	var walletIDs []int
+	for _, l := range entry.Lines {
+		if l.Account == wallet.AccountWallet {
+			walletIDs = append(walletIDs, l.WalletID)
+		}
+	}
+	locked, err := lockWallets(tx, walletIDs...)
+	if err != nil {
+		return wallet.JournalEntry{}, err
+	}
+	if err := checkEntryLimits(tx, entry, locked); err != nil {
+		return wallet.JournalEntry{}, err
+	}
+
+	err = tx.QueryRow("INSERT INTO journal_entries (type, reference, description, reversal_of) VALUES ($1, $2, $3, $4) RETURNING id, created_at", entry.Type, entry.Reference, entry.Description, entry.ReversalOf).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return wallet.JournalEntry{}, err
+	}
+	for _, l := range entry.Lines {
+		var walletID sql.NullInt64
+		if l.Account == wallet.AccountWallet {
+			if locked[l.WalletID].currency != l.Amount.Currency() {
+				return wallet.JournalEntry{}, wallet.ErrCurrencyMismatch
+			}
+			if err := wallet.CheckMovement(locked[l.WalletID].status, l.Amount, entry.Type); err != nil {
+				return wallet.JournalEntry{}, err
+			}
+			walletID = sql.NullInt64{Int64: int64(l.WalletID), Valid: true}
+		}
+		_, err := tx.Exec("INSERT INTO ledger_entries (journal_id, account, wallet_id, currency, amount) VALUES ($1, $2, $3, $4, $5)", entry.ID, l.Account, walletID, l.Amount.Currency(), l.Amount)
+		if err != nil {
+			return wallet.JournalEntry{}, err
+		}
+		if !walletID.Valid {
+			continue
+		}
+		// A debit may not dip into money reserved by holds or, on credit
+		// cards, go past the credit limit. Charges are booked regardless.
+		res, err := tx.Exec("UPDATE user_wallet SET balance = balance + $1 WHERE id = $2 AND ($1 >= 0 OR $3 OR balance + $1 >= "+heldAmount+" - credit_limit)", l.Amount, l.WalletID, wallet.IsCharge(entry.Type))
+		if err != nil {
+			return wallet.JournalEntry{}, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return wallet.JournalEntry{}, err
+		}
+		if n == 0 {
+			return wallet.JournalEntry{}, wallet.ErrInsufficientFunds
+		}
+	}
+	return entry, nil
+}
+
+// checkEntryLimits holds the wallets entry spends from to their spending
+// limits. Captures are not checked again: their hold was when it was placed.
+func checkEntryLimits(tx *sql.Tx, entry wallet.JournalEntry, locked map[int]lockedWallet) error {
+	if !wallet.IsSpending(entry.Type) || entry.Type == wallet.TransactionCapture {
+		return nil
+	}
+	debits := map[int]wallet.Money{}
+	var ids []int
+	for _, l := range entry.Lines {
+		if l.Account != wallet.AccountWallet || !l.Amount.IsNegative() || locked[l.WalletID].currency != l.Amount.Currency() {
+			continue
+		}
+		total, ok := debits[l.WalletID]
+		if !ok {
+			total = wallet.NewMoney(0, l.Amount.Currency())
+			ids = append(ids, l.WalletID)
+		}
+		total, err := total.Add(l.Amount.Neg())
+		if err != nil {
+			return err
+		}
+		debits[l.WalletID] = total
+	}
+	for _, id := range ids {
+		if err := checkLimits(tx, id, locked[id].limits, debits[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Postgres) WalletByID(id int) (wallet.Wallet, error) {
+	return walletByID(p.Db, id)
+}
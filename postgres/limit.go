@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+	"github.com/lib/pq"
+)
+
+const limitColumns = "limit_per_transaction, limit_daily, limit_monthly"
+
+// parseLimits reads the limit columns of a wallet in currency.
+func parseLimits(currency string, perTransaction, daily, monthly sql.NullString) (wallet.SpendingLimits, error) {
+	var limits wallet.SpendingLimits
+	for _, c := range []struct {
+		col sql.NullString
+		to  **wallet.Money
+	}{
+		{perTransaction, &limits.PerTransaction},
+		{daily, &limits.Daily},
+		{monthly, &limits.Monthly},
+	} {
+		if !c.col.Valid {
+			continue
+		}
+		m, err := wallet.ParseMoney(c.col.String, currency)
+		if err != nil {
+			return wallet.SpendingLimits{}, err
+		}
+		*c.to = &m
+	}
+	return limits, nil
+}
+
+// spending sums what a wallet has spent since the start of the UTC day and
+// month now falls in: debits of spending entries and the money active holds
+// reserve. A captured hold counts once, as its capture.
+func spending(q querier, walletID int, currency string, now time.Time) (wallet.Spending, error) {
+	day, month := wallet.LimitPeriods(now)
+	var today, thisMonth string
+	err := q.QueryRow(`SELECT COALESCE(SUM(amount) FILTER (WHERE at >= $2), 0), COALESCE(SUM(amount), 0) FROM (
+			SELECT -l.amount AS amount, l.created_at AS at FROM ledger_entries l
+				JOIN journal_entries j ON j.id = l.journal_id
+				WHERE l.account = 'wallet' AND l.wallet_id = $1 AND l.amount < 0 AND l.created_at >= $3 AND j.type = ANY($4)
+			UNION ALL
+			SELECT amount, created_at FROM holds
+				WHERE wallet_id = $1 AND status = 'active' AND expires_at > CURRENT_TIMESTAMP AND created_at >= $3
+		) spent`, walletID, day, month, pq.Array(wallet.SpendingTypes)).Scan(&today, &thisMonth)
+	if err != nil {
+		return wallet.Spending{}, err
+	}
+	var s wallet.Spending
+	if s.Today, err = wallet.ParseMoney(today, currency); err != nil {
+		return wallet.Spending{}, err
+	}
+	if s.ThisMonth, err = wallet.ParseMoney(thisMonth, currency); err != nil {
+		return wallet.Spending{}, err
+	}
+	return s, nil
+}
+
+// checkLimits fails with a LimitError if spending amount from a wallet the
+// caller has locked would go over one of its limits.
+func checkLimits(q querier, walletID int, limits wallet.SpendingLimits, amount wallet.Money) error {
+	if !limits.IsSet() {
+		return nil
+	}
+	now := time.Now().UTC()
+	spent, err := spending(q, walletID, amount.Currency(), now)
+	if err != nil {
+		return err
+	}
+	return limits.Check(amount, spent, now)
+}
+
+// SetLimits replaces a wallet's spending limits, which must already be in
+// its currency, and returns the wallet as stored.
+func (p *Postgres) SetLimits(walletID int, limits wallet.SpendingLimits) (wallet.Wallet, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockWallets(tx, walletID); err != nil {
+		return wallet.Wallet{}, err
+	}
+	_, err = tx.Exec("UPDATE user_wallet SET limit_per_transaction = $1, limit_daily = $2, limit_monthly = $3 WHERE id = $4",
+		limits.PerTransaction, limits.Daily, limits.Monthly, walletID)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	w, err := walletByID(tx, walletID)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	return w, tx.Commit()
+}
+
+func (p *Postgres) Spending(walletID int, now time.Time) (wallet.Spending, error) {
+	w, err := walletByID(p.Db, walletID)
+	if err != nil {
+		return wallet.Spending{}, err
+	}
+	return spending(p.Db, walletID, w.Currency, now)
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+const memberColumns = "m.wallet_id, m.user_id, u.name, u.created_at, m.role, m.created_at"
+
+func scanMember(row rowScanner) (wallet.Member, error) {
+	var m wallet.Member
+	err := row.Scan(&m.WalletID, &m.UserID, &m.User.Name, &m.User.CreatedAt, &m.Role, &m.CreatedAt)
+	if err == sql.ErrNoRows {
+		return wallet.Member{}, wallet.ErrMemberNotFound
+	}
+	m.User.ID = m.UserID
+	return m, err
+}
+
+// addOwner makes userID an owner of the wallet, whatever role they had.
+func addOwner(tx *sql.Tx, walletID, userID int) error {
+	_, err := tx.Exec(`INSERT INTO wallet_members (wallet_id, user_id, role) VALUES ($1, $2, 'owner')
+		ON CONFLICT (wallet_id, user_id) DO UPDATE SET role = 'owner'`, walletID, userID)
+	return err
+}
+
+func (p *Postgres) Members(walletID int) ([]wallet.Member, error) {
+	rows, err := p.Db.Query("SELECT "+memberColumns+" FROM wallet_members m JOIN users u ON u.id = m.user_id WHERE m.wallet_id = $1 ORDER BY m.created_at, m.user_id", walletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []wallet.Member{}
+	for rows.Next() {
+		m, err := scanMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func (p *Postgres) Role(walletID, userID int) (string, error) {
+	var role string
+	err := p.Db.QueryRow("SELECT role FROM wallet_members WHERE wallet_id = $1 AND user_id = $2", walletID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", wallet.ErrMemberNotFound
+	}
+	return role, err
+}
+
+// SetMember gives an existing user role on the wallet. The wallet is locked
+// so that its user_id cannot change meanwhile.
+func (p *Postgres) SetMember(walletID, userID int, role string) (wallet.Member, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Member{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockWallets(tx, walletID); err != nil {
+		return wallet.Member{}, err
+	}
+	w, err := walletByID(tx, walletID)
+	if err != nil {
+		return wallet.Member{}, err
+	}
+	if w.UserID == userID && role != wallet.RoleOwner {
+		return wallet.Member{}, wallet.ErrPrimaryOwner
+	}
+	if err := checkUser(tx, userID); err != nil {
+		return wallet.Member{}, err
+	}
+	_, err = tx.Exec(`INSERT INTO wallet_members (wallet_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (wallet_id, user_id) DO UPDATE SET role = EXCLUDED.role`, walletID, userID, role)
+	if err != nil {
+		return wallet.Member{}, err
+	}
+	m, err := scanMember(tx.QueryRow("SELECT "+memberColumns+" FROM wallet_members m JOIN users u ON u.id = m.user_id WHERE m.wallet_id = $1 AND m.user_id = $2", walletID, userID))
+	if err != nil {
+		return wallet.Member{}, err
+	}
+	return m, tx.Commit()
+}
+
+func (p *Postgres) RemoveMember(walletID, userID int) error {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockWallets(tx, walletID); err != nil {
+		return err
+	}
+	w, err := walletByID(tx, walletID)
+	if err != nil {
+		return err
+	}
+	if w.UserID == userID {
+		return wallet.ErrPrimaryOwner
+	}
+	res, err := tx.Exec("DELETE FROM wallet_members WHERE wallet_id = $1 AND user_id = $2", walletID, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return wallet.ErrMemberNotFound
+	}
+	return tx.Commit()
+}
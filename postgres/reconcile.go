@@ -0,0 +1,179 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+const correctionColumns = `id, wallet_id, currency, stored_balance, ledger_balance, difference, foreign_lines,
+	status, journal_id, created_at, resolved_at`
+
+func scanCorrection(row rowScanner) (wallet.Correction, error) {
+	var c wallet.Correction
+	var stored, ledger, difference string
+	var journalID sql.NullInt64
+	var resolvedAt sql.NullTime
+	err := row.Scan(&c.ID, &c.WalletID, &c.Currency, &stored, &ledger, &difference, &c.ForeignLines,
+		&c.Status, &journalID, &c.CreatedAt, &resolvedAt)
+	if err != nil {
+		return wallet.Correction{}, err
+	}
+	if c.StoredBalance, err = wallet.ParseFractionalMoney(stored, c.Currency); err != nil {
+		return wallet.Correction{}, err
+	}
+	if c.LedgerBalance, err = wallet.ParseFractionalMoney(ledger, c.Currency); err != nil {
+		return wallet.Correction{}, err
+	}
+	if c.Difference, err = wallet.ParseFractionalMoney(difference, c.Currency); err != nil {
+		return wallet.Correction{}, err
+	}
+	if journalID.Valid {
+		id := int(journalID.Int64)
+		c.JournalID = &id
+	}
+	if resolvedAt.Valid {
+		c.ResolvedAt = &resolvedAt.Time
+	}
+	return c, nil
+}
+
+// BalanceChecks reads every wallet's stored balance and ledger sum in one
+// statement, so both come from the same snapshot. Amounts are read at full
+// precision because a corrupted balance need not fit its currency.
+func (p *Postgres) BalanceChecks() ([]wallet.BalanceCheck, error) {
+	rows, err := p.Db.Query(`SELECT w.id, w.currency, w.balance,
+			COALESCE(SUM(l.amount) FILTER (WHERE l.currency = w.currency), 0),
+			COUNT(l.id) FILTER (WHERE l.currency <> w.currency)
+		FROM user_wallet w
+		LEFT JOIN ledger_entries l ON l.account = 'wallet' AND l.wallet_id = w.id
+		GROUP BY w.id
+		ORDER BY w.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checks []wallet.BalanceCheck
+	for rows.Next() {
+		var c wallet.BalanceCheck
+		var stored, ledger string
+		if err := rows.Scan(&c.WalletID, &c.Currency, &stored, &ledger, &c.ForeignLines); err != nil {
+			return nil, err
+		}
+		if c.Stored, err = wallet.ParseFractionalMoney(stored, c.Currency); err != nil {
+			return nil, err
+		}
+		if c.Ledger, err = wallet.ParseFractionalMoney(ledger, c.Currency); err != nil {
+			return nil, err
+		}
+		checks = append(checks, c)
+	}
+	return checks, rows.Err()
+}
+
+// OpenCorrection opens a correction ticket for the discrepancy, or returns
+// the ticket already open for the wallet.
+func (p *Postgres) OpenCorrection(d wallet.Discrepancy) (int, error) {
+	_, err := p.Db.Exec(`INSERT INTO balance_corrections (wallet_id, currency, stored_balance, ledger_balance, difference, foreign_lines)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (wallet_id) WHERE status = 'open' DO NOTHING`,
+		d.WalletID, d.Currency, d.StoredBalance, d.LedgerBalance, d.Difference, d.ForeignLines)
+	if err != nil {
+		return 0, err
+	}
+	var id int
+	err = p.Db.QueryRow("SELECT id FROM balance_corrections WHERE wallet_id = $1 AND status = 'open'", d.WalletID).Scan(&id)
+	return id, err
+}
+
+// Corrections lists correction tickets in the order they were opened, only
+// those in status unless it is empty.
+func (p *Postgres) Corrections(status string) ([]wallet.Correction, error) {
+	rows, err := p.Db.Query("SELECT "+correctionColumns+" FROM balance_corrections WHERE ($1 = '' OR status = $1) ORDER BY id", status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	corrections := []wallet.Correction{}
+	for rows.Next() {
+		c, err := scanCorrection(rows)
+		if err != nil {
+			return nil, err
+		}
+		corrections = append(corrections, c)
+	}
+	return corrections, rows.Err()
+}
+
+// ResolveCorrection resolves an open ticket. Whatever the wallet's stored
+// balance has over its ledger by now is booked to the ledger against the
+// suspense account, so that the two agree again. The lines are written
+// here rather than through postJournal, which would move the stored balance
+// along with them.
+func (p *Postgres) ResolveCorrection(id int) (wallet.Correction, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Correction{}, err
+	}
+	defer tx.Rollback()
+
+	c, err := scanCorrection(tx.QueryRow("SELECT "+correctionColumns+" FROM balance_corrections WHERE id = $1 FOR UPDATE", id))
+	if err == sql.ErrNoRows {
+		return wallet.Correction{}, wallet.ErrCorrectionNotFound
+	}
+	if err != nil {
+		return wallet.Correction{}, err
+	}
+	if c.Status != wallet.CorrectionOpen {
+		return wallet.Correction{}, wallet.ErrCorrectionResolved
+	}
+
+	// Locking the wallet keeps postings from changing its balance and
+	// ledger while the difference is measured and booked.
+	var stored, ledger string
+	err = tx.QueryRow(`SELECT balance, (SELECT COALESCE(SUM(l.amount), 0) FROM ledger_entries l
+			WHERE l.account = 'wallet' AND l.wallet_id = user_wallet.id AND l.currency = user_wallet.currency)
+		FROM user_wallet WHERE id = $1 FOR UPDATE`, c.WalletID).Scan(&stored, &ledger)
+	if err == sql.ErrNoRows {
+		return wallet.Correction{}, wallet.ErrWalletNotFound
+	}
+	if err != nil {
+		return wallet.Correction{}, err
+	}
+	storedBalance, err := wallet.ParseFractionalMoney(stored, c.Currency)
+	if err != nil {
+		return wallet.Correction{}, err
+	}
+	ledgerBalance, err := wallet.ParseFractionalMoney(ledger, c.Currency)
+	if err != nil {
+		return wallet.Correction{}, err
+	}
+	difference, err := storedBalance.Sub(ledgerBalance)
+	if err != nil {
+		return wallet.Correction{}, err
+	}
+
+	var journalID sql.NullInt64
+	if !difference.IsZero() {
+		err = tx.QueryRow("INSERT INTO journal_entries (type, description) VALUES ($1, $2) RETURNING id",
+			wallet.TransactionAdjustment, fmt.Sprintf("Correction %d of wallet %d", id, c.WalletID)).Scan(&journalID)
+		if err != nil {
+			return wallet.Correction{}, err
+		}
+		_, err = tx.Exec(`INSERT INTO ledger_entries (journal_id, account, wallet_id, currency, amount)
+			VALUES ($1, $2, $3, $4, $5), ($1, $6, NULL, $4, $7)`,
+			journalID, wallet.AccountWallet, c.WalletID, c.Currency, difference, wallet.AccountSuspense, difference.Neg())
+		if err != nil {
+			return wallet.Correction{}, err
+		}
+	}
+	c, err = scanCorrection(tx.QueryRow("UPDATE balance_corrections SET status = $2, journal_id = $3, resolved_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING "+correctionColumns,
+		id, wallet.CorrectionResolved, journalID))
+	if err != nil {
+		return wallet.Correction{}, err
+	}
+	return c, tx.Commit()
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+// ledgerLines reads the ledger lines of the journal entries query selects,
+// in the order they were booked.
+func ledgerLines(q interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}, query string, args ...any) ([]wallet.LedgerLine, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []wallet.LedgerLine
+	for rows.Next() {
+		var l wallet.LedgerLine
+		var walletID sql.NullInt64
+		var amount string
+		if err := rows.Scan(&l.Account, &walletID, &l.Currency, &amount); err != nil {
+			return nil, err
+		}
+		l.WalletID = int(walletID.Int64)
+		if l.Amount, err = wallet.ParseMoney(amount, l.Currency); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+func (p *Postgres) JournalEntry(id int) (wallet.JournalEntry, error) {
+	entry := wallet.JournalEntry{ID: id}
+	var reversalOf sql.NullInt64
+	err := p.Db.QueryRow("SELECT type, reference, description, reversal_of, created_at FROM journal_entries WHERE id = $1", id).
+		Scan(&entry.Type, &entry.Reference, &entry.Description, &reversalOf, &entry.CreatedAt)
+	if err == sql.ErrNoRows {
+		return wallet.JournalEntry{}, wallet.ErrTransactionNotFound
+	}
+	if err != nil {
+		return wallet.JournalEntry{}, err
+	}
+	entry.ReversalOf = nullableInt(reversalOf)
+	entry.Lines, err = ledgerLines(p.Db, "SELECT account, wallet_id, currency, amount FROM ledger_entries WHERE journal_id = $1 ORDER BY id", id)
+	return entry, err
+}
+
+// ReverseTransaction books the reversal of a journal entry. The entry is
+// locked first, so concurrent reversals of it are serialized and cannot
+// together reverse more than it booked.
+func (p *Postgres) ReverseTransaction(id int, req wallet.ReversalRequest) (wallet.Reversal, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Reversal{}, err
+	}
+	defer tx.Rollback()
+
+	original := wallet.JournalEntry{ID: id}
+	var reversalOf sql.NullInt64
+	err = tx.QueryRow("SELECT type, reference, description, reversal_of, created_at FROM journal_entries WHERE id = $1 FOR UPDATE", id).
+		Scan(&original.Type, &original.Reference, &original.Description, &reversalOf, &original.CreatedAt)
+	if err == sql.ErrNoRows {
+		return wallet.Reversal{}, wallet.ErrTransactionNotFound
+	}
+	if err != nil {
+		return wallet.Reversal{}, err
+	}
+	if reversalOf.Valid {
+		return wallet.Reversal{}, wallet.ErrNotReversible
+	}
+	if original.Lines, err = ledgerLines(tx, "SELECT account, wallet_id, currency, amount FROM ledger_entries WHERE journal_id = $1 ORDER BY id", id); err != nil {
+		return wallet.Reversal{}, err
+	}
+	reversed, err := ledgerLines(tx, `SELECT l.account, l.wallet_id, l.currency, l.amount
+		FROM ledger_entries l JOIN journal_entries j ON j.id = l.journal_id
+		WHERE j.reversal_of = $1 ORDER BY l.id`, id)
+	if err != nil {
+		return wallet.Reversal{}, err
+	}
+
+	entry, r, err := wallet.PlanReversal(original, reversed, req)
+	if err != nil {
+		return wallet.Reversal{}, err
+	}
+	posted, err := postJournal(tx, entry)
+	if err != nil {
+		return wallet.Reversal{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return wallet.Reversal{}, err
+	}
+	r.ID, r.Lines, r.CreatedAt = posted.ID, posted.Lines, posted.CreatedAt
+	return r, nil
+}
@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+// scheduleClaim is how long a scheduler may take to run a schedule it has
+// claimed before another one may take it over.
+const scheduleClaim = "5 minutes"
+
+const scheduleColumns = `id, from_wallet_id, to_wallet_id, amount, currency, reference, frequency,
+	start_at, next_run_at, status, attempts, max_retries, created_by, created_at`
+
+func scanSchedule(row rowScanner) (wallet.Schedule, error) {
+	var s wallet.Schedule
+	var amount string
+	err := row.Scan(&s.ID, &s.FromWalletID, &s.ToWalletID, &amount, &s.Currency, &s.Reference, &s.Frequency,
+		&s.StartAt, &s.NextRunAt, &s.Status, &s.Attempts, &s.MaxRetries, &s.CreatedBy, &s.CreatedAt)
+	if err == sql.ErrNoRows {
+		return wallet.Schedule{}, wallet.ErrScheduleNotFound
+	}
+	if err != nil {
+		return wallet.Schedule{}, err
+	}
+	if s.Amount, err = wallet.ParseMoney(amount, s.Currency); err != nil {
+		return wallet.Schedule{}, err
+	}
+	return s, nil
+}
+
+func scanSchedules(rows *sql.Rows) ([]wallet.Schedule, error) {
+	defer rows.Close()
+
+	schedules := []wallet.Schedule{}
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+func (p *Postgres) CreateSchedule(s wallet.Schedule) (wallet.Schedule, error) {
+	return scanSchedule(p.Db.QueryRow(`INSERT INTO transfer_schedules
+		(from_wallet_id, to_wallet_id, amount, currency, reference, frequency, start_at, next_run_at, status, max_retries, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING `+scheduleColumns,
+		s.FromWalletID, s.ToWalletID, s.Amount, s.Currency, s.Reference, s.Frequency,
+		s.StartAt.UTC(), s.NextRunAt.UTC(), s.Status, s.MaxRetries, s.CreatedBy))
+}
+
+func (p *Postgres) ScheduleByID(id int) (wallet.Schedule, error) {
+	return scanSchedule(p.Db.QueryRow("SELECT "+scheduleColumns+" FROM transfer_schedules WHERE id = $1", id))
+}
+
+func (p *Postgres) Schedules(walletID int) ([]wallet.Schedule, error) {
+	rows, err := p.Db.Query("SELECT "+scheduleColumns+" FROM transfer_schedules WHERE from_wallet_id = $1 OR to_wallet_id = $1 ORDER BY id", walletID)
+	if err != nil {
+		return nil, err
+	}
+	return scanSchedules(rows)
+}
+
+// UpdateSchedule replaces the schedule and drops any claim on it, so a run in
+// progress does not overwrite the new definition when it is recorded.
+func (p *Postgres) UpdateSchedule(id int, s wallet.Schedule) (wallet.Schedule, error) {
+	return scanSchedule(p.Db.QueryRow(`UPDATE transfer_schedules SET
+		from_wallet_id = $1, to_wallet_id = $2, amount = $3, currency = $4, reference = $5, frequency = $6,
+		start_at = $7, next_run_at = $8, status = $9, max_retries = $10, created_by = $11, attempts = 0, claimed_until = NULL
+		WHERE id = $12
+		RETURNING `+scheduleColumns,
+		s.FromWalletID, s.ToWalletID, s.Amount, s.Currency, s.Reference, s.Frequency,
+		s.StartAt.UTC(), s.NextRunAt.UTC(), s.Status, s.MaxRetries, s.CreatedBy, id))
+}
+
+func (p *Postgres) DeleteSchedule(id int) error {
+	res, err := p.Db.Exec("DELETE FROM transfer_schedules WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return wallet.ErrScheduleNotFound
+	}
+	return nil
+}
+
+func (p *Postgres) ScheduleExecutions(scheduleID int) ([]wallet.ScheduleExecution, error) {
+	if _, err := p.ScheduleByID(scheduleID); err != nil {
+		return nil, err
+	}
+	rows, err := p.Db.Query(`SELECT id, schedule_id, due_at, attempt, status, journal_id, error, executed_at
+		FROM schedule_executions WHERE schedule_id = $1 ORDER BY id DESC`, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	executions := []wallet.ScheduleExecution{}
+	for rows.Next() {
+		var e wallet.ScheduleExecution
+		var journalID sql.NullInt64
+		err := rows.Scan(&e.ID, &e.ScheduleID, &e.DueAt, &e.Attempt, &e.Status, &journalID, &e.Error, &e.ExecutedAt)
+		if err != nil {
+			return nil, err
+		}
+		if journalID.Valid {
+			id := int(journalID.Int64)
+			e.TransactionID = &id
+		}
+		executions = append(executions, e)
+	}
+	return executions, rows.Err()
+}
+
+// DueSchedules claims up to limit active schedules due at now. Rows another
+// scheduler is claiming are skipped rather than waited for.
+func (p *Postgres) DueSchedules(now time.Time, limit int) ([]wallet.Schedule, error) {
+	rows, err := p.Db.Query(`UPDATE transfer_schedules SET claimed_until = $1::timestamp + interval '`+scheduleClaim+`'
+		WHERE id IN (
+			SELECT id FROM transfer_schedules
+			WHERE status = 'active' AND next_run_at <= $1::timestamp
+				AND (claimed_until IS NULL OR claimed_until <= $1::timestamp)
+			ORDER BY next_run_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+scheduleColumns, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanSchedules(rows)
+}
+
+// RecordScheduleExecution logs e and stores the schedule's next run. If the
+// schedule was updated while it ran, the claim is gone and the update wins.
+func (p *Postgres) RecordScheduleExecution(s wallet.Schedule, e wallet.ScheduleExecution) error {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow("SELECT true FROM transfer_schedules WHERE id = $1 FOR UPDATE", s.ID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE transfer_schedules SET next_run_at = $1, status = $2, attempts = $3, claimed_until = NULL
+		WHERE id = $4 AND claimed_until IS NOT NULL`, s.NextRunAt.UTC(), s.Status, s.Attempts, s.ID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO schedule_executions (schedule_id, due_at, attempt, status, journal_id, error, executed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`, s.ID, e.DueAt.UTC(), e.Attempt, e.Status, e.TransactionID, e.Error, e.ExecutedAt.UTC())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
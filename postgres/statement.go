@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+const statementColumns = `id, wallet_id, currency, period_start, period_end, opening_balance, purchases, payments,
+	closing_balance, minimum_payment, due_date, interest, closed_at, created_at`
+
+func scanStatement(row rowScanner) (wallet.Statement, error) {
+	var s wallet.Statement
+	var opening, purchases, payments, closing, minimum, interest string
+	var closedAt sql.NullTime
+	err := row.Scan(&s.ID, &s.WalletID, &s.Currency, &s.PeriodStart, &s.PeriodEnd, &opening, &purchases, &payments,
+		&closing, &minimum, &s.DueDate, &interest, &closedAt, &s.CreatedAt)
+	if err != nil {
+		return wallet.Statement{}, err
+	}
+	for _, f := range []struct {
+		dst *wallet.Money
+		src string
+	}{
+		{&s.OpeningBalance, opening}, {&s.Purchases, purchases}, {&s.Payments, payments},
+		{&s.ClosingBalance, closing}, {&s.MinimumPayment, minimum}, {&s.Interest, interest},
+	} {
+		if *f.dst, err = wallet.ParseMoney(f.src, s.Currency); err != nil {
+			return wallet.Statement{}, err
+		}
+	}
+	if closedAt.Valid {
+		s.ClosedAt = &closedAt.Time
+	}
+	return s, nil
+}
+
+func scanStatements(rows *sql.Rows) ([]wallet.Statement, error) {
+	defer rows.Close()
+
+	statements := []wallet.Statement{}
+	for rows.Next() {
+		s, err := scanStatement(rows)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, s)
+	}
+	return statements, rows.Err()
+}
+
+// UnbilledWallets returns the wallets of walletType that existed before
+// periodStart's month ended and have no statement for it yet.
+func (p *Postgres) UnbilledWallets(walletType string, periodStart time.Time) ([]wallet.Wallet, error) {
+	rows, err := p.Db.Query(`SELECT `+walletColumns+` FROM user_wallet
+		WHERE wallet_type = $1 AND status <> 'closed' AND deleted_at IS NULL AND created_at < $2::timestamp + interval '1 month'
+			AND NOT EXISTS (SELECT 1 FROM statements s WHERE s.wallet_id = user_wallet.id AND s.period_start = $2::timestamp)
+		ORDER BY id`, walletType, periodStart.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return scanWallets(rows)
+}
+
+// StatementActivity reads the wallet's ledger lines; the ledger keeps every
+// movement, so the sums are the same however late they are taken.
+func (p *Postgres) StatementActivity(walletID int, from, to time.Time) (wallet.StatementActivity, error) {
+	w, err := p.WalletByID(walletID)
+	if err != nil {
+		return wallet.StatementActivity{}, err
+	}
+	var opening, debits, credits string
+	err = p.Db.QueryRow(`SELECT
+			COALESCE(SUM(amount) FILTER (WHERE created_at < $2), 0),
+			COALESCE(-SUM(amount) FILTER (WHERE created_at >= $2 AND amount < 0), 0),
+			COALESCE(SUM(amount) FILTER (WHERE created_at >= $2 AND amount > 0), 0)
+		FROM ledger_entries
+		WHERE account = 'wallet' AND wallet_id = $1 AND created_at < $3`, walletID, from.UTC(), to.UTC()).Scan(&opening, &debits, &credits)
+	if err != nil {
+		return wallet.StatementActivity{}, err
+	}
+	var a wallet.StatementActivity
+	if a.Opening, err = wallet.ParseMoney(opening, w.Currency); err != nil {
+		return wallet.StatementActivity{}, err
+	}
+	if a.Debits, err = wallet.ParseMoney(debits, w.Currency); err != nil {
+		return wallet.StatementActivity{}, err
+	}
+	if a.Credits, err = wallet.ParseMoney(credits, w.Currency); err != nil {
+		return wallet.StatementActivity{}, err
+	}
+	return a, nil
+}
+
+// CreateStatement stores s unless the wallet already has a statement for the
+// period, in which case the existing one is returned.
+func (p *Postgres) CreateStatement(s wallet.Statement) (wallet.Statement, error) {
+	_, err := p.Db.Exec(`INSERT INTO statements (wallet_id, currency, period_start, period_end, opening_balance, purchases, payments,
+			closing_balance, minimum_payment, due_date, interest)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (wallet_id, period_start) DO NOTHING`,
+		s.WalletID, s.Currency, s.PeriodStart.UTC(), s.PeriodEnd.UTC(), s.OpeningBalance, s.Purchases, s.Payments,
+		s.ClosingBalance, s.MinimumPayment, s.DueDate.UTC(), s.Interest)
+	if err != nil {
+		return wallet.Statement{}, err
+	}
+	return scanStatement(p.Db.QueryRow("SELECT "+statementColumns+" FROM statements WHERE wallet_id = $1 AND period_start = $2", s.WalletID, s.PeriodStart.UTC()))
+}
+
+func (p *Postgres) Statements(walletID int) ([]wallet.Statement, error) {
+	rows, err := p.Db.Query("SELECT "+statementColumns+" FROM statements WHERE wallet_id = $1 ORDER BY period_start DESC", walletID)
+	if err != nil {
+		return nil, err
+	}
+	return scanStatements(rows)
+}
+
+func (p *Postgres) DueStatements(now time.Time) ([]wallet.Statement, error) {
+	rows, err := p.Db.Query("SELECT "+statementColumns+" FROM statements WHERE closed_at IS NULL AND due_date <= $1 ORDER BY id", now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return scanStatements(rows)
+}
+
+// CloseStatement charges interest on the statement's wallet and closes it in
+// one transaction. A statement that is already closed is left as it is.
+func (p *Postgres) CloseStatement(id int, interest wallet.Money) (wallet.Statement, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Statement{}, err
+	}
+	defer tx.Rollback()
+
+	s, err := scanStatement(tx.QueryRow("SELECT "+statementColumns+" FROM statements WHERE id = $1 FOR UPDATE", id))
+	if err != nil {
+		return wallet.Statement{}, err
+	}
+	if s.ClosedAt != nil {
+		return s, nil
+	}
+	if interest.IsPositive() {
+		_, err = postJournal(tx, wallet.JournalEntry{
+			Type:        wallet.TransactionInterest,
+			Reference:   fmt.Sprintf("STATEMENT-%d", id),
+			Description: fmt.Sprintf("Interest on statement %d", id),
+			Lines: []wallet.LedgerLine{
+				wallet.WalletLine(s.WalletID, interest.Neg()),
+				wallet.AccountLine(wallet.AccountInterest, interest),
+			},
+		})
+		if err != nil {
+			return wallet.Statement{}, err
+		}
+	}
+	s, err = scanStatement(tx.QueryRow("UPDATE statements SET interest = $1, closed_at = CURRENT_TIMESTAMP WHERE id = $2 RETURNING "+statementColumns, interest, id))
+	if err != nil {
+		return wallet.Statement{}, err
+	}
+	return s, tx.Commit()
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+// ChangeWalletStatus moves the wallet to status to and records the change.
+// The wallet stays locked while the transition is checked, so a posting
+// cannot slip in between.
+func (p *Postgres) ChangeWalletStatus(id int, to, reason string) (wallet.Wallet, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockWallets(tx, id); err != nil {
+		return wallet.Wallet{}, err
+	}
+	w, err := walletByID(tx, id)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if err := w.CheckTransition(to); err != nil {
+		return wallet.Wallet{}, err
+	}
+	if to == wallet.WalletStatusClosed {
+		if err := checkFeeWallet(tx, id); err != nil {
+			return wallet.Wallet{}, err
+		}
+	}
+	_, err = tx.Exec("INSERT INTO wallet_status_changes (wallet_id, from_status, to_status, reason) VALUES ($1, $2, $3, $4)", id, w.Status, to, reason)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	_, err = tx.Exec("UPDATE user_wallet SET status = $1, status_reason = $2 WHERE id = $3", to, reason, id)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if w, err = walletByID(tx, id); err != nil {
+		return wallet.Wallet{}, err
+	}
+	return w, tx.Commit()
+}
+
+func (p *Postgres) StatusChanges(walletID int) ([]wallet.StatusChange, error) {
+	rows, err := p.Db.Query(`SELECT id, wallet_id, from_status, to_status, reason, created_at
+		FROM wallet_status_changes WHERE wallet_id = $1 ORDER BY id DESC`, walletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []wallet.StatusChange{}
+	for rows.Next() {
+		var c wallet.StatusChange
+		if err := rows.Scan(&c.ID, &c.WalletID, &c.From, &c.To, &c.Reason, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
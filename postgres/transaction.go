@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+	"github.com/lib/pq"
+)
+
+func (p *Postgres) Deposit(walletID int, m wallet.Movement) (wallet.Transaction, error) {
+	return p.postMovement(walletID, wallet.TransactionDeposit, fmt.Sprintf("Deposit to wallet %d", walletID), m.Amount, m)
+}
+
+func (p *Postgres) Withdraw(walletID int, m wallet.Movement) (wallet.Transaction, error) {
+	return p.postMovement(walletID, wallet.TransactionWithdrawal, fmt.Sprintf("Withdrawal from wallet %d", walletID), m.Amount.Neg(), m)
+}
+
+// postMovement books amount against the external account, so money enters
+// the wallet when amount is positive and leaves it when negative, and then
+// the movement's fee.
+func (p *Postgres) postMovement(walletID int, kind, description string, amount wallet.Money, m wallet.Movement) (wallet.Transaction, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Transaction{}, err
+	}
+	defer tx.Rollback()
+
+	entry, err := postJournal(tx, wallet.JournalEntry{
+		Type:        kind,
+		Reference:   m.Reference,
+		Description: description,
+		Lines: []wallet.LedgerLine{
+			wallet.WalletLine(walletID, amount),
+			wallet.AccountLine(wallet.AccountExternal, amount.Neg()),
+		},
+	})
+	if err != nil {
+		return wallet.Transaction{}, err
+	}
+	if err := postFee(tx, walletID, m.Fee, kind, entry.ID, m.Reference); err != nil {
+		return wallet.Transaction{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return wallet.Transaction{}, err
+	}
+	return wallet.Transaction{
+		ID:        entry.ID,
+		WalletID:  walletID,
+		Type:      kind,
+		Amount:    amount,
+		Currency:  amount.Currency(),
+		Reference: m.Reference,
+		CreatedAt: entry.CreatedAt,
+	}, nil
+}
+
+// transactionQuery selects a wallet's lines as transactions, with the
+// other wallet of the entry and the labels the wallet's user gave it.
+const transactionQuery = `SELECT j.id, l.wallet_id, j.type, l.amount, l.currency, j.reference, j.description,
+		(SELECT o.wallet_id FROM ledger_entries o WHERE o.journal_id = j.id AND o.account = 'wallet' AND o.wallet_id <> l.wallet_id ORDER BY o.id LIMIT 1),
+		t.category_id, COALESCE(c.name, ''), COALESCE(t.tags, '{}'), j.reversal_of, j.created_at
+	FROM ledger_entries l JOIN journal_entries j ON j.id = l.journal_id
+	LEFT JOIN transaction_labels t ON t.journal_id = j.id AND t.wallet_id = l.wallet_id
+	LEFT JOIN categories c ON c.id = t.category_id
+	WHERE l.account = 'wallet' AND l.wallet_id = $1`
+
+func scanTransaction(row rowScanner) (wallet.Transaction, error) {
+	var t wallet.Transaction
+	var amount string
+	var counterparty, categoryID, reversalOf sql.NullInt64
+	var tags []string
+	err := row.Scan(&t.ID, &t.WalletID, &t.Type, &amount, &t.Currency, &t.Reference, &t.Description,
+		&counterparty, &categoryID, &t.Category, pq.Array(&tags), &reversalOf, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return wallet.Transaction{}, wallet.ErrTransactionNotFound
+	}
+	if err != nil {
+		return wallet.Transaction{}, err
+	}
+	if t.Amount, err = wallet.ParseMoney(amount, t.Currency); err != nil {
+		return wallet.Transaction{}, err
+	}
+	for _, n := range []struct {
+		col sql.NullInt64
+		to  **int
+	}{{counterparty, &t.CounterpartyWalletID}, {categoryID, &t.CategoryID}, {reversalOf, &t.ReversalOf}} {
+		if n.col.Valid {
+			id := int(n.col.Int64)
+			*n.to = &id
+		}
+	}
+	if len(tags) > 0 {
+		t.Tags = tags
+	}
+	return t, nil
+}
+
+func (p *Postgres) Transactions(walletID int, f wallet.TransactionFilter) ([]wallet.Transaction, error) {
+	query := transactionQuery
+	args := []any{walletID}
+	where := func(cond string, arg any) {
+		args = append(args, arg)
+		query += fmt.Sprintf(" AND "+cond, len(args))
+	}
+	if f.Cursor > 0 {
+		where("j.id < $%d", f.Cursor)
+	}
+	if len(f.Types) > 0 {
+		where("j.type = ANY($%d)", pq.Array(f.Types))
+	}
+	if f.MinAmount != nil {
+		where("ABS(l.amount) >= $%d", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		where("ABS(l.amount) <= $%d", *f.MaxAmount)
+	}
+	if !f.From.IsZero() {
+		where("j.created_at >= $%d", f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		where("j.created_at < $%d", f.To.UTC())
+	}
+	args = append(args, f.Limit)
+	query += fmt.Sprintf(" ORDER BY j.id DESC LIMIT $%d", len(args))
+
+	rows, err := p.Db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []wallet.Transaction
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
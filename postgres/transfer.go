@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+func (p *Postgres) Transfer(t wallet.Transfer) (wallet.TransferResult, error) {
+	if t.Run != nil {
+		// A run paid before is not held up by the wallets as they are now.
+		paid, err := scheduleRunPayment(p.Db, *t.Run)
+		if err != nil {
+			return wallet.TransferResult{}, err
+		}
+		if paid != 0 {
+			return p.transferResult(paid, t)
+		}
+	}
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.TransferResult{}, err
+	}
+	defer tx.Rollback()
+
+	entry, err := postJournal(tx, wallet.JournalEntry{
+		Type:        wallet.TransactionTransfer,
+		Reference:   t.Reference,
+		Description: fmt.Sprintf("Transfer from wallet %d to wallet %d", t.FromWalletID, t.ToWalletID),
+		Lines:       transferLines(t),
+	})
+	if err != nil {
+		return wallet.TransferResult{}, err
+	}
+	if err := postFee(tx, t.FromWalletID, t.Fee, wallet.TransactionTransfer, entry.ID, t.Reference); err != nil {
+		return wallet.TransferResult{}, err
+	}
+	if t.Run != nil {
+		paid, err := payScheduleRun(tx, *t.Run, entry.ID)
+		if err != nil {
+			return wallet.TransferResult{}, err
+		}
+		if paid != entry.ID {
+			tx.Rollback()
+			return p.transferResult(paid, t)
+		}
+	}
+	from, err := walletByID(tx, t.FromWalletID)
+	if err != nil {
+		return wallet.TransferResult{}, err
+	}
+	to, err := walletByID(tx, t.ToWalletID)
+	if err != nil {
+		return wallet.TransferResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return wallet.TransferResult{}, err
+	}
+	return wallet.TransferResult{TransactionID: entry.ID, From: from, To: to}, nil
+}
+
+// payScheduleRun records journalID as the payment of run and returns it, or
+// the journal entry of an earlier payment of the same run. A concurrent
+// payment of the run is waited for.
+func payScheduleRun(tx *sql.Tx, run wallet.ScheduleRun, journalID int) (int, error) {
+	res, err := tx.Exec(`INSERT INTO schedule_payments (schedule_id, due_at, journal_id) VALUES ($1, $2, $3)
+		ON CONFLICT (schedule_id, due_at) DO NOTHING`, run.ScheduleID, run.DueAt.UTC(), journalID)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 1 {
+		return journalID, err
+	}
+	return scheduleRunPayment(tx, run)
+}
+
+// scheduleRunPayment returns the journal entry that paid run, or 0 if it has
+// not been paid.
+func scheduleRunPayment(q querier, run wallet.ScheduleRun) (int, error) {
+	var paid int
+	err := q.QueryRow("SELECT journal_id FROM schedule_payments WHERE schedule_id = $1 AND due_at = $2", run.ScheduleID, run.DueAt.UTC()).Scan(&paid)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return paid, err
+}
+
+// transferResult describes the transfer booked as journalID.
+func (p *Postgres) transferResult(journalID int, t wallet.Transfer) (wallet.TransferResult, error) {
+	from, err := p.WalletByID(t.FromWalletID)
+	if err != nil {
+		return wallet.TransferResult{}, err
+	}
+	to, err := p.WalletByID(t.ToWalletID)
+	if err != nil {
+		return wallet.TransferResult{}, err
+	}
+	return wallet.TransferResult{TransactionID: journalID, From: from, To: to}, nil
+}
+
+// transferLines books a transfer. Across currencies the fx account buys the
+// debited amount and sells the credited one, so both currencies balance.
+func transferLines(t wallet.Transfer) []wallet.LedgerLine {
+	credited := t.Credited
+	if credited.IsZero() {
+		credited = t.Amount
+	}
+	if credited.Currency() == t.Amount.Currency() {
+		return []wallet.LedgerLine{
+			wallet.WalletLine(t.FromWalletID, t.Amount.Neg()),
+			wallet.WalletLine(t.ToWalletID, credited),
+		}
+	}
+	return []wallet.LedgerLine{
+		wallet.WalletLine(t.FromWalletID, t.Amount.Neg()),
+		wallet.AccountLine(wallet.AccountFX, t.Amount),
+		wallet.AccountLine(wallet.AccountFX, credited.Neg()),
+		wallet.WalletLine(t.ToWalletID, credited),
+	}
+}
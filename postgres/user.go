@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+const userColumns = "id, name, created_at"
+
+func scanUser(row rowScanner) (wallet.User, error) {
+	var u wallet.User
+	err := row.Scan(&u.ID, &u.Name, &u.CreatedAt)
+	if err == sql.ErrNoRows {
+		return wallet.User{}, wallet.ErrUserNotFound
+	}
+	return u, err
+}
+
+// checkUser fails with ErrUnknownUser unless id names an existing user. It
+// locks the user so they cannot be deleted while a wallet is given to them.
+func checkUser(q querier, id int) error {
+	var found int
+	err := q.QueryRow("SELECT id FROM users WHERE id = $1 FOR SHARE", id).Scan(&found)
+	if err == sql.ErrNoRows {
+		return wallet.ErrUnknownUser
+	}
+	return err
+}
+
+func (p *Postgres) CreateUser(u wallet.User) (wallet.User, error) {
+	return scanUser(p.Db.QueryRow("INSERT INTO users (name) VALUES ($1) RETURNING "+userColumns, u.Name))
+}
+
+func (p *Postgres) Users() ([]wallet.User, error) {
+	rows, err := p.Db.Query("SELECT " + userColumns + " FROM users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []wallet.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (p *Postgres) UserByID(id int) (wallet.User, error) {
+	return scanUser(p.Db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = $1", id))
+}
+
+// UpdateUser renames a user. Their wallets read the name from users, so
+// they all show it at once.
+func (p *Postgres) UpdateUser(id int, u wallet.User) (wallet.User, error) {
+	return scanUser(p.Db.QueryRow("UPDATE users SET name = $1 WHERE id = $2 RETURNING "+userColumns, u.Name, id))
+}
+
+// DeleteUser deletes a user who owns no wallets, counting deleted wallets
+// that have not been purged.
+func (p *Postgres) DeleteUser(id int) error {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Locking the user waits for wallets being created for them.
+	if _, err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM users WHERE id = $1 FOR UPDATE", id)); err != nil {
+		return err
+	}
+	var owns bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM user_wallet WHERE user_id = $1)", id).Scan(&owns)
+	if err != nil {
+		return err
+	}
+	if owns {
+		return wallet.ErrUserHasWallets
+	}
+	if _, err := tx.Exec("DELETE FROM users WHERE id = $1", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
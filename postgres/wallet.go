@@ -2,110 +2,420 @@ package postgres
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
 )
 
 type Wallet struct {
-	ID         int       `postgres:"id"`
-	UserID     int       `postgres:"user_id"`
-	UserName   string    `postgres:"user_name"`
-	WalletName string    `postgres:"wallet_name"`
-	WalletType string    `postgres:"wallet_type"`
-	Balance    float64   `postgres:"balance"`
-	CreatedAt  time.Time `postgres:"created_at"`
+	ID                int            `postgres:"id"`
+	UserID            int            `postgres:"user_id"`
+	UserName          string         `postgres:"users.name"`
+	UserCreatedAt     time.Time      `postgres:"users.created_at"`
+	WalletName        string         `postgres:"wallet_name"`
+	WalletType        string         `postgres:"wallet_type"`
+	Currency          string         `postgres:"currency"`
+	Balance           string         `postgres:"balance"`
+	Available         string         `postgres:"available_balance"`
+	CreditLimit       string         `postgres:"credit_limit"`
+	InterestProductID sql.NullInt64  `postgres:"interest_product_id"`
+	LimitPerTx        sql.NullString `postgres:"limit_per_transaction"`
+	LimitDaily        sql.NullString `postgres:"limit_daily"`
+	LimitMonthly      sql.NullString `postgres:"limit_monthly"`
+	Status            string         `postgres:"status"`
+	StatusReason      string         `postgres:"status_reason"`
+	DeletedAt         sql.NullTime   `postgres:"deleted_at"`
+	DeletedBy         string         `postgres:"deleted_by"`
+	Version           int            `postgres:"version"`
+	CreatedAt         time.Time      `postgres:"created_at"`
 }
 
-func (p *Postgres) Wallets(walletType string) ([]wallet.Wallet, error) {
-	var rows *sql.Rows
-	var err error
-	if walletType != "" {
-		rows, err = p.Db.Query("SELECT * FROM user_wallet WHERE wallet_type = $1", walletType)
-	} else {
-		rows, err = p.Db.Query("SELECT * FROM user_wallet")
+// heldAmount is the part of a user_wallet row's balance reserved by holds
+// that have neither been settled nor expired.
+const heldAmount = `(SELECT COALESCE(SUM(holds.amount), 0) FROM holds
+	WHERE holds.wallet_id = user_wallet.id AND holds.status = 'active' AND holds.expires_at > CURRENT_TIMESTAMP)`
+
+// ownerColumns is the owning user of a user_wallet row.
+const ownerColumns = `(SELECT users.name FROM users WHERE users.id = user_wallet.user_id),
+	(SELECT users.created_at FROM users WHERE users.id = user_wallet.user_id)`
+
+const walletColumns = "id, user_id, " + ownerColumns + ", wallet_name, wallet_type, currency, balance, balance - " + heldAmount + ", credit_limit, interest_product_id, " + limitColumns + ", status, status_reason, deleted_at, deleted_by, version, created_at"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWallet(row rowScanner) (wallet.Wallet, error) {
+	return scanWalletWith(row)
+}
+
+// scanWalletWith scans a row of walletColumns followed by the columns
+// scanned into extra.
+func scanWalletWith(row rowScanner, extra ...any) (wallet.Wallet, error) {
+	var w Wallet
+	err := row.Scan(append([]any{&w.ID,
+		&w.UserID, &w.UserName, &w.UserCreatedAt,
+		&w.WalletName, &w.WalletType,
+		&w.Currency, &w.Balance, &w.Available, &w.CreditLimit, &w.InterestProductID,
+		&w.LimitPerTx, &w.LimitDaily, &w.LimitMonthly,
+		&w.Status, &w.StatusReason, &w.DeletedAt, &w.DeletedBy, &w.Version, &w.CreatedAt,
+	}, extra...)...)
+	if err != nil {
+		return wallet.Wallet{}, err
 	}
+	balance, err := wallet.ParseMoney(w.Balance, w.Currency)
 	if err != nil {
-		return nil, err
+		return wallet.Wallet{}, err
+	}
+	available, err := wallet.ParseMoney(w.Available, w.Currency)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	creditLimit, err := wallet.ParseMoney(w.CreditLimit, w.Currency)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	limits, err := parseLimits(w.Currency, w.LimitPerTx, w.LimitDaily, w.LimitMonthly)
+	if err != nil {
+		return wallet.Wallet{}, err
 	}
+	var deletedAt *time.Time
+	if w.DeletedAt.Valid {
+		deletedAt = &w.DeletedAt.Time
+	}
+	var interestProductID *int
+	if w.InterestProductID.Valid {
+		id := int(w.InterestProductID.Int64)
+		interestProductID = &id
+	}
+	return wallet.Wallet{
+		ID:                w.ID,
+		UserID:            w.UserID,
+		User:              wallet.User{ID: w.UserID, Name: w.UserName, CreatedAt: w.UserCreatedAt},
+		WalletName:        w.WalletName,
+		WalletType:        w.WalletType,
+		Currency:          w.Currency,
+		Balance:           balance,
+		Available:         available,
+		CreditLimit:       creditLimit,
+		Limits:            limits,
+		InterestProductID: interestProductID,
+		Status:            w.Status,
+		StatusReason:      w.StatusReason,
+		DeletedAt:         deletedAt,
+		DeletedBy:         w.DeletedBy,
+		Version:           w.Version,
+		CreatedAt:         w.CreatedAt,
+	}, nil
+}
+
+func scanWallets(rows *sql.Rows) ([]wallet.Wallet, error) {
 	defer rows.Close()
 
 	var wallets []wallet.Wallet
 	for rows.Next() {
-		var w Wallet
-		err := rows.Scan(&w.ID,
-			&w.UserID, &w.UserName,
-			&w.WalletName, &w.WalletType,
-			&w.Balance, &w.CreatedAt,
-		)
+		w, err := scanWallet(rows)
 		if err != nil {
 			return nil, err
 		}
-		wallets = append(wallets, wallet.Wallet{
-			ID:         w.ID,
-			UserID:     w.UserID,
-			UserName:   w.UserName,
-			WalletName: w.WalletName,
-			WalletType: w.WalletType,
-			Balance:    w.Balance,
-			CreatedAt:  w.CreatedAt,
-		})
+		wallets = append(wallets, w)
 	}
-	return wallets, nil
+	return wallets, rows.Err()
 }
 
-func (p *Postgres) WalletByUserID(id int) ([]wallet.Wallet, error) {
-	rows, err := p.Db.Query("SELECT * FROM user_wallet WHERE user_id = $1", id)
+// notDeleted filters out soft-deleted wallets unless includeDeleted is set.
+func notDeleted(includeDeleted bool) string {
+	if includeDeleted {
+		return ""
+	}
+	return " AND deleted_at IS NULL"
+}
+
+func (p *Postgres) Wallets(walletType string, includeDeleted bool) ([]wallet.Wallet, error) {
+	rows, err := p.Db.Query("SELECT "+walletColumns+" FROM user_wallet WHERE ($1 = '' OR wallet_type = $1)"+notDeleted(includeDeleted)+" ORDER BY id", walletType)
 	if err != nil {
 		return nil, err
 	}
+	return scanWallets(rows)
+}
+
+// memberRole is the role on a user_wallet row of the user in $1, and
+// memberWallets the rows they have one on.
+const (
+	memberRole    = "(SELECT m.role FROM wallet_members m WHERE m.wallet_id = user_wallet.id AND m.user_id = $1)"
+	memberWallets = " FROM user_wallet WHERE id IN (SELECT m.wallet_id FROM wallet_members m WHERE m.user_id = $1)"
+)
+
+// scanMemberWallets scans rows of walletColumns followed by the member's
+// role.
+func scanMemberWallets(rows *sql.Rows) ([]wallet.Wallet, error) {
 	defer rows.Close()
 
 	var wallets []wallet.Wallet
 	for rows.Next() {
-		var w Wallet
-		err := rows.Scan(&w.ID,
-			&w.UserID, &w.UserName,
-			&w.WalletName, &w.WalletType,
-			&w.Balance, &w.CreatedAt,
-		)
+		var role string
+		w, err := scanWalletWith(rows, &role)
 		if err != nil {
 			return nil, err
 		}
-		wallets = append(wallets, wallet.Wallet{
-			ID:         w.ID,
-			UserID:     w.UserID,
-			UserName:   w.UserName,
-			WalletName: w.WalletName,
-			WalletType: w.WalletType,
-			Balance:    w.Balance,
-			CreatedAt:  w.CreatedAt,
-		})
+		w.Role = role
+		wallets = append(wallets, w)
 	}
-	return wallets, nil
+	return wallets, rows.Err()
 }
 
-func (p *Postgres) CreateWallet(wallet wallet.Wallet) error {
-	row := p.Db.QueryRow("INSERT INTO user_wallet (user_id, user_name, wallet_name, wallet_type, balance) VALUES ($1, $2, $3, $4, $5) RETURNING id", wallet.UserID, wallet.UserName, wallet.WalletName, wallet.WalletType, wallet.Balance)
-	err := row.Scan(&wallet.ID)
+// WalletByUserID returns the wallets the user owns or that are shared with
+// them, each with their role.
+func (p *Postgres) WalletByUserID(id int, includeDeleted bool) ([]wallet.Wallet, error) {
+	rows, err := p.Db.Query("SELECT "+walletColumns+", "+memberRole+memberWallets+notDeleted(includeDeleted)+" ORDER BY id", id)
+	if err != nil {
+		return nil, err
+	}
+	return scanMemberWallets(rows)
+}
+
+// CreateWallet inserts w for its user, who must exist, and returns it as
+// stored.
+func (p *Postgres) CreateWallet(w wallet.Wallet) (wallet.Wallet, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	defer tx.Rollback()
+
+	if err := checkUser(tx, w.UserID); err != nil {
+		return wallet.Wallet{}, err
+	}
+	if err := checkInterestProduct(tx, w.InterestProductID); err != nil {
+		return wallet.Wallet{}, err
+	}
+	row := tx.QueryRow("INSERT INTO user_wallet (user_id, wallet_name, wallet_type, currency, balance, credit_limit, interest_product_id) VALUES ($1, $2, $3, $4, 0, $5, $6) RETURNING id", w.UserID, w.WalletName, w.WalletType, w.Currency, w.CreditLimit, w.InterestProductID)
+	err = row.Scan(&w.ID)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if err := addOwner(tx, w.ID, w.UserID); err != nil {
+		return wallet.Wallet{}, err
+	}
+	if !w.Balance.IsZero() {
+		_, err = postJournal(tx, balanceAdjustment(w.ID, wallet.TransactionOpening, "Opening balance", w.Balance))
+		if err != nil {
+			return wallet.Wallet{}, err
+		}
+	}
+	if w, err = walletByID(tx, w.ID); err != nil {
+		return wallet.Wallet{}, err
+	}
+	return w, tx.Commit()
+}
+
+// matchVersion is the condition of the UPDATEs that check the version a
+// client read; version 0 matches any.
+func matchVersion(arg string) string {
+	return fmt.Sprintf("(%[1]s = 0 OR version = %[1]s)", arg)
+}
+
+// updated fails with ErrVersionMismatch if res changed no rows, which for
+// an UPDATE on a locked wallet means its version did not match.
+func updated(res sql.Result) error {
+	n, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if n == 0 {
+		return wallet.ErrVersionMismatch
+	}
 	return nil
 }
 
-func (p *Postgres) UpdateWallet(id int, wallet wallet.Wallet) error {
-	_, err := p.Db.Exec("UPDATE user_wallet SET user_id = $1, user_name = $2, wallet_name = $3, wallet_type = $4, balance = $5 WHERE id = $6", wallet.UserID, wallet.UserName, wallet.WalletName, wallet.WalletType, wallet.Balance, id)
+// UpdateWallet overwrites the wallet's descriptive fields, credit limit and
+// interest product if its version is still version. Its type must stay the
+// same, and its balance and currency are left as they are: balances only
+// change through movements that are held to the wallet's funds, status and
+// spending limits. The credit limit is checked against the stored balance.
+func (p *Postgres) UpdateWallet(id int, w wallet.Wallet, version int) (wallet.Wallet, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockWallets(tx, id); err != nil {
+		return wallet.Wallet{}, err
+	}
+	current, err := walletByID(tx, id)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if w.WalletType != current.WalletType {
+		return wallet.Wallet{}, wallet.ErrWalletTypeChange
+	}
+	creditLimit, err := w.CreditLimit.WithCurrency(current.Currency)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if err := checkUser(tx, w.UserID); err != nil {
+		return wallet.Wallet{}, err
+	}
+	if err := checkInterestProduct(tx, w.InterestProductID); err != nil {
+		return wallet.Wallet{}, err
+	}
+	res, err := tx.Exec("UPDATE user_wallet SET user_id = $1, wallet_name = $2, credit_limit = $3, interest_product_id = $4 WHERE id = $5 AND "+matchVersion("$6"),
+		w.UserID, w.WalletName, creditLimit, w.InterestProductID, id, version)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if err := updated(res); err != nil {
+		return wallet.Wallet{}, err
+	}
+	if err := addOwner(tx, id, w.UserID); err != nil {
+		return wallet.Wallet{}, err
+	}
+	if w, err = walletByID(tx, id); err != nil {
+		return wallet.Wallet{}, err
+	}
+	if err := w.CheckCreditLimit(); err != nil {
+		return wallet.Wallet{}, err
+	}
+	return w, tx.Commit()
+}
+
+// PatchWallet writes only the given fields of w, which must be among those
+// wallet patches may change, if the wallet's version is still version. It
+// returns the wallet as stored.
+func (p *Postgres) PatchWallet(id int, w wallet.Wallet, fields []string, version int) (wallet.Wallet, error) {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockWallets(tx, id); err != nil {
+		return wallet.Wallet{}, err
+	}
+	var sets []string
+	var args []any
+	for _, f := range fields {
+		var v any
+		switch f {
+		case "wallet_name":
+			v = w.WalletName
+		case "credit_limit":
+			v = w.CreditLimit
+		case "interest_product_id":
+			if err := checkInterestProduct(tx, w.InterestProductID); err != nil {
+				return wallet.Wallet{}, err
+			}
+			v = w.InterestProductID
+		default:
+			return wallet.Wallet{}, fmt.Errorf("%s cannot be patched", f)
+		}
+		args = append(args, v)
+		sets = append(sets, fmt.Sprintf("%s = $%d", f, len(args)))
+	}
+	args = append(args, id, version)
+	res, err := tx.Exec(fmt.Sprintf("UPDATE user_wallet SET %s WHERE id = $%d AND %s", strings.Join(sets, ", "), len(args)-1, matchVersion(fmt.Sprintf("$%d", len(args)))), args...)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if err := updated(res); err != nil {
+		return wallet.Wallet{}, err
+	}
+	w, err = walletByID(tx, id)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if err := w.CheckCreditLimit(); err != nil {
+		return wallet.Wallet{}, err
+	}
+	return w, tx.Commit()
+}
+
+func balanceAdjustment(walletID int, kind, description string, amount wallet.Money) wallet.JournalEntry {
+	return wallet.JournalEntry{
+		Type:        kind,
+		Description: description,
+		Lines: []wallet.LedgerLine{
+			wallet.WalletLine(walletID, amount),
+			wallet.AccountLine(wallet.AccountEquity, amount.Neg()),
+		},
+	}
+}
+
+// DeleteWallet soft-deletes every wallet of the user, recording who asked.
+// Nothing is deleted unless all of them are empty and none is a house fee
+// wallet. The rows stay until PurgeWallets removes them.
+func (p *Postgres) DeleteWallet(id int, by string) error {
+	tx, err := p.Db.Begin()
 	if err != nil {
 		return err
 	}
-	return nil
+	defer tx.Rollback()
+
+	// Lock in id order, as lockWallets does, so that postings cannot
+	// fund a wallet between the check and the deletion.
+	rows, err := tx.Query("SELECT "+walletColumns+" FROM user_wallet WHERE user_id = $1 AND deleted_at IS NULL ORDER BY id FOR UPDATE", id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		w, err := scanWallet(rows)
+		if err != nil {
+			return err
+		}
+		if err := w.CheckEmpty(); err != nil {
+			return fmt.Errorf("wallet %d: %w", w.ID, err)
+		}
+		ids = append(ids, w.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	for _, walletID := range ids {
+		if err := checkFeeWallet(tx, walletID); err != nil {
+			return fmt.Errorf("wallet %d: %w", walletID, err)
+		}
+	}
+	if _, err := tx.Exec("UPDATE user_wallet SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $2 WHERE user_id = $1 AND deleted_at IS NULL", id, by); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (p *Postgres) DeleteWallet(id int) error {
-	_, err := p.Db.Exec("DELETE FROM user_wallet WHERE user_id = $1", id)
+// DeleteWalletByID soft-deletes one empty wallet that is not a house fee
+// wallet if its version is still version, recording who asked. A wallet that does not exist or is already
+// deleted is not found.
+func (p *Postgres) DeleteWalletByID(id int, by string, version int) error {
+	tx, err := p.Db.Begin()
 	if err != nil {
 		return err
 	}
-	return nil
+	defer tx.Rollback()
+
+	if _, err := lockWallets(tx, id); err != nil {
+		return err
+	}
+	w, err := walletByID(tx, id)
+	if err != nil {
+		return err
+	}
+	if err := w.CheckEmpty(); err != nil {
+		return err
+	}
+	if err := checkFeeWallet(tx, id); err != nil {
+		return err
+	}
+	res, err := tx.Exec("UPDATE user_wallet SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $2 WHERE id = $1 AND "+matchVersion("$3"), id, by, version)
+	if err != nil {
+		return err
+	}
+	if err := updated(res); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
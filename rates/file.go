@@ -0,0 +1,89 @@
+package rates
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+// File is a wallet.RateProvider backed by a JSON file for local use, e.g.
+//
+//	{"USD": {"THB": "36.50"}}
+//
+// meaning one USD buys 36.50 THB. Rates are written as decimal strings so
+// they are read exactly. Missing directions are served from the inverse
+// rate or crossed through a currency both sides are quoted against.
+type File struct {
+	rates      map[string]map[string]*big.Rat
+	currencies []string
+}
+
+func NewFile(path string) (*File, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]map[string]string
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	f := &File{rates: map[string]map[string]*big.Rat{}}
+	for from, quotes := range raw {
+		for to, s := range quotes {
+			r, ok := new(big.Rat).SetString(s)
+			if !ok || r.Sign() <= 0 {
+				return nil, fmt.Errorf("parse %s: invalid rate %s/%s %q", path, from, to, s)
+			}
+			f.set(from, to, r)
+		}
+	}
+	sort.Strings(f.currencies)
+	return f, nil
+}
+
+func (f *File) set(from, to string, r *big.Rat) {
+	if f.rates[from] == nil {
+		f.rates[from] = map[string]*big.Rat{}
+	}
+	f.rates[from][to] = r
+	for _, c := range []string{from, to} {
+		if !slices.Contains(f.currencies, c) {
+			f.currencies = append(f.currencies, c)
+		}
+	}
+}
+
+func (f *File) direct(from, to string) (*big.Rat, bool) {
+	if r, ok := f.rates[from][to]; ok {
+		return r, true
+	}
+	if r, ok := f.rates[to][from]; ok {
+		return new(big.Rat).Inv(r), true
+	}
+	return nil, false
+}
+
+func (f *File) Rate(from, to string) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+	if r, ok := f.direct(from, to); ok {
+		return new(big.Rat).Set(r), nil
+	}
+	for _, pivot := range f.currencies {
+		a, ok := f.direct(from, pivot)
+		if !ok {
+			continue
+		}
+		if b, ok := f.direct(pivot, to); ok {
+			return new(big.Rat).Mul(a, b), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s to %s", wallet.ErrRateUnavailable, from, to)
+}
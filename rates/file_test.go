@@ -0,0 +1,48 @@
+//go:build unit
+
+package rates
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(path, []byte(`{"USD": {"THB": "36.50"}, "BTC": {"USD": "67000"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	tests := []struct {
+		from, to string
+		want     *big.Rat
+	}{
+		{"USD", "THB", big.NewRat(73, 2)},
+		{"THB", "USD", big.NewRat(2, 73)},
+		{"BTC", "THB", big.NewRat(2445500, 1)},
+		{"THB", "THB", big.NewRat(1, 1)},
+	}
+	for _, tt := range tests {
+		got, err := f.Rate(tt.from, tt.to)
+		if err != nil {
+			t.Errorf("%s/%s: unexpected error %v", tt.from, tt.to, err)
+			continue
+		}
+		if got.Cmp(tt.want) != 0 {
+			t.Errorf("%s/%s: expected %s but got %s", tt.from, tt.to, tt.want, got)
+		}
+	}
+
+	if _, err := f.Rate("EUR", "THB"); !errors.Is(err, wallet.ErrRateUnavailable) {
+		t.Errorf("expected ErrRateUnavailable but got %v", err)
+	}
+}
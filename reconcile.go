@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/wallet"
+)
+
+// reconcile runs the reconcile command, which writes the reconciliation
+// report to out. It returns the exit status: 1 if any balance disagrees
+// with its ledger, so that cron jobs can alert on it, and 2 on errors.
+//
+//	funx reconcile [-format json|csv] [-open-corrections]
+func reconcile(h *wallet.Handler, args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	format := flags.String("format", "json", "report format, json or csv")
+	openCorrections := flags.Bool("open-corrections", false, "open a correction ticket for each wallet that disagrees")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "json" && *format != "csv" {
+		fmt.Fprintln(os.Stderr, "reconcile: format must be json or csv")
+		return 2
+	}
+
+	report, err := h.Reconcile(*openCorrections)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "reconcile:", err)
+		return 2
+	}
+	if *format == "csv" {
+		err = report.WriteCSV(out)
+	} else {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "reconcile:", err)
+		return 2
+	}
+	if len(report.Discrepancies) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// ErrCapitalizedConcurrently is returned when another run capitalized a
+// wallet's accruals first. That run paid them, so there is nothing left to
+// do.
+var ErrCapitalizedConcurrently = errors.New("interest was capitalized concurrently")
+
+// InterestAccrual is the batch job paying interest on Savings wallets. Each
+// run accrues every day that has ended since a wallet last accrued, from its
+// balance at the end of that day, and capitalizes the accruals of months
+// that are over. A day is accrued at most once, so runs may repeat and
+// several servers may run the job.
+type InterestAccrual struct {
+	handler  *Handler
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewInterestAccrual(h *Handler) *InterestAccrual {
+	return &InterestAccrual{
+		handler:  h,
+		interval: defaultBillingInterval,
+		now:      time.Now,
+	}
+}
+
+// Run accrues interest every interval until ctx is done.
+func (a *InterestAccrual) Run(ctx context.Context) {
+	runEvery(ctx, a.interval, "interest", a.RunDue)
+}
+
+// RunDue accrues and capitalizes what is due now. A wallet that fails is
+// logged and left for the next run, without holding up the others.
+func (a *InterestAccrual) RunDue() error {
+	now := a.now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	accounts, err := a.handler.store.InterestAccounts()
+	if err != nil {
+		return err
+	}
+	for _, acc := range accounts {
+		if err := a.accrue(acc, today); err != nil {
+			log.Printf("accruing wallet %d: %v", acc.Wallet.ID, err)
+			continue
+		}
+		err := a.capitalize(acc.Wallet, today)
+		if err != nil && !errors.Is(err, ErrCapitalizedConcurrently) {
+			log.Printf("capitalizing wallet %d: %v", acc.Wallet.ID, err)
+		}
+	}
+	return nil
+}
+
+// accrue records a day's interest for every day before today the wallet has
+// not accrued for. A wallet new to interest starts with yesterday.
+func (a *InterestAccrual) accrue(acc InterestAccount, today time.Time) error {
+	day := today.AddDate(0, 0, -1)
+	if !acc.LastAccrual.IsZero() {
+		day = acc.LastAccrual.AddDate(0, 0, 1)
+	}
+	for ; day.Before(today); day = day.AddDate(0, 0, 1) {
+		end := day.AddDate(0, 0, 1)
+		if acc.Wallet.CreatedAt.After(end) {
+			continue
+		}
+		balance, err := a.handler.store.BalanceAt(acc.Wallet.ID, end)
+		if err != nil {
+			return err
+		}
+		amount, err := acc.Product.accrue(balance, day)
+		if err != nil {
+			return err
+		}
+		err = a.handler.store.RecordAccrual(Accrual{
+			WalletID:   acc.Wallet.ID,
+			Date:       day,
+			ProductID:  acc.Product.ID,
+			Balance:    balance,
+			AnnualRate: acc.Product.AnnualRate,
+			Amount:     amount,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// capitalize pays the accruals of the months before today's as one
+// interest transaction, rounded to the currency.
+func (a *InterestAccrual) capitalize(w Wallet, today time.Time) error {
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	accruals, err := a.handler.store.UnpaidAccruals(w.ID)
+	if err != nil {
+		return err
+	}
+	var due []Accrual
+	for _, acc := range accruals {
+		if acc.Date.Before(monthStart) {
+			due = append(due, acc)
+		}
+	}
+	if len(due) == 0 {
+		return nil
+	}
+	_, paid, err := sumAccruals(w.Currency, due)
+	if err != nil {
+		return err
+	}
+	return a.handler.store.CapitalizeInterest(w.ID, monthStart, len(due), paid)
+}
@@ -0,0 +1,74 @@
+package wallet
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Asset describes a currency or crypto asset wallets can hold. Digits is the
+// number of decimal places of its smallest unit, e.g. 8 for satoshi.
+type Asset struct {
+	Symbol string `json:"symbol" example:"BTC"`
+	Digits int    `json:"digits" example:"8"`
+	Crypto bool   `json:"crypto" example:"true"`
+}
+
+var assets = map[string]Asset{
+	"THB":  {Symbol: "THB", Digits: 2},
+	"USD":  {Symbol: "USD", Digits: 2},
+	"EUR":  {Symbol: "EUR", Digits: 2},
+	"GBP":  {Symbol: "GBP", Digits: 2},
+	"SGD":  {Symbol: "SGD", Digits: 2},
+	"BTC":  {Symbol: "BTC", Digits: 8, Crypto: true},
+	"ETH":  {Symbol: "ETH", Digits: 18, Crypto: true},
+	"USDT": {Symbol: "USDT", Digits: 6, Crypto: true},
+}
+
+// IsSupportedCurrency reports whether wallets can hold currency.
+func IsSupportedCurrency(currency string) bool {
+	_, ok := assets[currency]
+	return ok
+}
+
+// AssetOf returns what is known about currency. Unknown currencies are
+// treated as fiat with two decimal places.
+func AssetOf(currency string) Asset {
+	currency = normalizeCurrency(currency)
+	if a, ok := assets[currency]; ok {
+		return a
+	}
+	return Asset{Symbol: currency, Digits: 2}
+}
+
+// validateAsset requires Crypto Wallets to hold a crypto asset and every
+// other wallet type to hold a fiat currency.
+func (w Wallet) validateAsset() error {
+	crypto := AssetOf(w.Currency).Crypto
+	if w.WalletType == WalletTypeCrypto && !crypto {
+		return errors.New("a Crypto Wallet must hold a crypto asset")
+	}
+	if w.WalletType != WalletTypeCrypto && crypto {
+		return errors.New("crypto assets can only be held in a Crypto Wallet")
+	}
+	return nil
+}
+
+// AssetsHandler
+//
+//	@Summary		Get supported assets
+//	@Description	Get the currencies and crypto assets wallets can hold, with the decimal places of their smallest unit. Crypto amounts are sent as strings.
+//	@Tags			wallet
+//	@Produce		json
+//	@Success		200	{array}	Asset
+//	@Router			/api/v1/assets [get]
+func (h *Handler) AssetsHandler(c echo.Context) error {
+	list := make([]Asset, 0, len(assets))
+	for _, a := range assets {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Symbol < list[j].Symbol })
+	return c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,117 @@
+//go:build unit
+
+package wallet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestCryptoWallets(t *testing.T) {
+	cryptoWallets := append([]Wallet{{ID: 8, UserID: 2, WalletName: "Jane's Ether", WalletType: WalletTypeCrypto, Currency: "ETH", Balance: MustParseMoney("1", "ETH")}}, transferWallets...)
+
+	t.Run("given one wei deposit should keep it and answer with exact string", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": "0.000000000000000001"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/deposits")
+		c.SetParamNames("id")
+		c.SetParamValues("8")
+
+		p := New(StubWallet{wallet: cryptoWallets, transaction: Transaction{ID: 3, WalletID: 8, Type: TransactionDeposit, Amount: NewMoney(1, "ETH"), Currency: "ETH"}})
+
+		p.DepositHandler(c)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d", http.StatusCreated, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), `"amount":"0.000000000000000001"`) {
+			t.Errorf("expected amount as exact string but got %s", rec.Body.String())
+		}
+	})
+
+	t.Run("given fiat wallet should reject crypto precision", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": "0.001"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/deposits")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		p := New(StubWallet{wallet: cryptoWallets})
+
+		p.DepositHandler(c)
+
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d but got %d", http.StatusUnprocessableEntity, rec.Code)
+		}
+	})
+
+	t.Run("given Crypto Wallet in fiat currency should return 400", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"user_id": 1, "wallet_name": "Coins", "wallet_type": "Crypto Wallet", "currency": "THB"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets")
+
+		p := New(StubWallet{})
+
+		p.CreateWalletHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("given Savings wallet turned into Crypto Wallet should return 409", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"user_id": 1, "wallet_name": "Coins", "wallet_type": "Crypto Wallet"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		req.Header.Set("If-Match", "*")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		p := New(StubWallet{wallet: []Wallet{{ID: 1, UserID: 1, WalletName: "Savings", WalletType: WalletTypeSavings, Currency: "THB"}}})
+
+		p.UpdateWalletHandler(c)
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rec.Code)
+		}
+	})
+
+	t.Run("given BTC opening balance should keep satoshi", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"user_id": 1, "wallet_name": "Coins", "wallet_type": "Crypto Wallet", "currency": "BTC", "balance": "0.00000001"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets")
+
+		p := New(StubWallet{})
+
+		p.CreateWalletHandler(c)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d", http.StatusCreated, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), `"balance":"0.00000001"`) {
+			t.Errorf("expected balance as exact string but got %s", rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package wallet
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WalletBalance is a wallet's balance at a moment, which may be in the past.
+type WalletBalance struct {
+	WalletID  int       `json:"wallet_id" example:"1"`
+	Currency  string    `json:"currency" example:"THB"`
+	Balance   Money     `json:"balance" swaggertype:"number" example:"100.00"`
+	Available Money     `json:"available_balance" swaggertype:"number" example:"80.00"`
+	AsOf      time.Time `json:"as_of" example:"2024-04-01T00:00:00Z"`
+}
+
+// parseAsOf reads the as_of query parameter, an RFC 3339 timestamp that may
+// not be in the future. ok is false when the parameter is absent.
+func parseAsOf(c echo.Context, now time.Time) (asOf time.Time, ok bool, err error) {
+	s := c.QueryParam("as_of")
+	if s == "" {
+		return now, false, nil
+	}
+	asOf, err = time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, false, errors.New("as_of must be an RFC 3339 timestamp such as 2024-04-01T00:00:00Z")
+	}
+	if asOf.After(now) {
+		return time.Time{}, false, errors.New("as_of must not be in the future")
+	}
+	return asOf.UTC(), true, nil
+}
+
+// WalletBalanceHandler
+//
+//	@Summary		Get wallet balance
+//	@Description	Get a wallet's balance now or, with as_of, as it was at a past moment. A past balance counts what was booked before as_of; use the first instant of a month for the balance the month before closed on.
+//	@Tags			wallet
+//	@Produce		json
+//	@Param			id		path		int		true	"Wallet ID"
+//	@Param			as_of	query		string	false	"RFC 3339 timestamp"
+//	@Success		200		{object}	WalletBalance
+//	@Failure		400		{object}	Err
+//	@Failure		404		{object}	Err
+//	@Failure		500		{object}	Err
+//	@Router			/api/v1/wallets/:id/balance [get]
+func (h *Handler) WalletBalanceHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	asOf, past, err := parseAsOf(c, time.Now().UTC())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	var w Wallet
+	if past {
+		w, err = h.store.WalletAt(id, asOf)
+	} else {
+		w, err = h.store.WalletByID(id)
+	}
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, WalletBalance{
+		WalletID:  w.ID,
+		Currency:  w.Currency,
+		Balance:   w.Balance,
+		Available: w.Available,
+		AsOf:      asOf,
+	})
+}
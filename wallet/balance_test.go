@@ -0,0 +1,109 @@
+//go:build unit
+
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestWalletBalance(t *testing.T) {
+	created := time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC)
+	wallets := []Wallet{
+		{ID: 1, UserID: 1, Currency: "THB", Balance: NewMoney(100000, "THB"), Available: NewMoney(80000, "THB"), CreatedAt: created},
+		{ID: 4, UserID: 1, Currency: "THB", Balance: NewMoney(5000, "THB"), Available: NewMoney(5000, "THB"), CreatedAt: created.AddDate(0, 3, 0)},
+	}
+	store := StubWallet{wallet: wallets, past: map[int]Money{1: NewMoney(25000, "THB")}}
+
+	balance := func(id, asOf string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/?as_of="+url.QueryEscape(asOf), nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/balance")
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+
+		New(store).WalletBalanceHandler(c)
+		return rec
+	}
+
+	t.Run("given no as_of should return current balance", func(t *testing.T) {
+		rec := balance("1", "")
+
+		var got WalletBalance
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if got.Balance != NewMoney(100000, "THB") || got.Available != NewMoney(80000, "THB") {
+			t.Errorf("expected current balances but got %v and %v", got.Balance, got.Available)
+		}
+	})
+
+	t.Run("given month end should return balance at that moment", func(t *testing.T) {
+		rec := balance("1", "2024-03-01T00:00:00Z")
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rec.Code)
+		}
+		var got WalletBalance
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if got.Balance != NewMoney(25000, "THB") {
+			t.Errorf("expected balance %v but got %v", NewMoney(25000, "THB"), got.Balance)
+		}
+		if want := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC); !got.AsOf.Equal(want) {
+			t.Errorf("expected as of %v but got %v", want, got.AsOf)
+		}
+	})
+
+	tests := []struct {
+		name string
+		asOf string
+		want int
+	}{
+		{"as_of with offset", "2024-02-01T07:00:00+07:00", http.StatusOK},
+		{"as_of without time zone", "2024-03-01", http.StatusBadRequest},
+		{"as_of in the future", time.Now().AddDate(1, 0, 0).Format(time.RFC3339), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			if rec := balance("1", tt.asOf); rec.Code != tt.want {
+				t.Errorf("expected status code %d but got %d", tt.want, rec.Code)
+			}
+		})
+	}
+
+	t.Run("given wallet created after as_of should return 404", func(t *testing.T) {
+		if rec := balance("4", "2024-03-01T00:00:00Z"); rec.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+
+	t.Run("given as_of should list only wallets existing then", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/?as_of=2024-03-01T00:00:00Z", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/users/:id/wallets")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		New(store).GetWalletByIDHandler(c)
+
+		var got []Wallet
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if len(got) != 1 || got[0].ID != 1 || got[0].Balance != NewMoney(25000, "THB") {
+			t.Errorf("expected wallet 1 at 250.00 but got %+v", got)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package wallet
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const defaultBillingInterval = time.Hour
+
+// Billing runs the credit card cycle: it cuts each month's statements once
+// the month is over and charges interest on statements left unpaid at their
+// due date. A wallet that cannot be billed is logged and retried on the next
+// run without holding up the others. Running it on several servers at once
+// is safe.
+type Billing struct {
+	handler  *Handler
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewBilling(h *Handler) *Billing {
+	return &Billing{
+		handler:  h,
+		interval: defaultBillingInterval,
+		now:      time.Now,
+	}
+}
+
+// Run bills every interval until ctx is done.
+func (b *Billing) Run(ctx context.Context) {
+	runEvery(ctx, b.interval, "billing", b.RunDue)
+}
+
+// RunDue cuts the statements for last month that are still missing and
+// closes the statements that have fallen due.
+func (b *Billing) RunDue() error {
+	now := b.now().UTC()
+	if err := b.cutStatements(now); err != nil {
+		return err
+	}
+	return b.closeStatements(now)
+}
+
+func (b *Billing) cutStatements(now time.Time) error {
+	start, end := statementPeriod(now)
+	wallets, err := b.handler.store.UnbilledWallets(WalletTypeCreditCard, start)
+	if err != nil {
+		return err
+	}
+	for _, w := range wallets {
+		if err := b.cutStatement(w, start, end); err != nil {
+			log.Printf("billing wallet %d: %v", w.ID, err)
+		}
+	}
+	return nil
+}
+
+func (b *Billing) cutStatement(w Wallet, start, end time.Time) error {
+	activity, err := b.handler.store.StatementActivity(w.ID, start, end)
+	if err != nil {
+		return err
+	}
+	st, err := newStatement(w, start, end, activity)
+	if err != nil {
+		return err
+	}
+	_, err = b.handler.store.CreateStatement(st)
+	return err
+}
+
+// closeStatements counts everything paid into the wallet between the
+// statement date and the due date towards the statement.
+func (b *Billing) closeStatements(now time.Time) error {
+	due, err := b.handler.store.DueStatements(now)
+	if err != nil {
+		return err
+	}
+	for _, st := range due {
+		if err := b.closeStatement(st); err != nil {
+			log.Printf("closing statement %d: %v", st.ID, err)
+		}
+	}
+	return nil
+}
+
+func (b *Billing) closeStatement(st Statement) error {
+	paid, err := b.handler.store.StatementActivity(st.WalletID, st.PeriodEnd, st.DueDate)
+	if err != nil {
+		return err
+	}
+	prev, prevPaid, err := b.previousStatement(st)
+	if err != nil {
+		return err
+	}
+	interest, err := st.interest(paid.Credits, prev, prevPaid)
+	if err != nil {
+		return err
+	}
+	_, err = b.handler.store.CloseStatement(st.ID, interest)
+	return err
+}
+
+// previousStatement returns the closed statement for the month before st,
+// if there is one, and what was paid towards it by its due date.
+func (b *Billing) previousStatement(st Statement) (*Statement, Money, error) {
+	statements, err := b.handler.store.Statements(st.WalletID)
+	if err != nil {
+		return nil, Money{}, err
+	}
+	for _, prev := range statements {
+		if !prev.PeriodEnd.Equal(st.PeriodStart) || prev.ClosedAt == nil {
+			continue
+		}
+		paid, err := b.handler.store.StatementActivity(prev.WalletID, prev.PeriodEnd, prev.DueDate)
+		if err != nil {
+			return nil, Money{}, err
+		}
+		return &prev, paid.Credits, nil
+	}
+	return nil, Money{}, nil
+}
@@ -0,0 +1,435 @@
+package wallet
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	maxTags      = 20
+	maxTagLength = 50
+)
+
+var (
+	ErrCategoryNotFound     = errors.New("category not found")
+	ErrCategoryRuleNotFound = errors.New("category rule not found")
+	ErrCategoryExists       = errors.New("a category with that name already exists")
+	// ErrUnknownCategory is returned when a rule or transaction names a
+	// category that is neither a default nor one of the user's own.
+	ErrUnknownCategory = errors.New("category_id does not name a category of the user")
+)
+
+// Category labels what a transaction was for. Categories without a user
+// are the default set every user shares.
+type Category struct {
+	ID        int       `json:"id" example:"3"`
+	UserID    *int      `json:"user_id,omitempty" example:"1"`
+	Name      string    `json:"name" example:"Food"`
+	CreatedAt time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
+
+func (c Category) Validate() error {
+	if strings.TrimSpace(c.Name) == "" || len(c.Name) > 64 {
+		return errors.New("name is required and must be at most 64 characters")
+	}
+	return nil
+}
+
+// CategoryRule files the transactions it matches under a category until
+// someone recategorizes them. A rule matches transactions of Type, with
+// CounterpartyWalletID on the other side or whose description or reference
+// contain DescriptionContains, ignoring case; conditions left empty match
+// anything. Rules without a user are defaults.
+type CategoryRule struct {
+	ID                   int       `json:"id" example:"1"`
+	UserID               *int      `json:"user_id,omitempty" example:"1"`
+	CategoryID           int       `json:"category_id" example:"2"`
+	Category             string    `json:"category" example:"Rent"`
+	Type                 string    `json:"type,omitempty" example:"transfer"`
+	CounterpartyWalletID *int      `json:"counterparty_wallet_id,omitempty" example:"4"`
+	DescriptionContains  string    `json:"description_contains,omitempty" example:"rent"`
+	CreatedAt            time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
+
+func (r CategoryRule) Validate() error {
+	if r.CategoryID <= 0 {
+		return errors.New("category_id is required")
+	}
+	if r.Type != "" && !slices.Contains(transactionTypes, r.Type) {
+		return errors.New("unknown transaction type " + r.Type)
+	}
+	if len(r.DescriptionContains) > 255 {
+		return errors.New("description_contains must be at most 255 characters")
+	}
+	if r.conditions() == 0 {
+		return errors.New("rule needs a type, counterparty_wallet_id or description_contains")
+	}
+	return nil
+}
+
+// conditions counts the conditions r sets.
+func (r CategoryRule) conditions() int {
+	n := 0
+	if r.Type != "" {
+		n++
+	}
+	if r.CounterpartyWalletID != nil {
+		n++
+	}
+	if r.DescriptionContains != "" {
+		n++
+	}
+	return n
+}
+
+// Matches reports whether r files t.
+func (r CategoryRule) Matches(t Transaction) bool {
+	if r.Type != "" && r.Type != t.Type {
+		return false
+	}
+	if r.CounterpartyWalletID != nil && (t.CounterpartyWalletID == nil || *t.CounterpartyWalletID != *r.CounterpartyWalletID) {
+		return false
+	}
+	if s := strings.ToLower(r.DescriptionContains); s != "" &&
+		!strings.Contains(strings.ToLower(t.Description), s) && !strings.Contains(strings.ToLower(t.Reference), s) {
+		return false
+	}
+	return true
+}
+
+// outranks reports whether r files a transaction rather than o when both
+// match: a user's rule over a default, then the rule with more conditions,
+// then the newer.
+func (r CategoryRule) outranks(o CategoryRule) bool {
+	if (r.UserID != nil) != (o.UserID != nil) {
+		return r.UserID != nil
+	}
+	if r.conditions() != o.conditions() {
+		return r.conditions() > o.conditions()
+	}
+	return r.ID > o.ID
+}
+
+// Categorize files the transactions nobody has categorized by the rule
+// that applies to each, if any.
+func Categorize(rules []CategoryRule, transactions []Transaction) {
+	for i, t := range transactions {
+		if t.CategoryID != nil {
+			continue
+		}
+		var best *CategoryRule
+		for j, r := range rules {
+			if r.Matches(t) && (best == nil || r.outranks(*best)) {
+				best = &rules[j]
+			}
+		}
+		if best != nil {
+			id, ruleID := best.CategoryID, best.ID
+			transactions[i].CategoryID, transactions[i].Category, transactions[i].CategoryRuleID = &id, best.Category, &ruleID
+		}
+	}
+}
+
+// Labels are what a wallet's user files a transaction under. A nil
+// CategoryID leaves the transaction to the rules again.
+type Labels struct {
+	CategoryID *int     `json:"category_id" example:"3"`
+	Tags       []string `json:"tags" example:"trip,japan"`
+}
+
+// Normalize trims the tags, drops empty and repeated ones and checks what
+// is left.
+func (l Labels) Normalize() (Labels, error) {
+	tags := []string{}
+	for _, tag := range l.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return Labels{}, errors.New("tags must be at most " + strconv.Itoa(maxTagLength) + " characters")
+		}
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxTags {
+		return Labels{}, errors.New("at most " + strconv.Itoa(maxTags) + " tags are allowed")
+	}
+	l.Tags = tags
+	return l, nil
+}
+
+// userID reads the :id path parameter of the users resource.
+func userID(c echo.Context) (int, error) {
+	return strconv.Atoi(c.Param("id"))
+}
+
+// CategoriesHandler
+//
+//	@Summary		Get categories
+//	@Description	Get the default categories and the user's own
+//	@Tags			categories
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{array}		Category
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/users/:id/categories [get]
+func (h *Handler) CategoriesHandler(c echo.Context) error {
+	id, err := userID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if _, err := h.store.UserByID(id); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	categories, err := h.store.Categories(id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, categories)
+}
+
+// CreateCategoryHandler
+//
+//	@Summary		Create category
+//	@Description	Add a category of the user's own next to the defaults
+//	@Tags			categories
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int			true	"User ID"
+//	@Param			category	body		Category	true	"Category"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; must be the user"
+//	@Success		201			{object}	Category
+//	@Failure		400			{object}	Err
+//	@Failure		401			{object}	Err
+//	@Failure		403			{object}	Err
+//	@Failure		404			{object}	Err
+//	@Failure		409			{object}	Err
+//	@Failure		500			{object}	Err
+//	@Router			/api/v1/users/:id/categories [post]
+func (h *Handler) CreateCategoryHandler(c echo.Context) error {
+	id, err := userID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	var cat Category
+	if err := c.Bind(&cat); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := cat.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := authorizeSelf(c, id); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	cat.UserID, cat.Name = &id, strings.TrimSpace(cat.Name)
+	if cat, err = h.store.CreateCategory(cat); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusCreated, cat)
+}
+
+// DeleteCategoryHandler
+//
+//	@Summary		Delete category
+//	@Description	Delete one of the user's own categories, with its rules. Transactions filed under it go back to the rules. Defaults cannot be deleted.
+//	@Tags			categories
+//	@Produce		json
+//	@Param			id			path	int	true	"User ID"
+//	@Param			category_id	path	int	true	"Category ID"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; must be the user"
+//	@Success		204
+//	@Failure		400	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/users/:id/categories/:category_id [delete]
+func (h *Handler) DeleteCategoryHandler(c echo.Context) error {
+	id, err := userID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	categoryID, err := strconv.Atoi(c.Param("category_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := authorizeSelf(c, id); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	if err := h.store.DeleteCategory(id, categoryID); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// CategoryRulesHandler
+//
+//	@Summary		Get category rules
+//	@Description	Get the default categorization rules and the user's own
+//	@Tags			categories
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{array}		CategoryRule
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/users/:id/category-rules [get]
+func (h *Handler) CategoryRulesHandler(c echo.Context) error {
+	id, err := userID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if _, err := h.store.UserByID(id); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	rules, err := h.store.CategoryRules(id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, rules)
+}
+
+// CreateCategoryRuleHandler
+//
+//	@Summary		Create category rule
+//	@Description	Add a rule filing the user's transactions under a default or own category. The user's rules win over the defaults, then rules with more conditions, then newer ones.
+//	@Tags			categories
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"User ID"
+//	@Param			rule	body		CategoryRule	true	"Rule"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; must be the user"
+//	@Success		201		{object}	CategoryRule
+//	@Failure		400		{object}	Err
+//	@Failure		401		{object}	Err
+//	@Failure		403		{object}	Err
+//	@Failure		422		{object}	Err
+//	@Failure		500		{object}	Err
+//	@Router			/api/v1/users/:id/category-rules [post]
+func (h *Handler) CreateCategoryRuleHandler(c echo.Context) error {
+	id, err := userID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	var r CategoryRule
+	if err := c.Bind(&r); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := r.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := authorizeSelf(c, id); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	r.UserID = &id
+	if r, err = h.store.CreateCategoryRule(r); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusCreated, r)
+}
+
+// DeleteCategoryRuleHandler
+//
+//	@Summary		Delete category rule
+//	@Description	Delete one of the user's own categorization rules. Defaults cannot be deleted.
+//	@Tags			categories
+//	@Produce		json
+//	@Param			id		path	int	true	"User ID"
+//	@Param			rule_id	path	int	true	"Rule ID"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; must be the user"
+//	@Success		204
+//	@Failure		400	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/users/:id/category-rules/:rule_id [delete]
+func (h *Handler) DeleteCategoryRuleHandler(c echo.Context) error {
+	id, err := userID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	ruleID, err := strconv.Atoi(c.Param("rule_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := authorizeSelf(c, id); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	if err := h.store.DeleteCategoryRule(id, ruleID); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// LabelTransactionHandler
+//
+//	@Summary		Recategorize transaction
+//	@Description	File a wallet's transaction under a default category or one of the wallet user's own, and replace its tags. A null category_id hands the transaction back to the rules.
+//	@Tags			categories
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int		true	"Wallet ID"
+//	@Param			transaction_id	path		int		true	"Transaction ID"
+//	@Param			labels			body		Labels	true	"Category and tags"
+//	@Param			X-User-ID		header		int		true	"User the request acts for; needs spender or owner"
+//	@Success		200				{object}	Transaction
+//	@Failure		400				{object}	Err
+//	@Failure		401				{object}	Err
+//	@Failure		403				{object}	Err
+//	@Failure		404				{object}	Err
+//	@Failure		422				{object}	Err
+//	@Failure		500				{object}	Err
+//	@Router			/api/v1/wallets/:id/transactions/:transaction_id/category [put]
+func (h *Handler) LabelTransactionHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	transactionID, err := strconv.Atoi(c.Param("transaction_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	var l Labels
+	if err := c.Bind(&l); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if l, err = l.Normalize(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := h.authorize(c, id, RoleSpender); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	w, err := h.store.WalletByID(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	t, err := h.store.LabelTransaction(id, transactionID, l)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	transactions := []Transaction{t}
+	if err := h.categorize(w, transactions); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, transactions[0])
+}
+
+// categorize files the transactions of w nobody has categorized by the
+// rules of w's user.
+func (h *Handler) categorize(w Wallet, transactions []Transaction) error {
+	rules, err := h.store.CategoryRules(w.UserID)
+	if err != nil {
+		return err
+	}
+	Categorize(rules, transactions)
+	return nil
+}
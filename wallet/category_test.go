@@ -0,0 +1,247 @@
+//go:build unit
+
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestCategorize(t *testing.T) {
+	user, landlord, other := 1, 7, 8
+	rules := []CategoryRule{
+		{ID: 1, CategoryID: 9, Category: "Transfers", Type: TransactionTransfer},
+		{ID: 2, CategoryID: 2, Category: "Rent", DescriptionContains: "rent"},
+		{ID: 3, UserID: &user, CategoryID: 12, Category: "Family", Type: TransactionTransfer, CounterpartyWalletID: &other},
+		{ID: 4, UserID: &user, CategoryID: 2, Category: "Rent", CounterpartyWalletID: &landlord},
+		{ID: 5, UserID: &user, CategoryID: 13, Category: "Home", Type: TransactionTransfer, CounterpartyWalletID: &landlord, DescriptionContains: "RENT"},
+	}
+	kept := 5
+
+	tests := []struct {
+		name     string
+		t        Transaction
+		category string
+		rule     int
+	}{
+		{"transfer to anyone", Transaction{Type: TransactionTransfer, CounterpartyWalletID: &user}, "Transfers", 1},
+		{"description matching a default rule", Transaction{Type: TransactionWithdrawal, Description: "March Rent"}, "Rent", 2},
+		{"reference matching a default rule", Transaction{Type: TransactionWithdrawal, Reference: "rent-2024-03"}, "Rent", 2},
+		{"counterparty matching a user rule", Transaction{Type: TransactionTransfer, CounterpartyWalletID: &other}, "Family", 3},
+		{"user rule against a default", Transaction{Type: TransactionDeposit, Description: "rent", CounterpartyWalletID: &landlord}, "Rent", 4},
+		{"user rules of different conditions", Transaction{Type: TransactionTransfer, Description: "rent for March", CounterpartyWalletID: &landlord}, "Home", 5},
+		{"no matching rule", Transaction{Type: TransactionDeposit, Description: "Salary"}, "", 0},
+		{"category set by the user", Transaction{Type: TransactionTransfer, CategoryID: &kept, Category: "Shopping"}, "Shopping", 0},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should file it under "+tt.category, func(t *testing.T) {
+			transactions := []Transaction{tt.t}
+
+			Categorize(rules, transactions)
+
+			got := transactions[0]
+			if got.Category != tt.category {
+				t.Errorf("expected category %q but got %q", tt.category, got.Category)
+			}
+			if tt.rule == 0 && got.CategoryRuleID != nil || tt.rule != 0 && (got.CategoryRuleID == nil || *got.CategoryRuleID != tt.rule) {
+				t.Errorf("expected rule %d but got %v", tt.rule, got.CategoryRuleID)
+			}
+		})
+	}
+}
+
+func TestLabelsNormalize(t *testing.T) {
+	t.Run("given padded and repeated tags should keep each once", func(t *testing.T) {
+		l, err := Labels{Tags: []string{" trip ", "japan", "", "trip"}}.Normalize()
+
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if !slices.Equal(l.Tags, []string{"trip", "japan"}) {
+			t.Errorf("expected tags [trip japan] but got %v", l.Tags)
+		}
+	})
+
+	t.Run("given no tags should clear them", func(t *testing.T) {
+		l, err := Labels{}.Normalize()
+
+		if err != nil || l.Tags == nil || len(l.Tags) != 0 {
+			t.Errorf("expected empty tags but got %v, %v", l.Tags, err)
+		}
+	})
+
+	t.Run("given too long a tag should fail", func(t *testing.T) {
+		if _, err := (Labels{Tags: []string{strings.Repeat("a", maxTagLength+1)}}).Normalize(); err == nil {
+			t.Error("expected error but got none")
+		}
+	})
+
+	t.Run("given too many tags should fail", func(t *testing.T) {
+		var tags []string
+		for i := 0; i <= maxTags; i++ {
+			tags = append(tags, strings.Repeat("a", i+1))
+		}
+		if _, err := (Labels{Tags: tags}).Normalize(); err == nil {
+			t.Error("expected error but got none")
+		}
+	})
+}
+
+func TestCategoryHandlers(t *testing.T) {
+	user := 1
+	store := StubWallet{
+		users:  []User{{ID: 1, Name: "John Doe"}},
+		wallet: []Wallet{{ID: 1, UserID: 1, WalletName: "John's Savings", WalletType: WalletTypeSavings, Currency: "THB"}},
+		categories: []Category{
+			{ID: 1, Name: "Food"},
+			{ID: 2, Name: "Rent"},
+			{ID: 12, UserID: &user, Name: "Family"},
+		},
+		catRules: []CategoryRule{
+			{ID: 1, CategoryID: 2, Category: "Rent", DescriptionContains: "rent"},
+			{ID: 2, UserID: &user, CategoryID: 12, Category: "Family", Type: TransactionTransfer},
+		},
+		members: []Member{
+			{WalletID: 1, UserID: 1, Role: RoleOwner},
+			{WalletID: 1, UserID: 3, Role: RoleViewer},
+		},
+		transactions: []Transaction{
+			{ID: 7, WalletID: 1, Type: TransactionWithdrawal, Description: "Rent for March"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		params  []string
+		caller  string
+		body    string
+		handler func(*Handler) echo.HandlerFunc
+		want    int
+	}{
+		{"list of categories", http.MethodGet, "/api/v1/users/:id/categories", []string{"1"}, "", "",
+			func(h *Handler) echo.HandlerFunc { return h.CategoriesHandler }, http.StatusOK},
+		{"list of categories of unknown user", http.MethodGet, "/api/v1/users/:id/categories", []string{"9"}, "", "",
+			func(h *Handler) echo.HandlerFunc { return h.CategoriesHandler }, http.StatusNotFound},
+		{"new category", http.MethodPost, "/api/v1/users/:id/categories", []string{"1"}, "1", `{"name": "Travel"}`,
+			func(h *Handler) echo.HandlerFunc { return h.CreateCategoryHandler }, http.StatusCreated},
+		{"category without name", http.MethodPost, "/api/v1/users/:id/categories", []string{"1"}, "1", `{"name": " "}`,
+			func(h *Handler) echo.HandlerFunc { return h.CreateCategoryHandler }, http.StatusBadRequest},
+		{"category repeating a default", http.MethodPost, "/api/v1/users/:id/categories", []string{"1"}, "1", `{"name": "food"}`,
+			func(h *Handler) echo.HandlerFunc { return h.CreateCategoryHandler }, http.StatusConflict},
+		{"deletion of own category", http.MethodDelete, "/api/v1/users/:id/categories/:category_id", []string{"1", "12"}, "1", "",
+			func(h *Handler) echo.HandlerFunc { return h.DeleteCategoryHandler }, http.StatusNoContent},
+		{"deletion of default category", http.MethodDelete, "/api/v1/users/:id/categories/:category_id", []string{"1", "1"}, "1", "",
+			func(h *Handler) echo.HandlerFunc { return h.DeleteCategoryHandler }, http.StatusNotFound},
+		{"list of rules", http.MethodGet, "/api/v1/users/:id/category-rules", []string{"1"}, "", "",
+			func(h *Handler) echo.HandlerFunc { return h.CategoryRulesHandler }, http.StatusOK},
+		{"new rule", http.MethodPost, "/api/v1/users/:id/category-rules", []string{"1"}, "1", `{"category_id": 1, "description_contains": "lunch"}`,
+			func(h *Handler) echo.HandlerFunc { return h.CreateCategoryRuleHandler }, http.StatusCreated},
+		{"rule without conditions", http.MethodPost, "/api/v1/users/:id/category-rules", []string{"1"}, "1", `{"category_id": 1}`,
+			func(h *Handler) echo.HandlerFunc { return h.CreateCategoryRuleHandler }, http.StatusBadRequest},
+		{"rule of unknown type", http.MethodPost, "/api/v1/users/:id/category-rules", []string{"1"}, "1", `{"category_id": 1, "type": "gift"}`,
+			func(h *Handler) echo.HandlerFunc { return h.CreateCategoryRuleHandler }, http.StatusBadRequest},
+		{"rule for unknown category", http.MethodPost, "/api/v1/users/:id/category-rules", []string{"1"}, "1", `{"category_id": 99, "type": "fee"}`,
+			func(h *Handler) echo.HandlerFunc { return h.CreateCategoryRuleHandler }, http.StatusUnprocessableEntity},
+		{"deletion of own rule", http.MethodDelete, "/api/v1/users/:id/category-rules/:rule_id", []string{"1", "2"}, "1", "",
+			func(h *Handler) echo.HandlerFunc { return h.DeleteCategoryRuleHandler }, http.StatusNoContent},
+		{"new category for another user", http.MethodPost, "/api/v1/users/:id/categories", []string{"1"}, "2", `{"name": "Travel"}`,
+			func(h *Handler) echo.HandlerFunc { return h.CreateCategoryHandler }, http.StatusForbidden},
+		{"deletion of another user's category", http.MethodDelete, "/api/v1/users/:id/categories/:category_id", []string{"1", "12"}, "2", "",
+			func(h *Handler) echo.HandlerFunc { return h.DeleteCategoryHandler }, http.StatusForbidden},
+		{"new rule for another user", http.MethodPost, "/api/v1/users/:id/category-rules", []string{"1"}, "2", `{"category_id": 1, "description_contains": "lunch"}`,
+			func(h *Handler) echo.HandlerFunc { return h.CreateCategoryRuleHandler }, http.StatusForbidden},
+		{"new rule without caller", http.MethodPost, "/api/v1/users/:id/category-rules", []string{"1"}, "", `{"category_id": 1, "description_contains": "lunch"}`,
+			func(h *Handler) echo.HandlerFunc { return h.CreateCategoryRuleHandler }, http.StatusUnauthorized},
+		{"deletion of another user's rule", http.MethodDelete, "/api/v1/users/:id/category-rules/:rule_id", []string{"1", "2"}, "2", "",
+			func(h *Handler) echo.HandlerFunc { return h.DeleteCategoryRuleHandler }, http.StatusForbidden},
+		{"deletion of default rule", http.MethodDelete, "/api/v1/users/:id/category-rules/:rule_id", []string{"1", "1"}, "1", "",
+			func(h *Handler) echo.HandlerFunc { return h.DeleteCategoryRuleHandler }, http.StatusNotFound},
+		{"recategorization by owner", http.MethodPut, "/api/v1/wallets/:id/transactions/:transaction_id/category", []string{"1", "7"}, "1", `{"category_id": 1, "tags": ["trip"]}`,
+			func(h *Handler) echo.HandlerFunc { return h.LabelTransactionHandler }, http.StatusOK},
+		{"recategorization by viewer", http.MethodPut, "/api/v1/wallets/:id/transactions/:transaction_id/category", []string{"1", "7"}, "3", `{"category_id": 1}`,
+			func(h *Handler) echo.HandlerFunc { return h.LabelTransactionHandler }, http.StatusForbidden},
+		{"recategorization of unknown transaction", http.MethodPut, "/api/v1/wallets/:id/transactions/:transaction_id/category", []string{"1", "8"}, "1", `{"category_id": 1}`,
+			func(h *Handler) echo.HandlerFunc { return h.LabelTransactionHandler }, http.StatusNotFound},
+		{"too long a tag", http.MethodPut, "/api/v1/wallets/:id/transactions/:transaction_id/category", []string{"1", "7"}, "1", `{"tags": ["` + strings.Repeat("a", maxTagLength+1) + `"]}`,
+			func(h *Handler) echo.HandlerFunc { return h.LabelTransactionHandler }, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.caller != "" {
+				req.Header.Set(HeaderUserID, tt.caller)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath(tt.path)
+			var names []string
+			for _, segment := range strings.Split(tt.path, "/") {
+				if name, ok := strings.CutPrefix(segment, ":"); ok {
+					names = append(names, name)
+				}
+			}
+			c.SetParamNames(names...)
+			c.SetParamValues(tt.params...)
+
+			tt.handler(New(store))(c)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status code %d but got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	t.Run("given transactions nobody categorized should file them by the rules", func(t *testing.T) {
+		store := store
+		store.transactions = slices.Clone(store.transactions)
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/transactions")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		New(store).TransactionsHandler(c)
+
+		var got TransactionPage
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if len(got.Transactions) != 1 || got.Transactions[0].Category != "Rent" || got.Transactions[0].CategoryRuleID == nil {
+			t.Errorf("expected the transaction filed under Rent by a rule but got %+v", got.Transactions)
+		}
+	})
+
+	t.Run("given recategorized transaction should keep the user's category", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"category_id": 1, "tags": ["lunch", "lunch"]}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/transactions/:transaction_id/category")
+		c.SetParamNames("id", "transaction_id")
+		c.SetParamValues("1", "7")
+
+		New(store).LabelTransactionHandler(c)
+
+		var got Transaction
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if got.CategoryID == nil || *got.CategoryID != 1 || got.CategoryRuleID != nil || !slices.Equal(got.Tags, []string{"lunch"}) {
+			t.Errorf("expected category 1 tagged lunch but got %+v", got)
+		}
+	})
+}
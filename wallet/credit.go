@@ -0,0 +1,238 @@
+package wallet
+
+import (
+	"errors"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Wallet types, as declared by the wallet_type enum.
+const (
+	WalletTypeSavings    = "Savings"
+	WalletTypeCreditCard = "Credit Card"
+	WalletTypeCrypto     = "Crypto Wallet"
+)
+
+// ErrWalletTypeChange is returned when an update names another type than
+// the wallet was created with. The type decides what a wallet may hold and
+// how it is billed, so it is fixed for good.
+var ErrWalletTypeChange = errors.New("wallet_type cannot be changed")
+
+// ErrBelowCreditLimit is returned for a credit limit that would leave the
+// wallet's balance below it.
+var ErrBelowCreditLimit = errors.New("balance must not be below the credit limit")
+
+// Credit card terms. A statement is cut for every calendar month and is due
+// statementGracePeriod later. Whatever is left of it unpaid by then is
+// charged creditInterestRate a year from the statement date, and goes on
+// being charged, through the statements that carry it, until it is paid.
+const statementGracePeriod = 25 * 24 * time.Hour
+
+var (
+	creditInterestRate    = big.NewRat(16, 100)
+	minimumPaymentPercent = big.NewRat(5, 100)
+)
+
+// Spendable is how far a debit may take the wallet: its available balance
+// plus, for credit cards, the credit limit.
+func (w Wallet) Spendable() Money {
+	spendable, err := w.Available.Add(w.CreditLimit)
+	if err != nil {
+		return w.Available
+	}
+	return spendable
+}
+
+// validateCredit applies the wallet type's rules to the credit limit and to
+// an opening balance. Only credit cards may have a limit, and their balance
+// may go as low as minus that limit.
+func (w Wallet) validateCredit() error {
+	if err := w.validateCreditLimit(); err != nil {
+		return err
+	}
+	return w.CheckCreditLimit()
+}
+
+// validateCreditLimit applies the wallet type's rules to the credit limit
+// alone, for requests that do not carry the balance.
+func (w Wallet) validateCreditLimit() error {
+	if w.CreditLimit.IsNegative() {
+		return errors.New("credit_limit must not be negative")
+	}
+	if !w.CreditLimit.IsZero() && w.WalletType != WalletTypeCreditCard {
+		return errors.New("credit_limit is only allowed on Credit Card wallets")
+	}
+	return nil
+}
+
+// CheckCreditLimit fails with ErrBelowCreditLimit if the balance is below
+// minus the credit limit. Stores check it against the stored balance.
+func (w Wallet) CheckCreditLimit() error {
+	if w.Balance.Cmp(w.CreditLimit.Neg()) < 0 {
+		return ErrBelowCreditLimit
+	}
+	return nil
+}
+
+// Statement is a credit card wallet's bill for one month. Purchases and
+// Payments are the money that left and entered the wallet during the
+// period. Interest is charged when the statement closes at its due date,
+// on its own unpaid part and on what was still overdue from the statement
+// before.
+type Statement struct {
+	ID             int        `json:"id" example:"1"`
+	WalletID       int        `json:"wallet_id" example:"3"`
+	Currency       string     `json:"currency" example:"THB"`
+	PeriodStart    time.Time  `json:"period_start" example:"2024-03-01T00:00:00Z"`
+	PeriodEnd      time.Time  `json:"period_end" example:"2024-04-01T00:00:00Z"`
+	OpeningBalance Money      `json:"opening_balance" swaggertype:"number" example:"-1200.00"`
+	Purchases      Money      `json:"purchases" swaggertype:"number" example:"3500.00"`
+	Payments       Money      `json:"payments" swaggertype:"number" example:"1200.00"`
+	ClosingBalance Money      `json:"closing_balance" swaggertype:"number" example:"-3500.00"`
+	MinimumPayment Money      `json:"minimum_payment" swaggertype:"number" example:"175.00"`
+	DueDate        time.Time  `json:"due_date" example:"2024-04-26T00:00:00Z"`
+	Interest       Money      `json:"interest" swaggertype:"number" example:"0.00"`
+	ClosedAt       *time.Time `json:"closed_at,omitempty" example:"2024-04-26T00:01:00Z"`
+	CreatedAt      time.Time  `json:"created_at" example:"2024-04-01T00:01:00Z"`
+}
+
+// StatementActivity sums a wallet's ledger over a period. Opening is the
+// balance when the period starts; Debits and Credits are both positive.
+type StatementActivity struct {
+	Opening Money
+	Debits  Money
+	Credits Money
+}
+
+// statementPeriod returns the last calendar month, in UTC, that ended at or
+// before now.
+func statementPeriod(now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return end.AddDate(0, -1, 0), end
+}
+
+// newStatement bills w for the period from start to end.
+func newStatement(w Wallet, start, end time.Time, a StatementActivity) (Statement, error) {
+	closing, err := a.Opening.Sub(a.Debits)
+	if err != nil {
+		return Statement{}, err
+	}
+	if closing, err = closing.Add(a.Credits); err != nil {
+		return Statement{}, err
+	}
+	st := Statement{
+		WalletID:       w.ID,
+		Currency:       w.Currency,
+		PeriodStart:    start,
+		PeriodEnd:      end,
+		OpeningBalance: a.Opening,
+		Purchases:      a.Debits,
+		Payments:       a.Credits,
+		ClosingBalance: closing,
+		DueDate:        end.Add(statementGracePeriod),
+		Interest:       NewMoney(0, w.Currency),
+	}
+	st.MinimumPayment, err = st.Owed().MulRat(minimumPaymentPercent, RoundUp)
+	if err != nil {
+		return Statement{}, err
+	}
+	return st, nil
+}
+
+// Owed is the amount the statement asks to be paid back.
+func (s Statement) Owed() Money {
+	if s.ClosingBalance.IsNegative() {
+		return s.ClosingBalance.Neg()
+	}
+	return NewMoney(0, s.Currency)
+}
+
+// unpaid is the part of the statement that paid leaves owing.
+func (s Statement) unpaid(paid Money) (Money, error) {
+	unpaid, err := s.Owed().Sub(paid)
+	if err != nil {
+		return Money{}, err
+	}
+	if !unpaid.IsPositive() {
+		return NewMoney(0, s.Currency), nil
+	}
+	return unpaid, nil
+}
+
+// overdue is what a closed statement left owing after its due date: the
+// unpaid part and the interest charged on it.
+func (s Statement) overdue(paid Money) (Money, error) {
+	unpaid, err := s.unpaid(paid)
+	if err != nil || unpaid.IsZero() {
+		return unpaid, err
+	}
+	return unpaid.Add(s.Interest)
+}
+
+// interest is charged on the part of the statement still unpaid at its due
+// date, for every day from the statement date to the due date. The unpaid
+// part includes what was overdue on the previous statement, prev, which
+// has been charged only up to prev's due date; as much of it as is still
+// unpaid is charged for the days from then to this statement's date as
+// well, so that an unpaid balance bears interest every day until it is
+// paid. prev is nil for the first statement.
+func (s Statement) interest(paid Money, prev *Statement, prevPaid Money) (Money, error) {
+	unpaid, err := s.unpaid(paid)
+	if err != nil || unpaid.IsZero() {
+		return unpaid, err
+	}
+	interest, err := unpaid.MulRat(creditInterestFor(s.PeriodEnd, s.DueDate), RoundHalfEven)
+	if err != nil {
+		return Money{}, err
+	}
+	if prev == nil || !prev.DueDate.Before(s.PeriodEnd) {
+		return interest, nil
+	}
+	carried, err := prev.overdue(prevPaid)
+	if err != nil {
+		return Money{}, err
+	}
+	if carried.Cmp(unpaid) > 0 {
+		carried = unpaid
+	}
+	late, err := carried.MulRat(creditInterestFor(prev.DueDate, s.PeriodEnd), RoundHalfEven)
+	if err != nil {
+		return Money{}, err
+	}
+	return interest.Add(late)
+}
+
+// creditInterestFor is creditInterestRate for the whole days from from to to.
+func creditInterestFor(from, to time.Time) *big.Rat {
+	days := int64(to.Sub(from) / (24 * time.Hour))
+	return new(big.Rat).Mul(creditInterestRate, big.NewRat(days, 365))
+}
+
+// StatementsHandler
+//
+//	@Summary		Get wallet statements
+//	@Description	Get the monthly statements of a credit card wallet, newest first
+//	@Tags			statements
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Wallet ID"
+//	@Success		200	{array}		Statement
+//	@Failure		400	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/wallets/:id/statements [get]
+func (h *Handler) StatementsHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	statements, err := h.store.Statements(id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, statements)
+}
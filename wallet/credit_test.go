@@ -0,0 +1,182 @@
+//go:build unit
+
+package wallet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestCreditLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"credit limit on savings wallet", `{"user_id": 1, "wallet_name": "Savings", "wallet_type": "Savings", "credit_limit": 1000}`, http.StatusBadRequest},
+		{"opening balance below credit limit", `{"user_id": 1, "wallet_name": "Card", "wallet_type": "Credit Card", "balance": -1500, "credit_limit": 1000}`, http.StatusBadRequest},
+		{"negative credit limit", `{"user_id": 1, "wallet_name": "Card", "wallet_type": "Credit Card", "credit_limit": -1}`, http.StatusBadRequest},
+		{"credit card drawn within its limit", `{"user_id": 1, "wallet_name": "Card", "wallet_type": "Credit Card", "balance": -500, "credit_limit": 1000}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/wallets")
+
+			p := New(StubWallet{})
+
+			p.CreateWalletHandler(c)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status code %d but got %d", tt.want, rec.Code)
+			}
+		})
+	}
+
+	t.Run("given credit limit lowered below the card's debt should return 422", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"user_id": 1, "wallet_name": "Card", "wallet_type": "Credit Card", "credit_limit": 0}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		req.Header.Set("If-Match", "*")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("2")
+
+		p := New(StubWallet{wallet: []Wallet{{ID: 2, UserID: 1, WalletName: "Card", WalletType: WalletTypeCreditCard, Currency: "THB",
+			Balance: NewMoney(-500000, "THB"), CreditLimit: NewMoney(1000000, "THB")}}})
+
+		p.UpdateWalletHandler(c)
+
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d but got %d", http.StatusUnprocessableEntity, rec.Code)
+		}
+	})
+
+	t.Run("given credit card should spend into its limit", func(t *testing.T) {
+		w := Wallet{WalletType: WalletTypeCreditCard, Available: NewMoney(-20000, "THB"), CreditLimit: NewMoney(100000, "THB")}
+
+		if got, want := w.Spendable(), NewMoney(80000, "THB"); got != want {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+}
+
+func TestBilling(t *testing.T) {
+	card := Wallet{ID: 2, WalletType: WalletTypeCreditCard, Currency: "THB", CreditLimit: NewMoney(5000000, "THB")}
+
+	t.Run("given month has ended should cut statement with minimum payment and due date", func(t *testing.T) {
+		bills := &billingRuns{closed: map[int]Money{}}
+		store := StubWallet{
+			wallet:   append([]Wallet{card}, transferWallets...),
+			activity: StatementActivity{Opening: NewMoney(-120000, "THB"), Debits: NewMoney(350000, "THB"), Credits: NewMoney(120000, "THB")},
+			bills:    bills,
+		}
+		b := NewBilling(New(store))
+		b.now = func() time.Time { return time.Date(2024, time.April, 1, 0, 30, 0, 0, time.UTC) }
+
+		if err := b.RunDue(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		if len(bills.created) != 1 {
+			t.Fatalf("expected one statement but got %d", len(bills.created))
+		}
+		st := bills.created[0]
+		if want := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC); !st.PeriodStart.Equal(want) {
+			t.Errorf("expected period from %v but got %v", want, st.PeriodStart)
+		}
+		if want := time.Date(2024, time.April, 26, 0, 0, 0, 0, time.UTC); !st.DueDate.Equal(want) {
+			t.Errorf("expected due date %v but got %v", want, st.DueDate)
+		}
+		if want := NewMoney(-350000, "THB"); st.ClosingBalance != want {
+			t.Errorf("expected closing balance %v but got %v", want, st.ClosingBalance)
+		}
+		if want := NewMoney(17500, "THB"); st.MinimumPayment != want {
+			t.Errorf("expected minimum payment %v but got %v", want, st.MinimumPayment)
+		}
+	})
+
+	t.Run("given statement partly unpaid at due date should charge interest on the rest", func(t *testing.T) {
+		bills := &billingRuns{closed: map[int]Money{}}
+		st := Statement{ID: 9, WalletID: 2, Currency: "THB", ClosingBalance: NewMoney(-365000, "THB"),
+			PeriodEnd: time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC), DueDate: time.Date(2024, time.April, 26, 0, 0, 0, 0, time.UTC)}
+		store := StubWallet{
+			statements: []Statement{st},
+			activity:   StatementActivity{Credits: NewMoney(182500, "THB")},
+			bills:      bills,
+		}
+		b := NewBilling(New(store))
+		b.now = func() time.Time { return time.Date(2024, time.April, 26, 1, 0, 0, 0, time.UTC) }
+
+		if err := b.RunDue(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		// 1825.00 unpaid at 16% a year for 25 days.
+		if got, want := bills.closed[9], NewMoney(2000, "THB"); got != want {
+			t.Errorf("expected interest %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given one wallet cannot be billed should still bill the others", func(t *testing.T) {
+		bills := &billingRuns{closed: map[int]Money{}, fail: 2}
+		other := card
+		other.ID = 3
+		store := StubWallet{wallet: []Wallet{card, other}, bills: bills}
+		b := NewBilling(New(store))
+		b.now = func() time.Time { return time.Date(2024, time.April, 1, 0, 30, 0, 0, time.UTC) }
+
+		if err := b.RunDue(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		if len(bills.created) != 1 || bills.created[0].WalletID != 3 {
+			t.Errorf("expected a statement for wallet 3 only but got %+v", bills.created)
+		}
+	})
+
+	t.Run("given previous statement still unpaid should charge its balance since its due date", func(t *testing.T) {
+		bills := &billingRuns{closed: map[int]Money{}}
+		closedAt := time.Date(2024, time.April, 26, 1, 0, 0, 0, time.UTC)
+		prev := Statement{ID: 9, WalletID: 2, Currency: "THB", ClosingBalance: NewMoney(-365000, "THB"), Interest: NewMoney(4000, "THB"),
+			PeriodEnd: time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC), DueDate: time.Date(2024, time.April, 26, 0, 0, 0, 0, time.UTC), ClosedAt: &closedAt}
+		st := Statement{ID: 10, WalletID: 2, Currency: "THB", ClosingBalance: NewMoney(-369000, "THB"), PeriodStart: prev.PeriodEnd,
+			PeriodEnd: time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC), DueDate: time.Date(2024, time.May, 26, 0, 0, 0, 0, time.UTC)}
+		store := StubWallet{statements: []Statement{st, prev}, bills: bills}
+		b := NewBilling(New(store))
+		b.now = func() time.Time { return time.Date(2024, time.May, 26, 1, 0, 0, 0, time.UTC) }
+
+		if err := b.RunDue(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		// 3690.00 unpaid at 16% a year for the 25 days to the due date,
+		// plus for the 5 days it was already overdue before May.
+		if got, want := bills.closed[10], NewMoney(4853, "THB"); got != want {
+			t.Errorf("expected interest %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given statement paid in full should close without interest", func(t *testing.T) {
+		st := Statement{Currency: "THB", ClosingBalance: NewMoney(-365000, "THB"),
+			PeriodEnd: time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC), DueDate: time.Date(2024, time.April, 26, 0, 0, 0, 0, time.UTC)}
+
+		got, err := st.interest(NewMoney(400000, "THB"), nil, Money{})
+
+		if err != nil || !got.IsZero() {
+			t.Errorf("expected no interest but got %v, %v", got, err)
+		}
+	})
+}
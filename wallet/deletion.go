@@ -0,0 +1,121 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KKGo-Software-engineering/fun-exercise-api/idempotency"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// DefaultRetention is how long a deleted wallet can be restored before
+	// it is purged.
+	DefaultRetention     = 30 * 24 * time.Hour
+	defaultPurgeInterval = 24 * time.Hour
+)
+
+var (
+	ErrWalletNotDeleted = errors.New("wallet is not deleted")
+	// ErrRestoreExpired is returned for a wallet deleted longer ago than
+	// the retention window, which is waiting to be purged.
+	ErrRestoreExpired = errors.New("wallet was deleted too long ago to restore")
+)
+
+// WithRetention sets how long deleted wallets are kept for restoring.
+func WithRetention(d time.Duration) Option {
+	return func(h *Handler) {
+		h.retention = d
+	}
+}
+
+// actor names who made a request, for the audit fields it writes: the
+// caller's X-Client-ID or, without one, its address.
+func actor(c echo.Context) string {
+	if id := c.Request().Header.Get(idempotency.HeaderClientID); id != "" {
+		return id
+	}
+	return c.RealIP()
+}
+
+// includeDeleted reads the include_deleted query parameter.
+func includeDeleted(c echo.Context) (bool, error) {
+	s := c.QueryParam("include_deleted")
+	if s == "" {
+		return false, nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, errors.New("include_deleted must be true or false")
+	}
+	return v, nil
+}
+
+// RestoreWalletHandler
+//
+//	@Summary		Restore wallet
+//	@Description	Undo the deletion of a wallet deleted within the retention window
+//	@Tags			wallet
+//	@Produce		json
+//	@Param			id	path		int	true	"Wallet ID"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; needs owner"
+//	@Success		200	{object}	Wallet
+//	@Failure		400	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		409	{object}	Err
+//	@Failure		410	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/wallets/:id/restore [post]
+func (h *Handler) RestoreWalletHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := h.authorize(c, id, RoleOwner); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	w, err := h.store.RestoreWallet(id, time.Now().UTC().Add(-h.retention))
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, w)
+}
+
+// Purger removes for good the wallets deleted longer ago than the
+// retention window.
+type Purger struct {
+	handler  *Handler
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewPurger(h *Handler) *Purger {
+	return &Purger{
+		handler:  h,
+		interval: defaultPurgeInterval,
+		now:      time.Now,
+	}
+}
+
+// Run purges every interval until ctx is done.
+func (p *Purger) Run(ctx context.Context) {
+	runEvery(ctx, p.interval, "purge", p.RunDue)
+}
+
+// RunDue purges the wallets whose retention window has passed.
+func (p *Purger) RunDue() error {
+	n, err := p.handler.store.PurgeWallets(p.now().UTC().Add(-p.handler.retention))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("purge: removed %d deleted wallets", n)
+	}
+	return nil
+}
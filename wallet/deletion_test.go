@@ -0,0 +1,121 @@
+//go:build unit
+
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestSoftDelete(t *testing.T) {
+	recent := time.Now().UTC().Add(-24 * time.Hour)
+	old := time.Now().UTC().Add(-DefaultRetention - 24*time.Hour)
+	wallets := []Wallet{
+		{ID: 1, UserID: 1, WalletName: "John's Savings"},
+		{ID: 2, UserID: 1, WalletName: "John's Card", DeletedAt: &recent, DeletedBy: "backoffice"},
+		{ID: 3, UserID: 1, WalletName: "John's Crypto", DeletedAt: &old, DeletedBy: "backoffice"},
+	}
+
+	list := func(query string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/"+query, nil)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets")
+
+		New(StubWallet{wallet: wallets}, WithAdmins(1)).GetAllWalletsHandler(c)
+		return rec
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"no include_deleted should hide deleted wallets", "", 1},
+		{"include_deleted should list deleted wallets", "?include_deleted=true", 3},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name, func(t *testing.T) {
+			var got []Wallet
+			if err := json.Unmarshal(list(tt.query).Body.Bytes(), &got); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("expected %d wallets but got %d", tt.want, len(got))
+			}
+		})
+	}
+
+	t.Run("given include_deleted from user who is not an admin should return 403", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/?include_deleted=true", nil)
+		req.Header.Set(HeaderUserID, "2")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets")
+
+		New(StubWallet{wallet: wallets}, WithAdmins(1)).GetAllWalletsHandler(c)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rec.Code)
+		}
+	})
+
+	t.Run("given include_deleted not a boolean should return 400", func(t *testing.T) {
+		if rec := list("?include_deleted=maybe"); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	restores := []struct {
+		name string
+		id   string
+		want int
+	}{
+		{"wallet deleted within retention", "2", http.StatusOK},
+		{"wallet deleted past retention", "3", http.StatusGone},
+		{"wallet not deleted", "1", http.StatusConflict},
+		{"unknown wallet", "9", http.StatusNotFound},
+	}
+	for _, tt := range restores {
+		t.Run("given "+tt.name+" restore should return "+http.StatusText(tt.want), func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set(HeaderUserID, "1")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/wallets/:id/restore")
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+
+			New(StubWallet{wallet: wallets}).RestoreWalletHandler(c)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status code %d but got %d", tt.want, rec.Code)
+			}
+		})
+	}
+
+	t.Run("given shorter retention should restore only recent deletions", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("2")
+
+		New(StubWallet{wallet: wallets}, WithRetention(time.Hour)).RestoreWalletHandler(c)
+
+		if rec.Code != http.StatusGone {
+			t.Errorf("expected status code %d but got %d", http.StatusGone, rec.Code)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package wallet
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrWalletNotFound    = errors.New("wallet not found")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrConversionTooSmall is returned when an amount converts to nothing
+	// in the destination currency.
+	ErrConversionTooSmall = errors.New("amount is too small to convert")
+)
+
+// errorStatus maps store errors to the HTTP status returned to clients.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrCallerID):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrCallerRequired):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden), errors.Is(err, ErrAdminRequired):
+		return http.StatusForbidden
+	case errors.Is(err, ErrWalletNotFound), errors.Is(err, ErrHoldNotFound), errors.Is(err, ErrScheduleNotFound),
+		errors.Is(err, ErrUserNotFound), errors.Is(err, ErrFeeRuleNotFound), errors.Is(err, ErrTransactionNotFound),
+		errors.Is(err, ErrMemberNotFound), errors.Is(err, ErrCategoryNotFound), errors.Is(err, ErrCategoryRuleNotFound),
+		errors.Is(err, ErrCorrectionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrHoldNotActive), errors.Is(err, ErrWalletFrozen), errors.Is(err, ErrWalletClosed),
+		errors.Is(err, ErrStatusTransition), errors.Is(err, ErrWalletNotEmpty), errors.Is(err, ErrWalletNotDeleted),
+		errors.Is(err, ErrUserHasWallets), errors.Is(err, ErrAlreadyReversed),
+		errors.Is(err, ErrPrimaryOwner), errors.Is(err, ErrCategoryExists), errors.Is(err, ErrWalletTypeChange),
+		errors.Is(err, ErrFeeWallet), errors.Is(err, ErrCorrectionResolved):
+		return http.StatusConflict
+	case errors.Is(err, ErrRestoreExpired):
+		return http.StatusGone
+	case errors.Is(err, ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, ErrInsufficientFunds), errors.Is(err, ErrLimitExceeded):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrOverflow), errors.Is(err, ErrPrecision), errors.Is(err, ErrCurrencyMismatch),
+		errors.Is(err, ErrRateUnavailable), errors.Is(err, ErrCaptureAmount), errors.Is(err, ErrConversionTooSmall),
+		errors.Is(err, ErrInterestProductNotFound), errors.Is(err, ErrUnknownUser), errors.Is(err, ErrNotReversible),
+		errors.Is(err, ErrReversalAmount), errors.Is(err, ErrUnbalancedJournal), errors.Is(err, ErrUnknownCategory),
+		errors.Is(err, ErrBelowCreditLimit):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errBody is the response to a request the store refused. A debit over a
+// spending limit also gets what is left of that limit.
+func errBody(err error) Err {
+	body := Err{Message: err.Error()}
+	var limit *LimitError
+	if errors.As(err, &limit) {
+		body.Allowance = &limit.Allowance
+	}
+	return body
+}
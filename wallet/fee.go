@@ -0,0 +1,407 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	ErrFeeRuleNotFound = errors.New("fee rule not found")
+	// ErrNoFeeWallet is returned for a fee in a currency that has no house
+	// wallet to credit it to.
+	ErrNoFeeWallet = errors.New("no house fee wallet for the currency")
+	// ErrFeeWallet is returned for closing or deleting a house fee wallet,
+	// which fees in its currency would otherwise have nowhere to go.
+	ErrFeeWallet = errors.New("wallet collects fees and cannot be closed or deleted")
+)
+
+// feeOperations are the movements fee rules can price.
+var feeOperations = []string{TransactionTransfer, TransactionWithdrawal}
+
+// FeeTier prices amounts from From upwards, until the next tier.
+type FeeTier struct {
+	From Money  `json:"from" swaggertype:"number" example:"10000.00"`
+	Flat Money  `json:"flat" swaggertype:"number" example:"0.00"`
+	Rate string `json:"rate,omitempty" example:"0.005"`
+}
+
+// FeeRule prices one operation on wallets in one currency, optionally of
+// one wallet type. The fee on an amount is Flat plus Rate of it, or the
+// flat and rate of the highest tier the amount reaches, rounded to the
+// currency and then kept between Min and Max.
+type FeeRule struct {
+	ID         int       `json:"id" example:"1"`
+	Name       string    `json:"name" example:"Transfer fee"`
+	Operation  string    `json:"operation" example:"transfer"`
+	WalletType string    `json:"wallet_type,omitempty" example:"Savings"`
+	Currency   string    `json:"currency" example:"THB"`
+	Flat       Money     `json:"flat" swaggertype:"number" example:"5.00"`
+	Rate       string    `json:"rate,omitempty" example:"0.01"`
+	Tiers      []FeeTier `json:"tiers,omitempty"`
+	Min        *Money    `json:"min,omitempty" swaggertype:"number" example:"5.00"`
+	Max        *Money    `json:"max,omitempty" swaggertype:"number" example:"250.00"`
+	CreatedAt  time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
+
+// validateRate checks that s is a decimal fraction from 0 up to but not
+// including 1, with at most 6 decimal places.
+func validateRate(field, s string) error {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || !decimalPattern.MatchString(s) {
+		return fmt.Errorf("%s must be a decimal fraction such as 0.015", field)
+	}
+	if r.Sign() < 0 || r.Cmp(big.NewRat(1, 1)) >= 0 {
+		return fmt.Errorf("%s must be at least 0 and below 1", field)
+	}
+	if exact := new(big.Rat).Mul(r, big.NewRat(1000000, 1)); !exact.IsInt() {
+		return fmt.Errorf("%s must have at most 6 decimal places", field)
+	}
+	return nil
+}
+
+// priced returns m in currency, failing unless it is at least zero.
+func priced(field string, m Money, currency string) (Money, error) {
+	m, err := m.WithCurrency(currency)
+	if err != nil {
+		return Money{}, fmt.Errorf("%s: %w", field, err)
+	}
+	if m.IsNegative() {
+		return Money{}, fmt.Errorf("%s must not be negative", field)
+	}
+	return m, nil
+}
+
+// Normalize validates r and prices its amounts in its currency.
+func (r FeeRule) Normalize() (FeeRule, error) {
+	if r.Name == "" || len(r.Name) > 255 {
+		return FeeRule{}, errors.New("name is required and must be at most 255 characters")
+	}
+	if !isFeeOperation(r.Operation) {
+		return FeeRule{}, errors.New("operation must be transfer or withdrawal")
+	}
+	switch r.WalletType {
+	case "", WalletTypeSavings, WalletTypeCreditCard, WalletTypeCrypto:
+	default:
+		return FeeRule{}, errors.New("unknown wallet_type " + r.WalletType)
+	}
+	if r.Currency == "" {
+		r.Currency = DefaultCurrency
+	}
+	if !IsSupportedCurrency(r.Currency) {
+		return FeeRule{}, errors.New("unsupported currency " + r.Currency)
+	}
+	var err error
+	if r.Flat, err = priced("flat", r.Flat, r.Currency); err != nil {
+		return FeeRule{}, err
+	}
+	if r.Rate != "" {
+		if err := validateRate("rate", r.Rate); err != nil {
+			return FeeRule{}, err
+		}
+	}
+	tiers := make([]FeeTier, len(r.Tiers))
+	for i, t := range r.Tiers {
+		if t.From, err = priced("tier from", t.From, r.Currency); err != nil {
+			return FeeRule{}, err
+		}
+		if i > 0 && t.From.Cmp(tiers[i-1].From) <= 0 {
+			return FeeRule{}, errors.New("tiers must be in increasing order of from")
+		}
+		if t.Flat, err = priced("tier flat", t.Flat, r.Currency); err != nil {
+			return FeeRule{}, err
+		}
+		if t.Rate != "" {
+			if err := validateRate("tier rate", t.Rate); err != nil {
+				return FeeRule{}, err
+			}
+		}
+		tiers[i] = t
+	}
+	r.Tiers = tiers
+	for _, bound := range []struct {
+		name string
+		m    **Money
+	}{{"min", &r.Min}, {"max", &r.Max}} {
+		if *bound.m == nil {
+			continue
+		}
+		m, err := priced(bound.name, **bound.m, r.Currency)
+		if err != nil {
+			return FeeRule{}, err
+		}
+		*bound.m = &m
+	}
+	if r.Min != nil && r.Max != nil && r.Min.Cmp(*r.Max) > 0 {
+		return FeeRule{}, errors.New("min must not be above max")
+	}
+	return r, nil
+}
+
+func isFeeOperation(op string) bool {
+	for _, o := range feeOperations {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Fee is the fee r charges on amount, which is in r's currency.
+func (r FeeRule) Fee(amount Money) (Money, error) {
+	flat, rate := r.Flat, r.Rate
+	for _, t := range r.Tiers {
+		if amount.Cmp(t.From) >= 0 {
+			flat, rate = t.Flat, t.Rate
+		}
+	}
+	fee := NewMoney(0, amount.Currency())
+	if rate != "" {
+		rat, _ := new(big.Rat).SetString(rate)
+		var err error
+		if fee, err = amount.MulRat(rat, RoundHalfEven); err != nil {
+			return Money{}, err
+		}
+	}
+	fee, err := fee.Add(flat)
+	if err != nil {
+		return Money{}, err
+	}
+	if r.Min != nil && fee.Cmp(*r.Min) < 0 {
+		fee = *r.Min
+	}
+	if r.Max != nil && fee.Cmp(*r.Max) > 0 {
+		fee = *r.Max
+	}
+	return fee, nil
+}
+
+// matchFeeRule picks the rule pricing op on w: one for w's type over one
+// for any type, and the newest of equals.
+func matchFeeRule(rules []FeeRule, op string, w Wallet) *FeeRule {
+	var best *FeeRule
+	for i, r := range rules {
+		if r.Operation != op || r.Currency != w.Currency || (r.WalletType != "" && r.WalletType != w.WalletType) {
+			continue
+		}
+		if best == nil || r.outranks(*best) {
+			best = &rules[i]
+		}
+	}
+	return best
+}
+
+// outranks reports whether r applies rather than o when both match.
+func (r FeeRule) outranks(o FeeRule) bool {
+	if (r.WalletType != "") != (o.WalletType != "") {
+		return r.WalletType != ""
+	}
+	return r.ID > o.ID
+}
+
+// FeeQuote is the fee an operation would be charged right now.
+type FeeQuote struct {
+	Operation string `json:"operation" example:"transfer"`
+	WalletID  int    `json:"wallet_id" example:"1"`
+	Currency  string `json:"currency" example:"THB"`
+	Amount    Money  `json:"amount" swaggertype:"number" example:"1000.00"`
+	Fee       Money  `json:"fee" swaggertype:"number" example:"10.00"`
+	// Total is what leaves the wallet: the amount and its fee.
+	Total  Money `json:"total" swaggertype:"number" example:"1010.00"`
+	RuleID *int  `json:"rule_id,omitempty" example:"1"`
+}
+
+// quote prices op of amount, in w's currency, with the current fee rules.
+func (h *Handler) quote(op string, w Wallet, amount Money) (FeeQuote, error) {
+	q := FeeQuote{Operation: op, WalletID: w.ID, Currency: w.Currency, Amount: amount, Fee: NewMoney(0, w.Currency), Total: amount}
+	if !isFeeOperation(op) {
+		return q, nil
+	}
+	rules, err := h.store.FeeRules()
+	if err != nil {
+		return FeeQuote{}, err
+	}
+	rule := matchFeeRule(rules, op, w)
+	if rule == nil {
+		return q, nil
+	}
+	if q.Fee, err = rule.Fee(amount); err != nil {
+		return FeeQuote{}, err
+	}
+	if q.Total, err = amount.Add(q.Fee); err != nil {
+		return FeeQuote{}, err
+	}
+	q.RuleID = &rule.ID
+	return q, nil
+}
+
+// feeCharged is fee as reported on a movement, nil if nothing was charged.
+func feeCharged(fee Money) *Money {
+	if !fee.IsPositive() {
+		return nil
+	}
+	return &fee
+}
+
+// FeeQuoteHandler
+//
+//	@Summary		Quote fee
+//	@Description	Get the fee a transfer or withdrawal of an amount from a wallet would be charged now, before making it
+//	@Tags			fees
+//	@Produce		json
+//	@Param			operation	query		string	true	"transfer or withdrawal"
+//	@Param			wallet_id	query		int		true	"Wallet the money leaves"
+//	@Param			amount		query		number	true	"Amount in the wallet's currency"
+//	@Success		200			{object}	FeeQuote
+//	@Failure		400			{object}	Err
+//	@Failure		404			{object}	Err
+//	@Failure		500			{object}	Err
+//	@Router			/api/v1/fees/quote [get]
+func (h *Handler) FeeQuoteHandler(c echo.Context) error {
+	op := c.QueryParam("operation")
+	if !isFeeOperation(op) {
+		return c.JSON(http.StatusBadRequest, Err{Message: "operation must be transfer or withdrawal"})
+	}
+	id, err := strconv.Atoi(c.QueryParam("wallet_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "wallet_id must be a number"})
+	}
+	w, err := h.store.WalletByID(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	amount, err := ParseMoney(c.QueryParam("amount"), w.Currency)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "amount: " + err.Error()})
+	}
+	if !amount.IsPositive() {
+		return c.JSON(http.StatusBadRequest, Err{Message: "amount must be greater than zero"})
+	}
+	q, err := h.quote(op, w, amount)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, q)
+}
+
+// CreateFeeRuleHandler
+//
+//	@Summary		Create fee rule
+//	@Description	Create a rule pricing transfers or withdrawals. The newest rule for a wallet's type, or failing that for any type, applies.
+//	@Tags			fees
+//	@Accept			json
+//	@Produce		json
+//	@Param			rule	body		FeeRule	true	"Fee rule"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; must be an admin"
+//	@Success		201		{object}	FeeRule
+//	@Failure		400		{object}	Err
+//	@Failure		401		{object}	Err
+//	@Failure		403		{object}	Err
+//	@Failure		500		{object}	Err
+//	@Router			/api/v1/fee-rules [post]
+func (h *Handler) CreateFeeRuleHandler(c echo.Context) error {
+	if err := h.authorizeAdmin(c); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	var r FeeRule
+	if err := c.Bind(&r); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	r, err := r.Normalize()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if r, err = h.store.CreateFeeRule(r); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusCreated, r)
+}
+
+// FeeRulesHandler
+//
+//	@Summary		Get fee rules
+//	@Description	Get all fee rules
+//	@Tags			fees
+//	@Produce		json
+//	@Success		200	{array}		FeeRule
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/fee-rules [get]
+func (h *Handler) FeeRulesHandler(c echo.Context) error {
+	rules, err := h.store.FeeRules()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, rules)
+}
+
+// UpdateFeeRuleHandler
+//
+//	@Summary		Update fee rule
+//	@Description	Replace a fee rule. Fees already charged are not changed.
+//	@Tags			fees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int		true	"Fee rule ID"
+//	@Param			rule	body		FeeRule	true	"Fee rule"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; must be an admin"
+//	@Success		200		{object}	FeeRule
+//	@Failure		400		{object}	Err
+//	@Failure		401		{object}	Err
+//	@Failure		403		{object}	Err
+//	@Failure		404		{object}	Err
+//	@Failure		500		{object}	Err
+//	@Router			/api/v1/fee-rules/:id [put]
+func (h *Handler) UpdateFeeRuleHandler(c echo.Context) error {
+	if err := h.authorizeAdmin(c); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	var r FeeRule
+	if err := c.Bind(&r); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if r, err = r.Normalize(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if r, err = h.store.UpdateFeeRule(id, r); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, r)
+}
+
+// DeleteFeeRuleHandler
+//
+//	@Summary		Delete fee rule
+//	@Description	Delete a fee rule. Fees already charged are not changed.
+//	@Tags			fees
+//	@Produce		json
+//	@Param			id	path	int	true	"Fee rule ID"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; must be an admin"
+//	@Success		204
+//	@Failure		400	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/fee-rules/:id [delete]
+func (h *Handler) DeleteFeeRuleHandler(c echo.Context) error {
+	if err := h.authorizeAdmin(c); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := h.store.DeleteFeeRule(id); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
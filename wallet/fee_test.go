@@ -0,0 +1,217 @@
+//go:build unit
+
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestFeeRuleFee(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   FeeRule
+		amount string
+		want   string
+	}{
+		{"flat fee", FeeRule{Flat: *thb("10.00")}, "1000.00", "10.00"},
+		{"percentage fee", FeeRule{Rate: "0.01"}, "1234.56", "12.35"},
+		{"percentage fee rounded half to even", FeeRule{Rate: "0.005"}, "101.00", "0.50"},
+		{"flat and percentage fee", FeeRule{Flat: *thb("5.00"), Rate: "0.001"}, "1000.00", "6.00"},
+		{"percentage fee under minimum", FeeRule{Rate: "0.01", Min: thb("15.00")}, "100.00", "15.00"},
+		{"percentage fee over cap", FeeRule{Rate: "0.01", Max: thb("250.00")}, "100000.00", "250.00"},
+		{"amount below first tier", FeeRule{Flat: *thb("20.00"), Tiers: []FeeTier{{From: *thb("10000.00"), Rate: "0.001"}}}, "9999.99", "20.00"},
+		{"amount reaching a tier", FeeRule{Flat: *thb("20.00"), Tiers: []FeeTier{{From: *thb("10000.00"), Rate: "0.001"}, {From: *thb("50000.00")}}}, "10000.00", "10.00"},
+		{"amount reaching the highest tier", FeeRule{Flat: *thb("20.00"), Tiers: []FeeTier{{From: *thb("10000.00"), Rate: "0.001"}, {From: *thb("50000.00")}}}, "80000.00", "0.00"},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should charge "+tt.want, func(t *testing.T) {
+			got, err := tt.rule.Fee(MustParseMoney(tt.amount, "THB"))
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got != *thb(tt.want) {
+				t.Errorf("expected fee %s but got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMatchFeeRule(t *testing.T) {
+	rules := []FeeRule{
+		{ID: 1, Operation: TransactionTransfer, Currency: "THB", Flat: *thb("10.00")},
+		{ID: 2, Operation: TransactionTransfer, WalletType: WalletTypeSavings, Currency: "THB", Flat: *thb("5.00")},
+		{ID: 3, Operation: TransactionTransfer, Currency: "THB", Flat: *thb("15.00")},
+		{ID: 4, Operation: TransactionWithdrawal, Currency: "THB", Flat: *thb("25.00")},
+		{ID: 5, Operation: TransactionTransfer, Currency: "USD"},
+	}
+	tests := []struct {
+		name   string
+		op     string
+		wallet Wallet
+		want   int
+	}{
+		{"rule for wallet type", TransactionTransfer, Wallet{WalletType: WalletTypeSavings, Currency: "THB"}, 2},
+		{"newest rule for any type", TransactionTransfer, Wallet{WalletType: WalletTypeCreditCard, Currency: "THB"}, 3},
+		{"rule for operation", TransactionWithdrawal, Wallet{WalletType: WalletTypeSavings, Currency: "THB"}, 4},
+		{"no rule in currency", TransactionWithdrawal, Wallet{WalletType: WalletTypeSavings, Currency: "USD"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should match it", func(t *testing.T) {
+			got := 0
+			if r := matchFeeRule(rules, tt.op, tt.wallet); r != nil {
+				got = r.ID
+			}
+			if got != tt.want {
+				t.Errorf("expected rule %d but got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFeeRules(t *testing.T) {
+	rules := []FeeRule{{ID: 1, Name: "Transfer fee", Operation: TransactionTransfer, Currency: "THB", Flat: *thb("10.00")}}
+
+	tests := []struct {
+		name    string
+		method  string
+		id      string
+		body    string
+		handler func(*Handler) echo.HandlerFunc
+		want    int
+	}{
+		{"new percentage rule with cap", http.MethodPost, "", `{"name": "Withdrawal fee", "operation": "withdrawal", "rate": "0.01", "min": 15, "max": 250}`, func(h *Handler) echo.HandlerFunc { return h.CreateFeeRuleHandler }, http.StatusCreated},
+		{"new tiered rule", http.MethodPost, "", `{"name": "Transfer fee", "operation": "transfer", "wallet_type": "Savings", "flat": 20, "tiers": [{"from": 10000, "rate": "0.001"}, {"from": 50000, "flat": 0}]}`, func(h *Handler) echo.HandlerFunc { return h.CreateFeeRuleHandler }, http.StatusCreated},
+		{"rule for unknown operation", http.MethodPost, "", `{"name": "Deposit fee", "operation": "deposit", "flat": 10}`, func(h *Handler) echo.HandlerFunc { return h.CreateFeeRuleHandler }, http.StatusBadRequest},
+		{"rule for unknown wallet type", http.MethodPost, "", `{"name": "Fee", "operation": "transfer", "wallet_type": "Pension", "flat": 10}`, func(h *Handler) echo.HandlerFunc { return h.CreateFeeRuleHandler }, http.StatusBadRequest},
+		{"rule with negative flat fee", http.MethodPost, "", `{"name": "Fee", "operation": "transfer", "flat": -1}`, func(h *Handler) echo.HandlerFunc { return h.CreateFeeRuleHandler }, http.StatusBadRequest},
+		{"rule with rate of 1 or more", http.MethodPost, "", `{"name": "Fee", "operation": "transfer", "rate": "1.5"}`, func(h *Handler) echo.HandlerFunc { return h.CreateFeeRuleHandler }, http.StatusBadRequest},
+		{"rule with tiers out of order", http.MethodPost, "", `{"name": "Fee", "operation": "transfer", "tiers": [{"from": 500}, {"from": 100}]}`, func(h *Handler) echo.HandlerFunc { return h.CreateFeeRuleHandler }, http.StatusBadRequest},
+		{"rule with min above max", http.MethodPost, "", `{"name": "Fee", "operation": "transfer", "rate": "0.01", "min": 50, "max": 10}`, func(h *Handler) echo.HandlerFunc { return h.CreateFeeRuleHandler }, http.StatusBadRequest},
+		{"list of rules", http.MethodGet, "", "", func(h *Handler) echo.HandlerFunc { return h.FeeRulesHandler }, http.StatusOK},
+		{"update of rule", http.MethodPut, "1", `{"name": "Transfer fee", "operation": "transfer", "flat": 12}`, func(h *Handler) echo.HandlerFunc { return h.UpdateFeeRuleHandler }, http.StatusOK},
+		{"update of unknown rule", http.MethodPut, "9", `{"name": "Transfer fee", "operation": "transfer", "flat": 12}`, func(h *Handler) echo.HandlerFunc { return h.UpdateFeeRuleHandler }, http.StatusNotFound},
+		{"delete of rule", http.MethodDelete, "1", "", func(h *Handler) echo.HandlerFunc { return h.DeleteFeeRuleHandler }, http.StatusNoContent},
+		{"delete of unknown rule", http.MethodDelete, "9", "", func(h *Handler) echo.HandlerFunc { return h.DeleteFeeRuleHandler }, http.StatusNotFound},
+		{"new rule by user who is not an admin", http.MethodPost, "", `{"name": "Fee", "operation": "transfer", "rate": "0.99"}`, func(h *Handler) echo.HandlerFunc { return h.CreateFeeRuleHandler }, http.StatusForbidden},
+		{"update of rule by user who is not an admin", http.MethodPut, "1", `{"name": "Fee", "operation": "transfer", "rate": "0.99"}`, func(h *Handler) echo.HandlerFunc { return h.UpdateFeeRuleHandler }, http.StatusForbidden},
+		{"delete of rule by user who is not an admin", http.MethodDelete, "1", "", func(h *Handler) echo.HandlerFunc { return h.DeleteFeeRuleHandler }, http.StatusForbidden},
+		{"new rule by unnamed user", http.MethodPost, "", `{"name": "Fee", "operation": "transfer", "rate": "0.99"}`, func(h *Handler) echo.HandlerFunc { return h.CreateFeeRuleHandler }, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			switch {
+			case strings.Contains(tt.name, "not an admin"):
+				req.Header.Set(HeaderUserID, "2")
+			case !strings.Contains(tt.name, "unnamed"):
+				req.Header.Set(HeaderUserID, "1")
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/fee-rules/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+
+			tt.handler(New(StubWallet{rules: rules}, WithAdmins(1)))(c)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status code %d but got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestFeeQuote(t *testing.T) {
+	wallets := []Wallet{{ID: 1, UserID: 1, WalletName: "John's Savings", WalletType: WalletTypeSavings, Currency: "THB", Balance: *thb("5000.00")}}
+	rules := []FeeRule{
+		{ID: 1, Name: "Transfer fee", Operation: TransactionTransfer, Currency: "THB", Rate: "0.01", Max: thb("250.00")},
+		{ID: 2, Name: "Withdrawal fee", Operation: TransactionWithdrawal, Currency: "THB", Flat: *thb("15.00")},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+		fee   string
+	}{
+		{"transfer", "operation=transfer&wallet_id=1&amount=1234.56", http.StatusOK, "12.35"},
+		{"withdrawal", "operation=withdrawal&wallet_id=1&amount=100", http.StatusOK, "15.00"},
+		{"deposit", "operation=deposit&wallet_id=1&amount=100", http.StatusBadRequest, ""},
+		{"unknown wallet", "operation=transfer&wallet_id=9&amount=100", http.StatusNotFound, ""},
+		{"amount finer than the currency", "operation=transfer&wallet_id=1&amount=1.001", http.StatusBadRequest, ""},
+		{"zero amount", "operation=transfer&wallet_id=1&amount=0", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run("given quote for "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/fees/quote?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			New(StubWallet{wallet: wallets, rules: rules}).FeeQuoteHandler(c)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected status code %d but got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+			if tt.fee == "" {
+				return
+			}
+			var got FeeQuote
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			total, _ := got.Amount.Add(*thb(tt.fee))
+			if got.Fee != *thb(tt.fee) || got.Total != total {
+				t.Errorf("expected fee %s on top of %s but got %+v", tt.fee, got.Amount, got)
+			}
+		})
+	}
+
+	t.Run("given withdrawal should report the fee charged", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 100}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/withdrawals")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		New(StubWallet{wallet: wallets, rules: rules, transaction: Transaction{ID: 1, WalletID: 1, Type: TransactionWithdrawal, Amount: *thb("-100.00"), Currency: "THB"}}).WithdrawalHandler(c)
+
+		var got Transaction
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if got.Fee == nil || *got.Fee != *thb("15.00") {
+			t.Errorf("expected fee 15.00 but got %v", got.Fee)
+		}
+	})
+
+	t.Run("given deposit should charge no fee", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 100}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/deposits")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		New(StubWallet{wallet: wallets, rules: rules, transaction: Transaction{ID: 1, WalletID: 1, Type: TransactionDeposit, Amount: *thb("100.00"), Currency: "THB"}}).DepositHandler(c)
+
+		if strings.Contains(rec.Body.String(), `"fee"`) {
+			t.Errorf("expected no fee but got %s", rec.Body)
+		}
+	})
+}
@@ -3,44 +3,145 @@ package wallet
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
 
 type Handler struct {
-	store Storer
+	store     Storer
+	rates     RateProvider
+	retention time.Duration
+	admins    map[int]bool
 }
 
 type Storer interface {
-	Wallets(wallet_type string) ([]Wallet, error)
-	WalletByUserID(id int) ([]Wallet, error)
-	CreateWallet(wallet Wallet) error
-	UpdateWallet(id int, wallet Wallet) error
-	DeleteWallet(id int) error
+	Wallets(wallet_type string, includeDeleted bool) ([]Wallet, error)
+	WalletByUserID(id int, includeDeleted bool) ([]Wallet, error)
+	WalletByID(id int) (Wallet, error)
+	WalletAt(id int, at time.Time) (Wallet, error)
+	WalletByUserIDAt(id int, at time.Time) ([]Wallet, error)
+	CreateWallet(wallet Wallet) (Wallet, error)
+	UpdateWallet(id int, wallet Wallet, version int) (Wallet, error)
+	PatchWallet(id int, wallet Wallet, fields []string, version int) (Wallet, error)
+	DeleteWallet(id int, by string) error
+	DeleteWalletByID(id int, by string, version int) error
+	RestoreWallet(id int, deletedSince time.Time) (Wallet, error)
+	PurgeWallets(deletedBefore time.Time) (int, error)
+	Transfer(t Transfer) (TransferResult, error)
+	JournalEntry(id int) (JournalEntry, error)
+	Deposit(walletID int, m Movement) (Transaction, error)
+	Withdraw(walletID int, m Movement) (Transaction, error)
+	Transactions(walletID int, f TransactionFilter) ([]Transaction, error)
+	PlaceHold(walletID int, req HoldRequest) (Hold, error)
+	Holds(walletID int) ([]Hold, error)
+	CaptureHold(id int, amount *Money) (Hold, error)
+	ReleaseHold(id int) (Hold, error)
+	HoldByID(id int) (Hold, error)
+	CreateSchedule(s Schedule) (Schedule, error)
+	ScheduleByID(id int) (Schedule, error)
+	Schedules(walletID int) ([]Schedule, error)
+	UpdateSchedule(id int, s Schedule) (Schedule, error)
+	DeleteSchedule(id int) error
+	ScheduleExecutions(scheduleID int) ([]ScheduleExecution, error)
+	DueSchedules(now time.Time, limit int) ([]Schedule, error)
+	RecordScheduleExecution(s Schedule, e ScheduleExecution) error
+	UnbilledWallets(walletType string, periodStart time.Time) ([]Wallet, error)
+	StatementActivity(walletID int, from, to time.Time) (StatementActivity, error)
+	CreateStatement(s Statement) (Statement, error)
+	Statements(walletID int) ([]Statement, error)
+	DueStatements(now time.Time) ([]Statement, error)
+	CloseStatement(id int, interest Money) (Statement, error)
+	CreateInterestProduct(p InterestProduct) (InterestProduct, error)
+	InterestProducts() ([]InterestProduct, error)
+	InterestAccounts() ([]InterestAccount, error)
+	BalanceAt(walletID int, at time.Time) (Money, error)
+	RecordAccrual(a Accrual) error
+	UnpaidAccruals(walletID int) ([]Accrual, error)
+	CapitalizeInterest(walletID int, before time.Time, accruals int, amount Money) error
+	BalanceChecks() ([]BalanceCheck, error)
+	OpenCorrection(d Discrepancy) (int, error)
+	Corrections(status string) ([]Correction, error)
+	ResolveCorrection(id int) (Correction, error)
+	ChangeWalletStatus(id int, to, reason string) (Wallet, error)
+	StatusChanges(walletID int) ([]StatusChange, error)
+	CreateUser(u User) (User, error)
+	Users() ([]User, error)
+	UserByID(id int) (User, error)
+	UpdateUser(id int, u User) (User, error)
+	DeleteUser(id int) error
+	SetLimits(walletID int, limits SpendingLimits) (Wallet, error)
+	Spending(walletID int, now time.Time) (Spending, error)
+	CreateFeeRule(r FeeRule) (FeeRule, error)
+	FeeRules() ([]FeeRule, error)
+	UpdateFeeRule(id int, r FeeRule) (FeeRule, error)
+	DeleteFeeRule(id int) error
+	ReverseTransaction(id int, req ReversalRequest) (Reversal, error)
+	Members(walletID int) ([]Member, error)
+	Role(walletID, userID int) (string, error)
+	SetMember(walletID, userID int, role string) (Member, error)
+	RemoveMember(walletID, userID int) error
+	Categories(userID int) ([]Category, error)
+	CreateCategory(c Category) (Category, error)
+	DeleteCategory(userID, id int) error
+	CategoryRules(userID int) ([]CategoryRule, error)
+	CreateCategoryRule(r CategoryRule) (CategoryRule, error)
+	DeleteCategoryRule(userID, id int) error
+	LabelTransaction(walletID, transactionID int, l Labels) (Transaction, error)
 }
 
-func New(db Storer) *Handler {
-	return &Handler{store: db}
+type Option func(*Handler)
+
+// WithRates sets the exchange rates used for transfers between wallets of
+// different currencies. Without it such transfers are rejected.
+func WithRates(r RateProvider) Option {
+	return func(h *Handler) {
+		h.rates = r
+	}
+}
+
+func New(db Storer, opts ...Option) *Handler {
+	h := &Handler{store: db, retention: DefaultRetention}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 type Err struct {
 	Message string `json:"message"`
+	// Allowance is what is left of the spending limit a debit went over.
+	Allowance *Allowance `json:"allowance,omitempty"`
 }
 
 // GetAllWalletsHandler
 //
 //	@Summary		Get all wallets
-//	@Description	Get all wallets
+//	@Description	Get all wallets. Deleted wallets are left out unless include_deleted is set, which only admins may do.
 //	@Tags			wallet
 //	@Accept			json
 //	@Produce		json
+//	@Param			include_deleted	query	bool	false	"Include deleted wallets"
+//	@Param			X-User-ID	header		int		false	"User the request acts for; must be an admin to include deleted wallets"
 //	@Success		200	{object}	Wallet
+//	@Failure		400	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
 //	@Router			/api/v1/wallets [get]
 //	@Failure		500	{object}	Err
 //	@Router /api/v1/wallets [get]
 func (h *Handler) GetAllWalletsHandler(c echo.Context) error {
 	walletType := c.QueryParam("wallet_type")
-	wallets, err := h.store.Wallets(walletType)
+	deleted, err := includeDeleted(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if deleted {
+		if err := h.authorizeAdmin(c); err != nil {
+			return c.JSON(errorStatus(err), Err{Message: err.Error()})
+		}
+	}
+	wallets, err := h.store.Wallets(walletType, deleted)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
 	}
@@ -50,11 +151,17 @@ func (h *Handler) GetAllWalletsHandler(c echo.Context) error {
 // GetWalletByIDHandler
 //
 //	@Summary		Get wallet by user id
-//	@Description	Get wallet by user id
+//	@Description	Get the wallets a user owns or that are shared with them, each with the user's role, with balances as they were at as_of if it is given
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
+//	@Param			as_of			query	string	false	"RFC 3339 timestamp"
+//	@Param			include_deleted	query	bool	false	"Include deleted wallets"
+//	@Param			X-User-ID	header		int		false	"User the request acts for; must be the user or an admin to include deleted wallets"
 //	@Success		200	{object}	Wallet
+//	@Failure		400	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
 //	@Router			/api/v1/users/:id/wallets [get]
 //	@Failure		500	{object}	Err
 //	@Router /api/v1/users/:id/wallets [get]
@@ -63,7 +170,27 @@ func (h *Handler) GetWalletByIDHandler(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
 	}
-	wallets, err := h.store.WalletByUserID(id)
+	asOf, past, err := parseAsOf(c, time.Now().UTC())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	deleted, err := includeDeleted(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if deleted {
+		if userID, err := caller(c); err != nil || userID != id {
+			if err := h.authorizeAdmin(c); err != nil {
+				return c.JSON(errorStatus(err), Err{Message: err.Error()})
+			}
+		}
+	}
+	var wallets []Wallet
+	if past {
+		wallets, err = h.store.WalletByUserIDAt(id, asOf)
+	} else {
+		wallets, err = h.store.WalletByUserID(id, deleted)
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
 	}
@@ -73,12 +200,16 @@ func (h *Handler) GetWalletByIDHandler(c echo.Context) error {
 // CreateWalletHandler
 //
 //	@Summary		Create wallet
-//	@Description	Create wallet
+//	@Description	Create a wallet for the existing user named by user_id
 //	@Tags			wallet
 //	@Accept			json
 //	@Produce		json
+//	@Param			Idempotency-Key	header	string	false	"Retry-safe request key"
 //	@Success		201	{object}	Wallet
 //	@Router			/api/v1/wallets [post]
+//	@Failure		400	{object}	Err
+//	@Failure		409	{object}	Err
+//	@Failure		422	{object}	Err
 //	@Failure		500	{object}	Err
 //	@Router /api/v1/wallets [post]
 func (h *Handler) CreateWalletHandler(c echo.Context) error {
@@ -86,9 +217,33 @@ func (h *Handler) CreateWalletHandler(c echo.Context) error {
 	if err := c.Bind(&wallet); err != nil {
 		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
 	}
-	err := h.store.CreateWallet(wallet)
+	if wallet.Currency == "" {
+		wallet.Currency = DefaultCurrency
+	}
+	if !IsSupportedCurrency(wallet.Currency) {
+		return c.JSON(http.StatusBadRequest, Err{Message: "unsupported currency " + wallet.Currency})
+	}
+	if err := wallet.validateAsset(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	balance, err := wallet.Balance.WithCurrency(wallet.Currency)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	wallet.Balance = balance
+	if wallet.CreditLimit, err = wallet.CreditLimit.WithCurrency(wallet.Currency); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := wallet.validateCredit(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := wallet.validateInterest(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	wallet.Status, wallet.StatusReason = WalletStatusActive, ""
+	wallet, err = h.store.CreateWallet(wallet)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
 	}
 	return c.JSON(http.StatusCreated, wallet)
 }
@@ -96,12 +251,21 @@ func (h *Handler) CreateWalletHandler(c echo.Context) error {
 // UpdateWalletHandler
 //
 //	@Summary		Update wallet by id
-//	@Description	Update wallet by id
+//	@Description	Update wallet by id. If-Match must carry the wallet's ETag, or * to overwrite whatever is stored. The wallet_type must stay the one the wallet was created with. The balance is read-only: it changes through deposits, withdrawals and transfers, and mistakes in posted transactions are corrected with a reversal.
 //	@Tags			wallet
 //	@Accept			json
 //	@Produce		json
+//	@Param			If-Match	header	string	true	"ETag of the wallet as last read"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; needs owner"
 //	@Success		200	{object}	Wallet
+//	@Header			200	{string}	ETag	"Version of the updated wallet"
 //	@Router			/api/v1/wallets/:id [put]
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Failure		409	{object}	Err
+//	@Failure		412	{object}	Err
+//	@Failure		422	{object}	Err
+//	@Failure		428	{object}	Err
 //	@Failure		500	{object}	Err
 //	@Router /api/v1/wallets/:id [put]
 func (h *Handler) UpdateWalletHandler(c echo.Context) error {
@@ -109,26 +273,44 @@ func (h *Handler) UpdateWalletHandler(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
 	}
+	if err := h.authorize(c, id, RoleOwner); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	version, status, err := ifMatch(c)
+	if err != nil {
+		return c.JSON(status, Err{Message: err.Error()})
+	}
 	var wallet Wallet
 	if err := c.Bind(&wallet); err != nil {
 		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
 	}
-	err = h.store.UpdateWallet(id, wallet)
+	if err := wallet.validateCreditLimit(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := wallet.validateInterest(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	wallet, err = h.store.UpdateWallet(id, wallet, version)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
 	}
+	setETag(c, wallet)
 	return c.JSON(http.StatusOK, wallet)
 }
 
 // DeleteWalletByIDHandler
 //
 //	@Summary		Delete wallet by user_id
-//	@Description	Delete wallet by user_id
+//	@Description	Delete every wallet of the user. Every wallet must have a zero balance and no active holds, and none may be a house fee wallet. Deleted wallets can be restored until the retention window passes and they are purged.
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
+//	@Param			X-User-ID	header		int		true	"User the request acts for; must be the user"
 //	@Success		204	{object}	Wallet
 //	@Router			/api/v1/users/:id/wallets [delete]
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Failure		409	{object}	Err
 //	@Failure		500	{object}	Err
 //	@Router /api/v1/users/:id/wallets [delete]
 func (h *Handler) DeleteWalletByIDHandler(c echo.Context) error {
@@ -136,9 +318,79 @@ func (h *Handler) DeleteWalletByIDHandler(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
 	}
-	err = h.store.DeleteWallet(id)
+	userID, err := caller(c)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	if userID != id {
+		return c.JSON(http.StatusForbidden, Err{Message: ErrForbidden.Error()})
+	}
+	err = h.store.DeleteWallet(id, actor(c))
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
 	}
 	return c.JSON(http.StatusNoContent, nil)
 }
+
+// GetWalletHandler
+//
+//	@Summary		Get wallet by id
+//	@Description	Get one wallet by its id
+//	@Tags			wallet
+//	@Produce		json
+//	@Param			id	path		int	true	"Wallet ID"
+//	@Success		200	{object}	Wallet
+//	@Header			200	{string}	ETag	"Version of the wallet, for If-Match"
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/wallets/:id [get]
+func (h *Handler) GetWalletHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	w, err := h.store.WalletByID(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	setETag(c, w)
+	return c.JSON(http.StatusOK, w)
+}
+
+// DeleteWalletHandler
+//
+//	@Summary		Delete wallet by id
+//	@Description	Delete one wallet by its id. It must have a zero balance and no active holds, and must not be a house fee wallet. It can be restored until the retention window passes and it is purged. If-Match must carry the wallet's ETag, or * to delete whatever is stored.
+//	@Tags			wallet
+//	@Produce		json
+//	@Param			id			path	int		true	"Wallet ID"
+//	@Param			If-Match	header	string	true	"ETag of the wallet as last read"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; needs owner"
+//	@Success		204
+//	@Failure		400	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		409	{object}	Err
+//	@Failure		412	{object}	Err
+//	@Failure		428	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/wallets/:id [delete]
+func (h *Handler) DeleteWalletHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := h.authorize(c, id, RoleOwner); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	version, status, err := ifMatch(c)
+	if err != nil {
+		return c.JSON(status, Err{Message: err.Error()})
+	}
+	if err := h.store.DeleteWalletByID(id, actor(c), version); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,235 @@
+package wallet
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Hold statuses. A hold is active until it is captured, released or passes
+// its expiry; only active holds reduce the available balance.
+const (
+	HoldActive   = "active"
+	HoldCaptured = "captured"
+	HoldReleased = "released"
+	HoldExpired  = "expired"
+)
+
+const (
+	defaultHoldDuration = 7 * 24 * time.Hour
+	maxHoldDuration     = 30 * 24 * time.Hour
+)
+
+var (
+	ErrHoldNotFound  = errors.New("hold not found")
+	ErrHoldNotActive = errors.New("hold is no longer active")
+	ErrCaptureAmount = errors.New("capture amount must be positive and at most the held amount")
+)
+
+// Hold reserves part of a wallet's balance until it is captured, which
+// debits the wallet, or released.
+type Hold struct {
+	ID        int       `json:"id" example:"1"`
+	WalletID  int       `json:"wallet_id" example:"2"`
+	Amount    Money     `json:"amount" swaggertype:"number" example:"150.00"`
+	Captured  Money     `json:"captured" swaggertype:"number" example:"120.00"`
+	Currency  string    `json:"currency" example:"THB"`
+	Status    string    `json:"status" example:"active"`
+	Reference string    `json:"reference,omitempty" example:"AUTH-0001"`
+	ExpiresAt time.Time `json:"expires_at" example:"2024-04-01T14:19:00Z"`
+	CreatedAt time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
+
+// HoldRequest places a hold of Amount. ExpiresIn is in seconds and defaults
+// to seven days.
+type HoldRequest struct {
+	Amount    Money  `json:"amount" swaggertype:"number" example:"150.00"`
+	Reference string `json:"reference" example:"AUTH-0001"`
+	ExpiresIn int    `json:"expires_in" example:"86400"`
+}
+
+func (r HoldRequest) Validate() error {
+	if !r.Amount.IsPositive() {
+		return errors.New("amount must be greater than zero")
+	}
+	if len(r.Reference) > 255 {
+		return errors.New("reference must be at most 255 characters")
+	}
+	if r.ExpiresIn < 0 || time.Duration(r.ExpiresIn)*time.Second > maxHoldDuration {
+		return errors.New("expires_in must be between 0 and 30 days")
+	}
+	return nil
+}
+
+// Duration is how long the hold lasts before it expires.
+func (r HoldRequest) Duration() time.Duration {
+	if r.ExpiresIn == 0 {
+		return defaultHoldDuration
+	}
+	return time.Duration(r.ExpiresIn) * time.Second
+}
+
+// CaptureRequest settles a hold. Without an amount the full hold is
+// captured; any uncaptured remainder is released.
+type CaptureRequest struct {
+	Amount *Money `json:"amount,omitempty" swaggertype:"number" example:"120.00"`
+}
+
+// PlaceHoldHandler
+//
+//	@Summary		Place hold
+//	@Description	Reserve part of the wallet's available balance until the hold is captured, released or expires
+//	@Tags			holds
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int			true	"Wallet ID"
+//	@Param			hold			body		HoldRequest	true	"Hold"
+//	@Param			Idempotency-Key	header		string		false	"Retry-safe request key"
+//	@Param			X-User-ID		header		int			true	"User the request acts for; needs spender or owner"
+//	@Success		201				{object}	Hold
+//	@Failure		400				{object}	Err
+//	@Failure		401				{object}	Err
+//	@Failure		403				{object}	Err
+//	@Failure		404				{object}	Err
+//	@Failure		409				{object}	Err
+//	@Failure		422				{object}	Err
+//	@Failure		500				{object}	Err
+//	@Router			/api/v1/wallets/:id/holds [post]
+func (h *Handler) PlaceHoldHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	var req HoldRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := req.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := h.authorize(c, id, RoleSpender); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	w, err := h.store.WalletByID(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	if req.Amount, err = req.Amount.WithCurrency(w.Currency); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	hold, err := h.store.PlaceHold(id, req)
+	if err != nil {
+		return c.JSON(errorStatus(err), errBody(err))
+	}
+	return c.JSON(http.StatusCreated, hold)
+}
+
+// HoldsHandler
+//
+//	@Summary		Get wallet holds
+//	@Description	Get all holds placed on a wallet, newest first
+//	@Tags			holds
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Wallet ID"
+//	@Success		200	{array}		Hold
+//	@Failure		400	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/wallets/:id/holds [get]
+func (h *Handler) HoldsHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	holds, err := h.store.Holds(id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, holds)
+}
+
+// authorizeHold fails unless the request's caller may spend from the
+// wallet the hold is on.
+func (h *Handler) authorizeHold(c echo.Context, id int) error {
+	hold, err := h.store.HoldByID(id)
+	if err != nil {
+		return err
+	}
+	return h.authorize(c, hold.WalletID, RoleSpender)
+}
+
+// CaptureHoldHandler
+//
+//	@Summary		Capture hold
+//	@Description	Debit all or part of a held amount from the wallet and release the rest
+//	@Tags			holds
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int				true	"Hold ID"
+//	@Param			capture			body		CaptureRequest	false	"Capture"
+//	@Param			Idempotency-Key	header		string			false	"Retry-safe request key"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; needs spender or owner"
+//	@Success		200				{object}	Hold
+//	@Failure		400				{object}	Err
+//	@Failure		401				{object}	Err
+//	@Failure		403				{object}	Err
+//	@Failure		404				{object}	Err
+//	@Failure		409				{object}	Err
+//	@Failure		422				{object}	Err
+//	@Failure		500				{object}	Err
+//	@Router			/api/v1/holds/:id/capture [post]
+func (h *Handler) CaptureHoldHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	var req CaptureRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := h.authorizeHold(c, id); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	hold, err := h.store.CaptureHold(id, req.Amount)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, hold)
+}
+
+// ReleaseHoldHandler
+//
+//	@Summary		Release hold
+//	@Description	Release a hold so its amount becomes available again
+//	@Tags			holds
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Hold ID"
+//	@Param			Idempotency-Key	header		string	false	"Retry-safe request key"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; needs spender or owner"
+//	@Success		200	{object}	Hold
+//	@Failure		400	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		409	{object}	Err
+//	@Failure		422	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/holds/:id/release [post]
+func (h *Handler) ReleaseHoldHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := h.authorizeHold(c, id); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	hold, err := h.store.ReleaseHold(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, hold)
+}
@@ -0,0 +1,216 @@
+package wallet
+
+import (
+	"errors"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Day-count conventions, which decide the number of days in the year that
+// an annual rate is divided by for one day's interest.
+const (
+	DayCountActual365    = "ACT/365"
+	DayCountActual360    = "ACT/360"
+	DayCountActualActual = "ACT/ACT"
+)
+
+var ErrInterestProductNotFound = errors.New("interest product not found")
+
+// InterestProduct is a savings rate. AnnualRate is a decimal fraction, e.g.
+// "0.015" for 1.5% a year.
+type InterestProduct struct {
+	ID         int       `json:"id" example:"1"`
+	Name       string    `json:"name" example:"Standard Savings"`
+	AnnualRate string    `json:"annual_rate" example:"0.015"`
+	DayCount   string    `json:"day_count" example:"ACT/365"`
+	CreatedAt  time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
+
+func (p InterestProduct) Validate() error {
+	if p.Name == "" || len(p.Name) > 255 {
+		return errors.New("name is required and must be at most 255 characters")
+	}
+	if err := validateRate("annual_rate", p.AnnualRate); err != nil {
+		return err
+	}
+	switch p.DayCount {
+	case DayCountActual365, DayCountActual360, DayCountActualActual:
+		return nil
+	}
+	return errors.New("day_count must be one of ACT/365, ACT/360 or ACT/ACT")
+}
+
+// rate returns the annual rate, which Validate has checked.
+func (p InterestProduct) rate() *big.Rat {
+	r, _ := new(big.Rat).SetString(p.AnnualRate)
+	return r
+}
+
+// yearDays is the day count's number of days in the year containing day.
+func (p InterestProduct) yearDays(day time.Time) int64 {
+	switch p.DayCount {
+	case DayCountActual360:
+		return 360
+	case DayCountActualActual:
+		start := time.Date(day.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		return int64(start.AddDate(1, 0, 0).Sub(start) / (24 * time.Hour))
+	}
+	return 365
+}
+
+// accrue returns one day's interest on balance, exact to 18 decimal places.
+// Only money in the wallet earns interest; a negative balance accrues none.
+func (p InterestProduct) accrue(balance Money, day time.Time) (Money, error) {
+	if !balance.IsPositive() {
+		return NewMoney(0, balance.Currency()), nil
+	}
+	x := new(big.Rat).Mul(balance.Rat(), p.rate())
+	x.Quo(x, new(big.Rat).SetInt64(p.yearDays(day)))
+	x.Mul(x, new(big.Rat).SetInt(pow10(maxDigits)))
+	return fromScaled(roundRat(x, RoundHalfEven), balance.Currency())
+}
+
+// Accrual is one day's interest on a wallet. Amount is not rounded to the
+// currency; accruals are summed and rounded when they are capitalized.
+type Accrual struct {
+	WalletID      int        `json:"wallet_id" example:"1"`
+	Date          time.Time  `json:"date" example:"2024-03-25T00:00:00Z"`
+	ProductID     int        `json:"product_id" example:"1"`
+	Balance       Money      `json:"balance" swaggertype:"number" example:"1000.00"`
+	AnnualRate    string     `json:"annual_rate" example:"0.015"`
+	Amount        Money      `json:"amount" swaggertype:"number" example:"0.041095890410958904"`
+	CapitalizedAt *time.Time `json:"capitalized_at,omitempty"`
+}
+
+// InterestAccount is a wallet earning interest, with the last day it has
+// accrued for, or the zero time if it has not accrued yet.
+type InterestAccount struct {
+	Wallet      Wallet
+	Product     InterestProduct
+	LastAccrual time.Time
+}
+
+// AccruedInterest previews the interest a wallet has earned but not yet
+// been paid. Accrued is what capitalizing it now would pay; Exact is the
+// unrounded sum.
+type AccruedInterest struct {
+	WalletID int        `json:"wallet_id" example:"1"`
+	Currency string     `json:"currency" example:"THB"`
+	Days     int        `json:"days" example:"24"`
+	From     *time.Time `json:"from,omitempty" example:"2024-03-01T00:00:00Z"`
+	Through  *time.Time `json:"through,omitempty" example:"2024-03-24T00:00:00Z"`
+	Accrued  Money      `json:"accrued" swaggertype:"number" example:"0.99"`
+	Exact    Money      `json:"exact" swaggertype:"number" example:"0.986301369863013696"`
+}
+
+// sumAccruals adds up accruals and rounds the total to the currency.
+func sumAccruals(currency string, accruals []Accrual) (exact, paid Money, err error) {
+	exact = NewMoney(0, currency)
+	for _, a := range accruals {
+		if exact, err = exact.Add(a.Amount); err != nil {
+			return Money{}, Money{}, err
+		}
+	}
+	paid, err = exact.MulRat(big.NewRat(1, 1), RoundHalfEven)
+	return exact, paid, err
+}
+
+// validateInterest allows only Savings wallets to earn interest.
+func (w Wallet) validateInterest() error {
+	if w.InterestProductID != nil && w.WalletType != WalletTypeSavings {
+		return errors.New("interest_product_id is only allowed on Savings wallets")
+	}
+	return nil
+}
+
+// CreateInterestProductHandler
+//
+//	@Summary		Create interest product
+//	@Description	Create a savings rate that Savings wallets can earn
+//	@Tags			interest
+//	@Accept			json
+//	@Produce		json
+//	@Param			product	body		InterestProduct	true	"Interest product"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; must be an admin"
+//	@Success		201		{object}	InterestProduct
+//	@Failure		400		{object}	Err
+//	@Failure		401		{object}	Err
+//	@Failure		403		{object}	Err
+//	@Failure		500		{object}	Err
+//	@Router			/api/v1/interest-products [post]
+func (h *Handler) CreateInterestProductHandler(c echo.Context) error {
+	if err := h.authorizeAdmin(c); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	var p InterestProduct
+	if err := c.Bind(&p); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if p.DayCount == "" {
+		p.DayCount = DayCountActual365
+	}
+	if err := p.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	p, err := h.store.CreateInterestProduct(p)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusCreated, p)
+}
+
+// InterestProductsHandler
+//
+//	@Summary		Get interest products
+//	@Description	Get all savings rates
+//	@Tags			interest
+//	@Produce		json
+//	@Success		200	{array}		InterestProduct
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/interest-products [get]
+func (h *Handler) InterestProductsHandler(c echo.Context) error {
+	products, err := h.store.InterestProducts()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, products)
+}
+
+// AccruedInterestHandler
+//
+//	@Summary		Preview accrued interest
+//	@Description	Get the interest a wallet has accrued since it was last paid, which is capitalized at the start of next month
+//	@Tags			interest
+//	@Produce		json
+//	@Param			id	path		int	true	"Wallet ID"
+//	@Success		200	{object}	AccruedInterest
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/wallets/:id/interest [get]
+func (h *Handler) AccruedInterestHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	w, err := h.store.WalletByID(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	accruals, err := h.store.UnpaidAccruals(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	preview := AccruedInterest{WalletID: id, Currency: w.Currency, Days: len(accruals)}
+	if preview.Exact, preview.Accrued, err = sumAccruals(w.Currency, accruals); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	if len(accruals) > 0 {
+		preview.From, preview.Through = &accruals[0].Date, &accruals[len(accruals)-1].Date
+	}
+	return c.JSON(http.StatusOK, preview)
+}
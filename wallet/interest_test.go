@@ -0,0 +1,197 @@
+//go:build unit
+
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestInterestProductAccrue(t *testing.T) {
+	day := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		dayCount string
+		balance  Money
+		want     string
+	}{
+		{"ACT/365 should divide the rate by 365", DayCountActual365, MustParseMoney("1000.00", "THB"), "0.041095890410958904"},
+		{"ACT/360 should divide the rate by 360", DayCountActual360, MustParseMoney("1000.00", "THB"), "0.041666666666666667"},
+		{"ACT/ACT in a leap year should divide the rate by 366", DayCountActualActual, MustParseMoney("1000.00", "THB"), "0.040983606557377049"},
+		{"negative balance should accrue nothing", DayCountActual365, MustParseMoney("-1000.00", "THB"), "0"},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name, func(t *testing.T) {
+			p := InterestProduct{AnnualRate: "0.015", DayCount: tt.dayCount}
+
+			got, err := p.accrue(tt.balance, day)
+
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if want, _ := ParseFractionalMoney(tt.want, "THB"); got != want {
+				t.Errorf("expected %v but got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestInterestProducts(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"product without day count", `{"name": "Saver", "annual_rate": "0.015"}`, http.StatusCreated},
+		{"rate of 100%", `{"name": "Saver", "annual_rate": "1"}`, http.StatusBadRequest},
+		{"negative rate", `{"name": "Saver", "annual_rate": "-0.01"}`, http.StatusBadRequest},
+		{"rate finer than 6 decimal places", `{"name": "Saver", "annual_rate": "0.0150001"}`, http.StatusBadRequest},
+		{"unknown day count", `{"name": "Saver", "annual_rate": "0.015", "day_count": "30/360"}`, http.StatusBadRequest},
+	}
+
+	t.Run("given user who is not an admin should return 403", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "Saver", "annual_rate": "0.5"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "2")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/interest-products")
+
+		New(StubWallet{}, WithAdmins(1)).CreateInterestProductHandler(c)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rec.Code)
+		}
+	})
+
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(HeaderUserID, "1")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/interest-products")
+
+			p := New(StubWallet{}, WithAdmins(1))
+
+			p.CreateInterestProductHandler(c)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status code %d but got %d", tt.want, rec.Code)
+			}
+		})
+	}
+
+	t.Run("given interest product on credit card should return 400", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"user_id": 1, "wallet_name": "Card", "wallet_type": "Credit Card", "interest_product_id": 1}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets")
+
+		p := New(StubWallet{})
+
+		p.CreateWalletHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestInterestAccrual(t *testing.T) {
+	product := 1
+	savings := Wallet{ID: 1, WalletType: WalletTypeSavings, Currency: "THB", Balance: MustParseMoney("1000.00", "THB"),
+		InterestProductID: &product, CreatedAt: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	products := []InterestProduct{{ID: 1, AnnualRate: "0.015", DayCount: DayCountActual365}}
+
+	t.Run("given month has turned should capitalize its accruals once", func(t *testing.T) {
+		runs := &interestRuns{paid: map[int][]Money{}}
+		store := StubWallet{wallet: []Wallet{savings}, products: products, interest: runs}
+		a := NewInterestAccrual(New(store))
+		now := time.Date(2024, time.March, 31, 1, 0, 0, 0, time.UTC)
+		a.now = func() time.Time { return now }
+
+		if err := a.RunDue(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		now = time.Date(2024, time.April, 2, 1, 0, 0, 0, time.UTC)
+		for i := 0; i < 2; i++ {
+			if err := a.RunDue(); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+		}
+
+		if len(runs.accruals) != 3 {
+			t.Fatalf("expected 3 days accrued but got %d", len(runs.accruals))
+		}
+		// 30 and 31 March at 0.041095890410958904 a day.
+		if got, want := runs.paid[1], []Money{MustParseMoney("0.08", "THB")}; len(got) != 1 || got[0] != want[0] {
+			t.Errorf("expected interest paid %v but got %v", want, got)
+		}
+		if runs.accruals[2].CapitalizedAt != nil {
+			t.Errorf("expected 1 April to stay unpaid")
+		}
+	})
+
+	t.Run("given one wallet cannot accrue should still pay the others", func(t *testing.T) {
+		other := 2
+		broken := savings
+		broken.ID = 2
+		runs := &interestRuns{paid: map[int][]Money{}, fail: other}
+		for _, id := range []int{1, other} {
+			runs.accruals = append(runs.accruals, Accrual{WalletID: id, Date: time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC), Amount: MustParseMoney("0.04", "THB")})
+		}
+		store := StubWallet{wallet: []Wallet{broken, savings}, products: products, interest: runs}
+		a := NewInterestAccrual(New(store))
+		a.now = func() time.Time { return time.Date(2024, time.April, 2, 1, 0, 0, 0, time.UTC) }
+
+		if err := a.RunDue(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		if len(runs.paid[1]) != 1 || len(runs.paid[other]) != 0 {
+			t.Errorf("expected only wallet 1 paid but got %v", runs.paid)
+		}
+	})
+
+	t.Run("given accrued interest should preview it rounded", func(t *testing.T) {
+		runs := &interestRuns{paid: map[int][]Money{}}
+		for d := 1; d <= 3; d++ {
+			amount, _ := ParseFractionalMoney("0.041095890410958904", "THB")
+			runs.accruals = append(runs.accruals, Accrual{WalletID: 1, Date: time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC), Amount: amount})
+		}
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/interest")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		p := New(StubWallet{wallet: []Wallet{savings}, products: products, interest: runs})
+
+		p.AccruedInterestHandler(c)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rec.Code)
+		}
+		var got AccruedInterest
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if got.Days != 3 || got.Accrued != MustParseMoney("0.12", "THB") {
+			t.Errorf("expected 3 days and 0.12 but got %d days and %v", got.Days, got.Accrued)
+		}
+	})
+}
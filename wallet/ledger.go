@@ -0,0 +1,110 @@
+package wallet
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// Ledger accounts. Every line of a journal entry books to one of these;
+// AccountWallet lines also carry the wallet they belong to.
+const (
+	AccountWallet = "wallet"
+	AccountEquity = "equity"
+	// AccountFX takes both sides of a currency conversion so that each
+	// currency in a journal entry balances on its own.
+	AccountFX = "fx"
+	// AccountExternal is money entering or leaving the system through
+	// deposits and withdrawals.
+	AccountExternal = "external"
+	// AccountInterest earns the interest charged on credit card wallets.
+	AccountInterest = "interest"
+	// AccountSuspense takes the other side of corrections that bring a
+	// wallet's ledger in line with its stored balance, until someone finds
+	// where the difference came from.
+	AccountSuspense = "suspense"
+)
+
+var ErrUnbalancedJournal = errors.New("journal entry lines must sum to zero")
+
+// JournalEntry is an immutable record of why balances changed. Its lines are
+// double-entry: debits are negative, credits positive and together they sum
+// to zero in every currency.
+type JournalEntry struct {
+	ID          int          `json:"id" example:"1"`
+	Type        string       `json:"type" example:"adjustment"`
+	Reference   string       `json:"reference,omitempty" example:"INV-2024-0001"`
+	Description string       `json:"description" example:"Transfer from wallet 1 to wallet 4"`
+	Lines       []LedgerLine `json:"lines"`
+	// ReversalOf links a reversal to the entry it undoes. Only reversals
+	// booked through ReverseTransaction set it.
+	ReversalOf *int      `json:"reversal_of,omitempty" example:"42"`
+	CreatedAt  time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
+
+type LedgerLine struct {
+	Account  string `json:"account" example:"wallet"`
+	WalletID int    `json:"wallet_id,omitempty" example:"1"`
+	Amount   Money  `json:"amount" swaggertype:"number" example:"-100.00"`
+	Currency string `json:"currency" example:"THB"`
+}
+
+// WalletLine books amount to a wallet.
+func WalletLine(walletID int, amount Money) LedgerLine {
+	return LedgerLine{Account: AccountWallet, WalletID: walletID, Amount: amount, Currency: amount.Currency()}
+}
+
+// AccountLine books amount to one of the non-wallet accounts.
+func AccountLine(account string, amount Money) LedgerLine {
+	return LedgerLine{Account: account, Amount: amount, Currency: amount.Currency()}
+}
+
+// WalletIDs returns the wallets j books to, each once.
+func (j JournalEntry) WalletIDs() []int {
+	var ids []int
+	for _, l := range j.Lines {
+		if l.Account == AccountWallet && !slices.Contains(ids, l.WalletID) {
+			ids = append(ids, l.WalletID)
+		}
+	}
+	return ids
+}
+
+func (j JournalEntry) Validate() error {
+	if j.Type == "" {
+		return errors.New("type is required")
+	}
+	if j.Description == "" {
+		return errors.New("description is required")
+	}
+	if len(j.Lines) < 2 {
+		return errors.New("journal entry needs at least two lines")
+	}
+	sums := map[string]Money{}
+	for _, l := range j.Lines {
+		if l.Account == "" {
+			return errors.New("account is required on every line")
+		}
+		if (l.Account == AccountWallet) != (l.WalletID != 0) {
+			return errors.New("wallet_id must be set exactly on wallet lines")
+		}
+		if l.Amount.IsZero() {
+			return errors.New("line amount must not be zero")
+		}
+		currency := l.Amount.Currency()
+		sum, ok := sums[currency]
+		if !ok {
+			sum = NewMoney(0, currency)
+		}
+		var err error
+		if sums[currency], err = sum.Add(l.Amount); err != nil {
+			return err
+		}
+	}
+	for _, sum := range sums {
+		if !sum.IsZero() {
+			return ErrUnbalancedJournal
+		}
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Spending limit periods. Days and months start at midnight UTC.
+const (
+	LimitPerTransaction = "per_transaction"
+	LimitDaily          = "daily"
+	LimitMonthly        = "monthly"
+)
+
+var ErrLimitExceeded = errors.New("spending limit exceeded")
+
+// SpendingLimits cap the money going out of a wallet. A nil limit is not
+// enforced.
+type SpendingLimits struct {
+	PerTransaction *Money `json:"per_transaction,omitempty" swaggertype:"number" example:"5000.00"`
+	Daily          *Money `json:"daily,omitempty" swaggertype:"number" example:"20000.00"`
+	Monthly        *Money `json:"monthly,omitempty" swaggertype:"number" example:"100000.00"`
+}
+
+// IsSet reports whether any limit is set.
+func (l SpendingLimits) IsSet() bool {
+	return l.PerTransaction != nil || l.Daily != nil || l.Monthly != nil
+}
+
+// WithCurrency prices the limits in a wallet's currency and checks each is
+// positive.
+func (l SpendingLimits) WithCurrency(currency string) (SpendingLimits, error) {
+	var priced SpendingLimits
+	for _, f := range []struct {
+		name string
+		from *Money
+		to   **Money
+	}{
+		{LimitPerTransaction, l.PerTransaction, &priced.PerTransaction},
+		{LimitDaily, l.Daily, &priced.Daily},
+		{LimitMonthly, l.Monthly, &priced.Monthly},
+	} {
+		if f.from == nil {
+			continue
+		}
+		m, err := f.from.WithCurrency(currency)
+		if err != nil {
+			return SpendingLimits{}, err
+		}
+		if !m.IsPositive() {
+			return SpendingLimits{}, fmt.Errorf("%s limit must be positive", f.name)
+		}
+		*f.to = &m
+	}
+	return priced, nil
+}
+
+// SpendingTypes are the entry types whose debits count toward a wallet's
+// spending limits, fees on them included. Interest charges do not.
+var SpendingTypes = []string{TransactionWithdrawal, TransactionTransfer, TransactionCapture, TransactionFee}
+
+// IsSpending reports whether entries of type kind count toward limits.
+func IsSpending(kind string) bool {
+	for _, t := range SpendingTypes {
+		if t == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Spending is what a wallet has spent in the current day and month,
+// including money reserved by active holds.
+type Spending struct {
+	Today     Money
+	ThisMonth Money
+}
+
+// LimitPeriods returns the start of the day and month now falls in.
+func LimitPeriods(now time.Time) (day, month time.Time) {
+	now = now.UTC()
+	day = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return day, month
+}
+
+// Allowance is how much of one limit is left to spend.
+type Allowance struct {
+	Period    string     `json:"period" example:"daily"`
+	Limit     Money      `json:"limit" swaggertype:"number" example:"20000.00"`
+	Spent     Money      `json:"spent" swaggertype:"number" example:"15000.00"`
+	Remaining Money      `json:"remaining" swaggertype:"number" example:"5000.00"`
+	ResetsAt  *time.Time `json:"resets_at,omitempty" example:"2024-03-26T00:00:00Z"`
+}
+
+// Allowances returns what is left of each limit set, given spent so far at
+// now.
+func (l SpendingLimits) Allowances(spent Spending, now time.Time) ([]Allowance, error) {
+	day, month := LimitPeriods(now)
+	nextDay, nextMonth := day.AddDate(0, 0, 1), month.AddDate(0, 1, 0)
+	allowances := []Allowance{}
+	for _, p := range []struct {
+		period string
+		limit  *Money
+		spent  Money
+		resets *time.Time
+	}{
+		{LimitPerTransaction, l.PerTransaction, Money{}, nil},
+		{LimitDaily, l.Daily, spent.Today, &nextDay},
+		{LimitMonthly, l.Monthly, spent.ThisMonth, &nextMonth},
+	} {
+		if p.limit == nil {
+			continue
+		}
+		a := Allowance{Period: p.period, Limit: *p.limit, Spent: NewMoney(0, p.limit.Currency()), Remaining: *p.limit, ResetsAt: p.resets}
+		if p.period != LimitPerTransaction {
+			a.Spent = p.spent
+			remaining, err := p.limit.Sub(p.spent)
+			if err != nil {
+				return nil, err
+			}
+			if remaining.IsNegative() {
+				remaining = NewMoney(0, remaining.Currency())
+			}
+			a.Remaining = remaining
+		}
+		allowances = append(allowances, a)
+	}
+	return allowances, nil
+}
+
+// Check fails with a LimitError if spending amount on top of spent would go
+// over a limit.
+func (l SpendingLimits) Check(amount Money, spent Spending, now time.Time) error {
+	allowances, err := l.Allowances(spent, now)
+	if err != nil {
+		return err
+	}
+	for _, a := range allowances {
+		if amount.Cmp(a.Remaining) > 0 {
+			return &LimitError{Allowance: a}
+		}
+	}
+	return nil
+}
+
+// LimitError is a debit refused by a spending limit, with what is left of
+// that limit.
+type LimitError struct {
+	Allowance Allowance
+}
+
+func (e *LimitError) Error() string {
+	a := e.Allowance
+	msg := fmt.Sprintf("%s limit of %s exceeded: %s remaining", a.Period, a.Limit, a.Remaining)
+	if a.ResetsAt != nil {
+		msg += " until " + a.ResetsAt.Format(time.RFC3339)
+	}
+	return msg
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// WalletLimits is a wallet's spending limits with what is left of them.
+type WalletLimits struct {
+	WalletID   int            `json:"wallet_id" example:"1"`
+	Currency   string         `json:"currency" example:"THB"`
+	Limits     SpendingLimits `json:"limits"`
+	Allowances []Allowance    `json:"allowances"`
+}
+
+func (h *Handler) walletLimits(w Wallet) (WalletLimits, error) {
+	now := time.Now()
+	spent, err := h.store.Spending(w.ID, now)
+	if err != nil {
+		return WalletLimits{}, err
+	}
+	allowances, err := w.Limits.Allowances(spent, now)
+	if err != nil {
+		return WalletLimits{}, err
+	}
+	return WalletLimits{WalletID: w.ID, Currency: w.Currency, Limits: w.Limits, Allowances: allowances}, nil
+}
+
+// WalletLimitsHandler
+//
+//	@Summary		Get spending limits
+//	@Description	Get a wallet's spending limits and how much of each is left today and this month
+//	@Tags			limits
+//	@Produce		json
+//	@Param			id	path		int	true	"Wallet ID"
+//	@Success		200	{object}	WalletLimits
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/wallets/:id/limits [get]
+func (h *Handler) WalletLimitsHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	w, err := h.store.WalletByID(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	limits, err := h.walletLimits(w)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, limits)
+}
+
+// SetWalletLimitsHandler
+//
+//	@Summary		Set spending limits
+//	@Description	Replace a wallet's limits on money going out per transaction, per day and per month. A limit left out is removed. Withdrawals, transfers and holds over a limit are refused.
+//	@Tags			limits
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Wallet ID"
+//	@Param			limits	body		SpendingLimits	true	"Spending limits"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; needs owner"
+//	@Success		200		{object}	WalletLimits
+//	@Failure		400		{object}	Err
+//	@Failure		401		{object}	Err
+//	@Failure		403		{object}	Err
+//	@Failure		404		{object}	Err
+//	@Failure		500		{object}	Err
+//	@Router			/api/v1/wallets/:id/limits [put]
+func (h *Handler) SetWalletLimitsHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	var limits SpendingLimits
+	if err := c.Bind(&limits); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := h.authorize(c, id, RoleOwner); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	w, err := h.store.WalletByID(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	if limits, err = limits.WithCurrency(w.Currency); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if w, err = h.store.SetLimits(id, limits); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	wl, err := h.walletLimits(w)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, wl)
+}
@@ -0,0 +1,168 @@
+//go:build unit
+
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func thb(s string) *Money {
+	m := MustParseMoney(s, "THB")
+	return &m
+}
+
+func TestSpendingLimitsCheck(t *testing.T) {
+	now := time.Date(2024, time.March, 25, 15, 0, 0, 0, time.UTC)
+	limits := SpendingLimits{PerTransaction: thb("5000.00"), Daily: thb("20000.00"), Monthly: thb("100000.00")}
+	tests := []struct {
+		name   string
+		amount string
+		spent  Spending
+		period string
+	}{
+		{"amount within every limit should pass", "5000.00", Spending{Today: *thb("15000.00"), ThisMonth: *thb("95000.00")}, ""},
+		{"amount over per transaction limit should fail", "5000.01", Spending{Today: *thb("0"), ThisMonth: *thb("0")}, LimitPerTransaction},
+		{"amount over what is left today should fail", "1000.00", Spending{Today: *thb("19500.00"), ThisMonth: *thb("19500.00")}, LimitDaily},
+		{"amount over what is left this month should fail", "1000.00", Spending{Today: *thb("0"), ThisMonth: *thb("99500.00")}, LimitMonthly},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name, func(t *testing.T) {
+			err := limits.Check(MustParseMoney(tt.amount, "THB"), tt.spent, now)
+
+			var limit *LimitError
+			switch {
+			case tt.period == "" && err != nil:
+				t.Errorf("unexpected error %v", err)
+			case tt.period != "" && (!errors.As(err, &limit) || limit.Allowance.Period != tt.period):
+				t.Errorf("expected %s limit exceeded but got %v", tt.period, err)
+			}
+		})
+	}
+
+	t.Run("given daily limit exceeded should say what is left until midnight", func(t *testing.T) {
+		err := limits.Check(*thb("1000.00"), Spending{Today: *thb("19500.00"), ThisMonth: *thb("19500.00")}, now)
+
+		want := "daily limit of 20000.00 exceeded: 500.00 remaining until 2024-03-26T00:00:00Z"
+		if err == nil || err.Error() != want || !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("expected %q but got %v", want, err)
+		}
+	})
+
+	t.Run("given no limits should pass anything", func(t *testing.T) {
+		if err := (SpendingLimits{}).Check(*thb("1000000.00"), Spending{}, now); err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+}
+
+func TestIsSpending(t *testing.T) {
+	tests := []struct {
+		kind string
+		want bool
+	}{
+		{TransactionWithdrawal, true},
+		{TransactionTransfer, true},
+		{TransactionCapture, true},
+		{TransactionFee, true},
+		{TransactionInterest, false},
+		{TransactionAdjustment, false},
+		{TransactionDeposit, false},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.kind+" entry", func(t *testing.T) {
+			if got := IsSpending(tt.kind); got != tt.want {
+				t.Errorf("expected IsSpending(%q) to be %v but got %v", tt.kind, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWalletLimits(t *testing.T) {
+	wallets := []Wallet{{ID: 1, UserID: 1, WalletName: "John's Savings", WalletType: WalletTypeSavings, Currency: "THB",
+		Balance: *thb("1000.00"), Limits: SpendingLimits{Daily: thb("500.00")}}}
+
+	call := func(method, body string, store StubWallet) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(method, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/limits")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		if method == http.MethodPut {
+			New(store).SetWalletLimitsHandler(c)
+		} else {
+			New(store).WalletLimitsHandler(c)
+		}
+		return rec
+	}
+
+	t.Run("given spending today should return what is left", func(t *testing.T) {
+		rec := call(http.MethodGet, "", StubWallet{wallet: wallets, spent: Spending{Today: *thb("120.00"), ThisMonth: *thb("300.00")}})
+
+		var got WalletLimits
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if len(got.Allowances) != 1 || got.Allowances[0].Remaining != *thb("380.00") {
+			t.Errorf("expected 380.00 left today but got %+v", got.Allowances)
+		}
+	})
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"limits in wallet currency", `{"per_transaction": 100, "monthly": 5000}`, http.StatusOK},
+		{"no limits", `{}`, http.StatusOK},
+		{"zero limit", `{"daily": 0}`, http.StatusBadRequest},
+		{"negative limit", `{"monthly": -1}`, http.StatusBadRequest},
+		{"limit finer than the currency", `{"daily": 10.001}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			if rec := call(http.MethodPut, tt.body, StubWallet{wallet: wallets, spent: Spending{Today: *thb("0"), ThisMonth: *thb("0")}}); rec.Code != tt.want {
+				t.Errorf("expected status code %d but got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	t.Run("given withdrawal over a limit should return 422 with allowance", func(t *testing.T) {
+		resets := time.Date(2024, time.March, 26, 0, 0, 0, 0, time.UTC)
+		refused := &LimitError{Allowance: Allowance{Period: LimitDaily, Limit: *thb("500.00"), Spent: *thb("450.00"), Remaining: *thb("50.00"), ResetsAt: &resets}}
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 100}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/withdrawals")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		New(StubWallet{wallet: wallets, err: refused}).WithdrawalHandler(c)
+
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected status code %d but got %d", http.StatusUnprocessableEntity, rec.Code)
+		}
+		var got Err
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if got.Allowance == nil || got.Allowance.Remaining != *thb("50.00") {
+			t.Errorf("expected 50.00 remaining but got %+v", got)
+		}
+	})
+}
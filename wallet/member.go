@@ -0,0 +1,249 @@
+package wallet
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Wallet member roles, from most to least trusted. Owners may do anything
+// with a wallet, including managing its members; spenders may move money
+// out of it; viewers may only look.
+const (
+	RoleOwner   = "owner"
+	RoleSpender = "spender"
+	RoleViewer  = "viewer"
+)
+
+// HeaderUserID names the user a request acts for. Requests that change a
+// wallet are refused without it.
+const HeaderUserID = "X-User-ID"
+
+var (
+	ErrMemberNotFound = errors.New("user is not a member of the wallet")
+	ErrForbidden      = errors.New("user's role on the wallet does not allow this")
+	ErrPrimaryOwner   = errors.New("the wallet's user_id must remain an owner")
+	ErrCallerID       = errors.New(HeaderUserID + " must be a user ID")
+	ErrCallerRequired = errors.New(HeaderUserID + " is required for this request")
+	// ErrAdminRequired is returned to callers who are not admins on the
+	// endpoints that configure the service or look past users' own data.
+	ErrAdminRequired = errors.New("only admins may do this")
+)
+
+// WithAdmins sets the users allowed to use the admin endpoints: fee rules,
+// interest products, correction tickets and listing deleted wallets.
+// Without it nobody may.
+func WithAdmins(userIDs ...int) Option {
+	return func(h *Handler) {
+		h.admins = map[int]bool{}
+		for _, id := range userIDs {
+			h.admins[id] = true
+		}
+	}
+}
+
+// roleRank orders roles so that each allows what the ones below it do.
+var roleRank = map[string]int{RoleViewer: 1, RoleSpender: 2, RoleOwner: 3}
+
+// RoleAllows reports whether role grants what need does.
+func RoleAllows(role, need string) bool {
+	return roleRank[role] > 0 && roleRank[role] >= roleRank[need]
+}
+
+// Member is a user sharing a wallet. The user named by the wallet's
+// user_id is always one of its owners.
+type Member struct {
+	WalletID  int       `json:"wallet_id" example:"1"`
+	UserID    int       `json:"user_id" example:"2"`
+	User      User      `json:"user"`
+	Role      string    `json:"role" example:"spender"`
+	CreatedAt time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
+
+// MemberRequest gives a user a role on a wallet.
+type MemberRequest struct {
+	Role string `json:"role" example:"spender"`
+}
+
+func (r MemberRequest) Validate() error {
+	if roleRank[r.Role] == 0 {
+		return errors.New("role must be owner, spender or viewer")
+	}
+	return nil
+}
+
+// caller returns the user a request acts for.
+func caller(c echo.Context) (int, error) {
+	s := c.Request().Header.Get(HeaderUserID)
+	if s == "" {
+		return 0, ErrCallerRequired
+	}
+	id, err := strconv.Atoi(s)
+	if err != nil || id <= 0 {
+		return 0, ErrCallerID
+	}
+	return id, nil
+}
+
+// authorize fails with ErrForbidden unless the request's caller has at
+// least role need on the wallet.
+func (h *Handler) authorize(c echo.Context, walletID int, need string) error {
+	userID, err := caller(c)
+	if err != nil {
+		return err
+	}
+	return h.allow(userID, walletID, need)
+}
+
+// authorizeAdmin fails with ErrAdminRequired unless the request's caller is
+// an admin.
+func (h *Handler) authorizeAdmin(c echo.Context) error {
+	userID, err := caller(c)
+	if err != nil {
+		return err
+	}
+	if !h.admins[userID] {
+		return ErrAdminRequired
+	}
+	return nil
+}
+
+// authorizeSelf fails with ErrForbidden unless the request's caller is user
+// id, for changes a user may only make to themselves.
+func authorizeSelf(c echo.Context, id int) error {
+	userID, err := caller(c)
+	if err != nil {
+		return err
+	}
+	if userID != id {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// allow fails with ErrForbidden unless userID has at least role need on the
+// wallet.
+func (h *Handler) allow(userID, walletID int, need string) error {
+	role, err := h.store.Role(walletID, userID)
+	if errors.Is(err, ErrMemberNotFound) {
+		return ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+	if !RoleAllows(role, need) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// MembersHandler
+//
+//	@Summary		Get wallet members
+//	@Description	Get the users sharing a wallet and their roles
+//	@Tags			members
+//	@Produce		json
+//	@Param			id	path		int	true	"Wallet ID"
+//	@Success		200	{array}		Member
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/wallets/:id/members [get]
+func (h *Handler) MembersHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if _, err := h.store.WalletByID(id); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	members, err := h.store.Members(id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, members)
+}
+
+// SetMemberHandler
+//
+//	@Summary		Share wallet
+//	@Description	Give a user a role on a wallet, or change the role they have. Only owners may.
+//	@Tags			members
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int				true	"Wallet ID"
+//	@Param			user_id		path		int				true	"User ID"
+//	@Param			member		body		MemberRequest	true	"Role"
+//	@Param			X-User-ID	header		int				true	"User the request acts for; needs owner"
+//	@Success		200			{object}	Member
+//	@Failure		400			{object}	Err
+//	@Failure		401			{object}	Err
+//	@Failure		403			{object}	Err
+//	@Failure		404			{object}	Err
+//	@Failure		409			{object}	Err
+//	@Failure		422			{object}	Err
+//	@Failure		500			{object}	Err
+//	@Router			/api/v1/wallets/:id/members/:user_id [put]
+func (h *Handler) SetMemberHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	var req MemberRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := req.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := h.authorize(c, id, RoleOwner); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	m, err := h.store.SetMember(id, userID, req.Role)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, m)
+}
+
+// RemoveMemberHandler
+//
+//	@Summary		Stop sharing wallet
+//	@Description	Take a user's role on a wallet away. Only owners may; the wallet's user_id cannot be removed.
+//	@Tags			members
+//	@Produce		json
+//	@Param			id			path	int	true	"Wallet ID"
+//	@Param			user_id		path	int	true	"User ID"
+//	@Param			X-User-ID	header	int	true	"User the request acts for; needs owner"
+//	@Success		204
+//	@Failure		400	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		409	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/wallets/:id/members/:user_id [delete]
+func (h *Handler) RemoveMemberHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := h.authorize(c, id, RoleOwner); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	if err := h.store.RemoveMember(id, userID); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
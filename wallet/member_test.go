@@ -0,0 +1,227 @@
+//go:build unit
+
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestWalletMembers(t *testing.T) {
+	wallets := []Wallet{
+		{ID: 1, UserID: 1, WalletName: "Family Savings", WalletType: WalletTypeSavings, Currency: "THB", Balance: *thb("1000.00"), Available: *thb("1000.00")},
+		{ID: 2, UserID: 2, WalletName: "Jane's Savings", WalletType: WalletTypeSavings, Currency: "THB", Balance: *thb("500.00"), Available: *thb("500.00")},
+	}
+	members := []Member{
+		{WalletID: 1, UserID: 1, Role: RoleOwner},
+		{WalletID: 1, UserID: 2, Role: RoleSpender},
+		{WalletID: 1, UserID: 3, Role: RoleViewer},
+		{WalletID: 2, UserID: 2, Role: RoleOwner},
+	}
+	store := StubWallet{wallet: wallets, members: members, transaction: Transaction{ID: 1, WalletID: 1, Type: TransactionWithdrawal}}
+
+	t.Run("given user with own and shared wallets should list both with role", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/users/:id/wallets")
+		c.SetParamNames("id")
+		c.SetParamValues("2")
+
+		New(store).GetWalletByIDHandler(c)
+
+		var got []Wallet
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if len(got) != 2 || got[0].Role != RoleSpender || got[1].Role != RoleOwner {
+			t.Errorf("expected wallet 1 as spender and 2 as owner but got %+v", got)
+		}
+	})
+
+	withdraw := []struct {
+		name   string
+		caller string
+		want   int
+	}{
+		{"owner", "1", http.StatusCreated},
+		{"spender", "2", http.StatusCreated},
+		{"viewer", "3", http.StatusForbidden},
+		{"non-member", "4", http.StatusForbidden},
+		{"unnamed user", "", http.StatusUnauthorized},
+		{"malformed user", "john", http.StatusBadRequest},
+	}
+	for _, tt := range withdraw {
+		t.Run("given withdrawal by "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 100}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.caller != "" {
+				req.Header.Set(HeaderUserID, tt.caller)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/wallets/:id/withdrawals")
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			New(store).WithdrawalHandler(c)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status code %d but got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	t.Run("given deposit by viewer should return 201", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 100}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "3")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/deposits")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		New(store).DepositHandler(c)
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("expected status code %d but got %d: %s", http.StatusCreated, rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("given transfer out of wallet by viewer should return 403", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"from_wallet_id": 1, "to_wallet_id": 2, "amount": 100}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "3")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		New(store).TransferHandler(c)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d: %s", http.StatusForbidden, rec.Code, rec.Body)
+		}
+	})
+
+	manage := []struct {
+		name    string
+		method  string
+		member  string
+		caller  string
+		body    string
+		handler func(*Handler) echo.HandlerFunc
+		want    int
+	}{
+		{"list of members", http.MethodGet, "", "", "", func(h *Handler) echo.HandlerFunc { return h.MembersHandler }, http.StatusOK},
+		{"owner sharing with new member", http.MethodPut, "4", "1", `{"role": "viewer"}`, func(h *Handler) echo.HandlerFunc { return h.SetMemberHandler }, http.StatusOK},
+		{"owner promoting spender", http.MethodPut, "2", "1", `{"role": "owner"}`, func(h *Handler) echo.HandlerFunc { return h.SetMemberHandler }, http.StatusOK},
+		{"spender sharing wallet", http.MethodPut, "4", "2", `{"role": "viewer"}`, func(h *Handler) echo.HandlerFunc { return h.SetMemberHandler }, http.StatusForbidden},
+		{"unknown role", http.MethodPut, "4", "1", `{"role": "admin"}`, func(h *Handler) echo.HandlerFunc { return h.SetMemberHandler }, http.StatusBadRequest},
+		{"demotion of wallet's user", http.MethodPut, "1", "1", `{"role": "viewer"}`, func(h *Handler) echo.HandlerFunc { return h.SetMemberHandler }, http.StatusConflict},
+		{"owner removing viewer", http.MethodDelete, "3", "1", "", func(h *Handler) echo.HandlerFunc { return h.RemoveMemberHandler }, http.StatusNoContent},
+		{"removal of wallet's user", http.MethodDelete, "1", "1", "", func(h *Handler) echo.HandlerFunc { return h.RemoveMemberHandler }, http.StatusConflict},
+		{"removal of non-member", http.MethodDelete, "4", "1", "", func(h *Handler) echo.HandlerFunc { return h.RemoveMemberHandler }, http.StatusNotFound},
+		{"viewer removing spender", http.MethodDelete, "2", "3", "", func(h *Handler) echo.HandlerFunc { return h.RemoveMemberHandler }, http.StatusForbidden},
+	}
+	for _, tt := range manage {
+		t.Run("given "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.caller != "" {
+				req.Header.Set(HeaderUserID, tt.caller)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/wallets/:id/members/:user_id")
+			c.SetParamNames("id", "user_id")
+			c.SetParamValues("1", tt.member)
+
+			tt.handler(New(store))(c)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status code %d but got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	guarded := []struct {
+		name    string
+		method  string
+		path    string
+		params  []string
+		caller  string
+		body    string
+		handler func(*Handler) echo.HandlerFunc
+		want    int
+	}{
+		{"update of wallet by spender", http.MethodPut, "/api/v1/wallets/:id", []string{"1"}, "2", `{"wallet_name": "Mine now"}`,
+			func(h *Handler) echo.HandlerFunc { return h.UpdateWalletHandler }, http.StatusForbidden},
+		{"update of wallet without user", http.MethodPut, "/api/v1/wallets/:id", []string{"1"}, "", `{"wallet_name": "Mine now"}`,
+			func(h *Handler) echo.HandlerFunc { return h.UpdateWalletHandler }, http.StatusUnauthorized},
+		{"deletion of wallet by spender", http.MethodDelete, "/api/v1/wallets/:id", []string{"1"}, "2", "",
+			func(h *Handler) echo.HandlerFunc { return h.DeleteWalletHandler }, http.StatusForbidden},
+		{"deletion of another user's wallets", http.MethodDelete, "/api/v1/users/:id/wallets", []string{"1"}, "2", "",
+			func(h *Handler) echo.HandlerFunc { return h.DeleteWalletByIDHandler }, http.StatusForbidden},
+		{"freeze by spender", http.MethodPost, "/api/v1/wallets/:id/freeze", []string{"1"}, "2", `{"reason": "Mine now"}`,
+			func(h *Handler) echo.HandlerFunc { return h.FreezeWalletHandler }, http.StatusForbidden},
+		{"lifting of limits by spender", http.MethodPut, "/api/v1/wallets/:id/limits", []string{"1"}, "2", `{}`,
+			func(h *Handler) echo.HandlerFunc { return h.SetWalletLimitsHandler }, http.StatusForbidden},
+		{"capture of hold by viewer", http.MethodPost, "/api/v1/holds/:id/capture", []string{"5"}, "3", `{}`,
+			func(h *Handler) echo.HandlerFunc { return h.CaptureHoldHandler }, http.StatusForbidden},
+		{"release of hold by spender", http.MethodPost, "/api/v1/holds/:id/release", []string{"5"}, "2", "",
+			func(h *Handler) echo.HandlerFunc { return h.ReleaseHoldHandler }, http.StatusOK},
+		{"schedule moved onto another user's wallet", http.MethodPut, "/api/v1/schedules/:id", []string{"9"}, "3",
+			`{"from_wallet_id": 1, "to_wallet_id": 2, "amount": 100, "frequency": "monthly", "start_at": "2099-01-01T09:00:00Z"}`,
+			func(h *Handler) echo.HandlerFunc { return h.UpdateScheduleHandler }, http.StatusForbidden},
+		{"deletion of schedule by viewer", http.MethodDelete, "/api/v1/schedules/:id", []string{"8"}, "3", "",
+			func(h *Handler) echo.HandlerFunc { return h.DeleteScheduleHandler }, http.StatusForbidden},
+		{"reversal of transfer by user of one side", http.MethodPost, "/api/v1/transactions/:id/reversal", []string{"42"}, "1", `{"reason": "Refund"}`,
+			func(h *Handler) echo.HandlerFunc { return h.ReverseTransactionHandler }, http.StatusForbidden},
+		{"reversal of transfer by user of both sides", http.MethodPost, "/api/v1/transactions/:id/reversal", []string{"42"}, "2", `{"reason": "Refund"}`,
+			func(h *Handler) echo.HandlerFunc { return h.ReverseTransactionHandler }, http.StatusCreated},
+	}
+	guardedStore := store
+	guardedStore.hold = Hold{WalletID: 1, Status: HoldActive}
+	guardedStore.schedules = []Schedule{
+		{ID: 8, FromWalletID: 1, ToWalletID: 2},
+		{ID: 9, FromWalletID: 3, ToWalletID: 2},
+	}
+	guardedStore.members = append(slices.Clone(members), Member{WalletID: 3, UserID: 3, Role: RoleOwner})
+	guardedStore.journal = JournalEntry{Type: TransactionTransfer, Lines: []LedgerLine{
+		WalletLine(1, *thb("-100.00")),
+		WalletLine(2, *thb("100.00")),
+	}}
+	for _, tt := range guarded {
+		t.Run("given "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set("If-Match", "*")
+			if tt.caller != "" {
+				req.Header.Set(HeaderUserID, tt.caller)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath(tt.path)
+			c.SetParamNames("id")
+			c.SetParamValues(tt.params...)
+
+			tt.handler(New(guardedStore))(c)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status code %d but got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+}
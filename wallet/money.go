@@ -0,0 +1,336 @@
+package wallet
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// DefaultCurrency is the currency of amounts that do not name one.
+const DefaultCurrency = "THB"
+
+var (
+	ErrOverflow         = errors.New("amount out of range")
+	ErrPrecision        = errors.New("amount has more decimal places than the currency allows")
+	ErrCurrencyMismatch = errors.New("amounts are in different currencies")
+)
+
+// RoundingMode decides how results that fall between two minor units are
+// rounded. Parsing never rounds; only arithmetic that can produce fractions
+// of a minor unit takes a RoundingMode.
+type RoundingMode int
+
+const (
+	// RoundHalfEven rounds to the nearest minor unit and ties to the even
+	// one (banker's rounding). Use it unless a rule says otherwise.
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp rounds to the nearest minor unit and ties away from zero.
+	RoundHalfUp
+	// RoundDown truncates towards zero.
+	RoundDown
+	// RoundUp rounds away from zero.
+	RoundUp
+)
+
+// Money is an exact amount of a currency or crypto asset. The value is held
+// as a 128-bit integer count of 10^-18 units, the finest precision of any
+// supported asset, so amounts of every asset share one representation and
+// compare with ==. The zero value is zero THB.
+type Money struct {
+	hi int64
+	lo uint64
+	// currency is empty for DefaultCurrency so that the zero value and
+	// NewMoney(0, DefaultCurrency) compare equal.
+	currency string
+}
+
+// maxDigits is the finest precision of any asset, and the scale of Money.
+const maxDigits = 18
+
+// maxMagnitude bounds amounts to what the NUMERIC(38, 18) columns hold.
+var maxMagnitude = new(big.Int).Exp(big.NewInt(10), big.NewInt(38), nil)
+
+func exponent(currency string) int {
+	return AssetOf(currency).Digits
+}
+
+// decimalPattern bounds the exponent so that hostile input such as "1e999999999"
+// cannot make big.Rat allocate without limit.
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d{1,3})?$`)
+
+func normalizeCurrency(currency string) string {
+	if currency == "" {
+		return DefaultCurrency
+	}
+	return strings.ToUpper(currency)
+}
+
+func storedCurrency(currency string) string {
+	if currency = normalizeCurrency(currency); currency == DefaultCurrency {
+		return ""
+	}
+	return currency
+}
+
+// NewMoney returns minor units of currency, e.g. NewMoney(1050, "THB") is
+// 10.50 THB and NewMoney(1, "BTC") one satoshi.
+func NewMoney(minor int64, currency string) Money {
+	v := new(big.Int).Mul(big.NewInt(minor), pow10(maxDigits-exponent(currency)))
+	m, _ := fromScaled(v, currency)
+	return m
+}
+
+// ParseMoney parses a decimal string such as "-12.34" exactly. Amounts with
+// more decimal places than the currency's smallest unit are rejected with
+// ErrPrecision rather than rounded.
+func ParseMoney(s string, currency string) (Money, error) {
+	return parseMoney(s, currency, exponent(currency))
+}
+
+// parseMoney parses s allowing at most digits decimal places.
+func parseMoney(s string, currency string, digits int) (Money, error) {
+	s = strings.TrimSpace(s)
+	if !decimalPattern.MatchString(s) {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt(pow10(digits)))
+	if !r.IsInt() {
+		return Money{}, ErrPrecision
+	}
+	return fromMinor(r.Num(), digits, currency)
+}
+
+// ParseFractionalMoney is like ParseMoney but allows up to 18 decimal places
+// whatever the currency. It is meant for amounts kept finer than the
+// currency until they are rounded, such as interest accruals.
+func ParseFractionalMoney(s string, currency string) (Money, error) {
+	return parseMoney(s, currency, maxDigits)
+}
+
+// MustParseMoney is like ParseMoney but panics on error. It is meant for
+// constants and tests.
+func MustParseMoney(s string, currency string) Money {
+	m, err := ParseMoney(s, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// fromMinor returns minor units with the given number of decimal places.
+func fromMinor(minor *big.Int, digits int, currency string) (Money, error) {
+	return fromScaled(new(big.Int).Mul(minor, pow10(maxDigits-digits)), currency)
+}
+
+// fromScaled returns Money for v units of 10^-18.
+func fromScaled(v *big.Int, currency string) (Money, error) {
+	if new(big.Int).Abs(v).Cmp(maxMagnitude) >= 0 {
+		return Money{}, ErrOverflow
+	}
+	// Two's complement of v in 128 bits.
+	u := new(big.Int).Set(v)
+	if u.Sign() < 0 {
+		u.Add(u, new(big.Int).Lsh(big.NewInt(1), 128))
+	}
+	lo := new(big.Int).And(u, new(big.Int).SetUint64(^uint64(0))).Uint64()
+	hi := new(big.Int).Rsh(u, 64).Uint64()
+	return Money{hi: int64(hi), lo: lo, currency: storedCurrency(currency)}, nil
+}
+
+// scaled returns m in units of 10^-18.
+func (m Money) scaled() *big.Int {
+	v := new(big.Int).Lsh(big.NewInt(m.hi), 64)
+	return v.Or(v, new(big.Int).SetUint64(m.lo))
+}
+
+// Minor returns the amount in the currency's smallest unit, e.g. satang or
+// satoshi, rounded towards zero.
+func (m Money) Minor() *big.Int {
+	return new(big.Int).Quo(m.scaled(), pow10(maxDigits-exponent(m.Currency())))
+}
+
+// Currency returns the ISO currency code or asset symbol of m.
+func (m Money) Currency() string { return normalizeCurrency(m.currency) }
+
+func (m Money) IsZero() bool     { return m.hi == 0 && m.lo == 0 }
+func (m Money) IsPositive() bool { return m.hi > 0 || (m.hi == 0 && m.lo > 0) }
+func (m Money) IsNegative() bool { return m.hi < 0 }
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	n, _ := fromScaled(new(big.Int).Neg(m.scaled()), m.currency)
+	return n
+}
+
+// Add returns m + o, failing on overflow or when the currencies differ.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency() != o.Currency() {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return fromScaled(new(big.Int).Add(m.scaled(), o.scaled()), m.Currency())
+}
+
+// Sub returns m - o, failing on overflow or when the currencies differ.
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency() != o.Currency() {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return fromScaled(new(big.Int).Sub(m.scaled(), o.scaled()), m.Currency())
+}
+
+// Cmp compares m and o and returns -1, 0 or +1. Both must be in the same
+// currency.
+func (m Money) Cmp(o Money) int {
+	return m.scaled().Cmp(o.scaled())
+}
+
+// MulRat returns m * r rounded to a whole smallest unit with mode.
+func (m Money) MulRat(r *big.Rat, mode RoundingMode) (Money, error) {
+	digits := exponent(m.Currency())
+	x := new(big.Rat).Mul(m.Rat(), r)
+	x.Mul(x, new(big.Rat).SetInt(pow10(digits)))
+	return fromMinor(roundRat(x, mode), digits, m.Currency())
+}
+
+// WithCurrency returns the same decimal amount labelled with currency. It is
+// used for amounts parsed before their currency was known; it does not
+// convert.
+func (m Money) WithCurrency(currency string) (Money, error) {
+	currency = normalizeCurrency(currency)
+	digits := exponent(currency)
+	x := new(big.Rat).Mul(m.Rat(), new(big.Rat).SetInt(pow10(digits)))
+	if !x.IsInt() {
+		return Money{}, ErrPrecision
+	}
+	return fromMinor(x.Num(), digits, currency)
+}
+
+// Convert returns m in currency, where rate is the number of units of
+// currency bought by one unit of m's currency.
+func (m Money) Convert(rate *big.Rat, currency string, mode RoundingMode) (Money, error) {
+	currency = normalizeCurrency(currency)
+	digits := exponent(currency)
+	x := new(big.Rat).Mul(m.Rat(), rate)
+	x.Mul(x, new(big.Rat).SetInt(pow10(digits)))
+	return fromMinor(roundRat(x, mode), digits, currency)
+}
+
+// Rat returns m in major units, e.g. 10.50 for 1050 satang.
+func (m Money) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(m.scaled(), pow10(maxDigits))
+}
+
+func roundRat(x *big.Rat, mode RoundingMode) *big.Int {
+	q, rem := new(big.Int).QuoRem(x.Num(), x.Denom(), new(big.Int))
+	if rem.Sign() == 0 || mode == RoundDown {
+		return q
+	}
+	away := new(big.Int).Add(q, big.NewInt(int64(x.Sign())))
+	if mode == RoundUp {
+		return away
+	}
+	half := new(big.Int).Abs(rem)
+	half.Lsh(half, 1)
+	switch half.Cmp(x.Denom()) {
+	case 1:
+		return away
+	case -1:
+		return q
+	}
+	if mode == RoundHalfUp || q.Bit(0) == 1 {
+		return away
+	}
+	return q
+}
+
+// String formats m with exactly the currency's number of decimal places.
+// Digits beyond them, which only amounts not yet labelled with their
+// currency can have, are kept rather than dropped.
+func (m Money) String() string {
+	v := m.scaled()
+	s := new(big.Int).Abs(v).String()
+	if len(s) <= maxDigits {
+		s = strings.Repeat("0", maxDigits-len(s)+1) + s
+	}
+	whole, frac := s[:len(s)-maxDigits], strings.TrimRight(s[len(s)-maxDigits:], "0")
+	if digits := exponent(m.Currency()); len(frac) < digits {
+		frac += strings.Repeat("0", digits-len(frac))
+	}
+	s = whole
+	if frac != "" {
+		s += "." + frac
+	}
+	if v.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// MarshalJSON writes fiat amounts as a JSON number with the exact decimal
+// digits, e.g. 0.30 rather than 0.30000000000000004. Crypto amounts are
+// written as strings, which clients cannot mistake for a float64.
+func (m Money) MarshalJSON() ([]byte, error) {
+	if AssetOf(m.Currency()).Crypto {
+		return []byte(`"` + m.String() + `"`), nil
+	}
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a decimal string. The digits are
+// parsed exactly and never go through float64. A request body does not know
+// its currency yet, so up to 18 decimal places are accepted here and the
+// amount is checked against its currency by WithCurrency.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	s := strings.Trim(string(b), `"`)
+	digits := exponent(m.Currency())
+	if m.currency == "" {
+		digits = maxDigits
+	}
+	parsed, err := parseMoney(s, m.Currency(), digits)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner for NUMERIC columns.
+func (m *Money) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		s = fmt.Sprint(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	parsed, err := ParseMoney(s, m.currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value implements driver.Valuer. The decimal string is passed to Postgres
+// unchanged so that NUMERIC columns receive the exact amount.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
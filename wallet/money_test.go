@@ -0,0 +1,168 @@
+//go:build unit
+
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestMoney(t *testing.T) {
+	t.Run("given decimal amounts should add exactly", func(t *testing.T) {
+		a := MustParseMoney("0.1", "THB")
+		b := MustParseMoney("0.2", "THB")
+
+		got, err := a.Add(b)
+
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if got != MustParseMoney("0.30", "THB") {
+			t.Errorf("expected 0.30 but got %s", got)
+		}
+	})
+
+	t.Run("given more decimals than the currency allows should return ErrPrecision", func(t *testing.T) {
+		_, err := ParseMoney("1.005", "THB")
+
+		if !errors.Is(err, ErrPrecision) {
+			t.Errorf("expected ErrPrecision but got %v", err)
+		}
+	})
+
+	t.Run("given amount beyond NUMERIC(38, 18) should return ErrOverflow", func(t *testing.T) {
+		_, err := ParseMoney("100000000000000000000", "THB")
+
+		if !errors.Is(err, ErrOverflow) {
+			t.Errorf("expected ErrOverflow but got %v", err)
+		}
+	})
+
+	t.Run("given sum beyond range should return ErrOverflow", func(t *testing.T) {
+		max := MustParseMoney("99999999999999999999.99", "THB")
+
+		_, err := max.Add(NewMoney(1, "THB"))
+
+		if !errors.Is(err, ErrOverflow) {
+			t.Errorf("expected ErrOverflow but got %v", err)
+		}
+	})
+
+	t.Run("given different currencies should not add", func(t *testing.T) {
+		_, err := NewMoney(1, "THB").Add(NewMoney(1, "USD"))
+
+		if !errors.Is(err, ErrCurrencyMismatch) {
+			t.Errorf("expected ErrCurrencyMismatch but got %v", err)
+		}
+	})
+
+	t.Run("given invalid input should return error", func(t *testing.T) {
+		for _, s := range []string{"", "abc", "1/3", "0x10", "1e9999"} {
+			if _, err := ParseMoney(s, "THB"); err == nil {
+				t.Errorf("expected error for %q", s)
+			}
+		}
+	})
+
+	t.Run("given rounding modes should round ties explicitly", func(t *testing.T) {
+		half := big.NewRat(1, 2)
+		tests := []struct {
+			amount string
+			mode   RoundingMode
+			want   string
+		}{
+			{"0.05", RoundHalfEven, "0.02"},
+			{"0.07", RoundHalfEven, "0.04"},
+			{"0.05", RoundHalfUp, "0.03"},
+			{"-0.05", RoundHalfUp, "-0.03"},
+			{"0.05", RoundDown, "0.02"},
+			{"0.05", RoundUp, "0.03"},
+			{"-0.05", RoundUp, "-0.03"},
+		}
+		for _, tt := range tests {
+			got, err := MustParseMoney(tt.amount, "THB").MulRat(half, tt.mode)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("%s / 2 with mode %d: expected %s but got %s", tt.amount, tt.mode, tt.want, got)
+			}
+		}
+	})
+
+	t.Run("given JSON number or string should round-trip exactly", func(t *testing.T) {
+		var got struct {
+			A Money `json:"a"`
+			B Money `json:"b"`
+		}
+		if err := json.Unmarshal([]byte(`{"a": 1234567890123.45, "b": "-0.10"}`), &got); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		b, err := json.Marshal(got)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		if string(b) != `{"a":1234567890123.45,"b":-0.10}` {
+			t.Errorf("unexpected JSON %s", b)
+		}
+	})
+
+	t.Run("given NUMERIC column value should scan exactly", func(t *testing.T) {
+		var m Money
+
+		if err := m.Scan([]byte("1000.10")); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		if m != NewMoney(100010, "THB") {
+			t.Errorf("expected 1000.10 but got %s", m)
+		}
+	})
+	t.Run("given crypto asset should keep its native precision", func(t *testing.T) {
+		eth := MustParseMoney("1234.000000000000000001", "ETH")
+
+		got, err := eth.Add(NewMoney(1, "ETH"))
+
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if got.String() != "1234.000000000000000002" {
+			t.Errorf("expected 1234.000000000000000002 but got %s", got)
+		}
+		if got.Minor().String() != "1234000000000000000002" {
+			t.Errorf("expected wei but got %s", got.Minor())
+		}
+		if _, err := ParseMoney("0.000000001", "BTC"); !errors.Is(err, ErrPrecision) {
+			t.Errorf("expected ErrPrecision below one satoshi but got %v", err)
+		}
+	})
+
+	t.Run("given crypto amount should marshal as exact string", func(t *testing.T) {
+		b, err := json.Marshal(MustParseMoney("0.00000001", "BTC"))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		if string(b) != `"0.00000001"` {
+			t.Errorf("unexpected JSON %s", b)
+		}
+	})
+
+	t.Run("given JSON amount with crypto precision should relabel exactly", func(t *testing.T) {
+		var m Money
+		if err := json.Unmarshal([]byte(`"0.000000000000000001"`), &m); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		got, err := m.WithCurrency("ETH")
+		if err != nil || got != NewMoney(1, "ETH") {
+			t.Errorf("expected 1 wei but got %s, %v", got, err)
+		}
+		if _, err := m.WithCurrency("THB"); !errors.Is(err, ErrPrecision) {
+			t.Errorf("expected ErrPrecision for THB but got %v", err)
+		}
+	})
+}
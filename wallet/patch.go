@@ -0,0 +1,283 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Media types of the two patch formats PatchWalletHandler accepts.
+const (
+	MIMEMergePatch = "application/merge-patch+json"
+	MIMEJSONPatch  = "application/json-patch+json"
+)
+
+// patchable lists the wallet fields a patch may change and whether it may
+// remove them. Balances only move through the ledger and the owner, type
+// and currency of a wallet are fixed. The owner's name is the user's and
+// is changed through the users resource.
+var patchable = map[string]bool{
+	"wallet_name":         false,
+	"credit_limit":        false,
+	"interest_product_id": true,
+}
+
+// walletDocument is a wallet as the JSON object patches are applied to.
+// Numbers are kept as json.Number so amounts survive the round trip exactly.
+func walletDocument(w Wallet) (map[string]any, error) {
+	b, err := json.Marshal(w)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var doc map[string]any
+	return doc, dec.Decode(&doc)
+}
+
+func decodeJSON(b []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// mergePatch applies an RFC 7396 JSON Merge Patch to doc: members of the
+// patch replace those of doc and null members remove them.
+func mergePatch(doc map[string]any, patch []byte) (map[string]any, error) {
+	var p any
+	if err := decodeJSON(patch, &p); err != nil {
+		return nil, err
+	}
+	obj, ok := p.(map[string]any)
+	if !ok {
+		return nil, errors.New("merge patch must be a JSON object")
+	}
+	return mergeObject(doc, obj), nil
+}
+
+func mergeObject(doc, patch map[string]any) map[string]any {
+	out := make(map[string]any, len(doc))
+	for k, v := range doc {
+		out[k] = v
+	}
+	for k, v := range patch {
+		switch v := v.(type) {
+		case nil:
+			delete(out, k)
+		case map[string]any:
+			target, _ := out[k].(map[string]any)
+			out[k] = mergeObject(target, v)
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// patchOperation is one operation of an RFC 6902 JSON Patch.
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// jsonPatch applies an RFC 6902 JSON Patch to doc. A wallet is a flat
+// object, so only top-level paths and the add, remove, replace and test
+// operations are supported.
+func jsonPatch(doc map[string]any, patch []byte) (map[string]any, error) {
+	var ops []patchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, errors.New("JSON patch must be an array of operations")
+	}
+	out := make(map[string]any, len(doc))
+	for k, v := range doc {
+		out[k] = v
+	}
+	for i, op := range ops {
+		key, ok := strings.CutPrefix(op.Path, "/")
+		if !ok || strings.Contains(key, "/") {
+			return nil, fmt.Errorf("operation %d: path must name a top-level field such as /wallet_name", i)
+		}
+		key = strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+		var value any
+		if op.Op != "remove" {
+			if op.Value == nil {
+				return nil, fmt.Errorf("operation %d: value is required", i)
+			}
+			if err := decodeJSON(op.Value, &value); err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+		}
+		_, exists := out[key]
+		switch op.Op {
+		case "add":
+			out[key] = value
+		case "replace", "remove":
+			if !exists {
+				return nil, fmt.Errorf("operation %d: %s does not exist", i, op.Path)
+			}
+			if op.Op == "remove" {
+				delete(out, key)
+			} else {
+				out[key] = value
+			}
+		case "test":
+			if !exists || !reflect.DeepEqual(out[key], value) {
+				return nil, fmt.Errorf("operation %d: test of %s failed", i, op.Path)
+			}
+		default:
+			return nil, fmt.Errorf("operation %d: unsupported op %q", i, op.Op)
+		}
+	}
+	return out, nil
+}
+
+// changedFields returns the fields patched differs from doc in, in name
+// order, and fails if any of them may not be patched.
+func changedFields(doc, patched map[string]any) ([]string, error) {
+	keys := map[string]bool{}
+	for k := range doc {
+		keys[k] = true
+	}
+	for k := range patched {
+		keys[k] = true
+	}
+	var fields []string
+	for k := range keys {
+		before, had := doc[k]
+		after, has := patched[k]
+		if had == has && reflect.DeepEqual(before, after) {
+			continue
+		}
+		removable, ok := patchable[k]
+		if !ok {
+			return nil, fmt.Errorf("%s cannot be patched", k)
+		}
+		if !has && !removable {
+			return nil, fmt.Errorf("%s cannot be removed", k)
+		}
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+// PatchWalletHandler
+//
+//	@Summary		Patch wallet
+//	@Description	Change some fields of a wallet, leaving the rest as they are. The body is a JSON Merge Patch (application/merge-patch+json) or a JSON Patch (application/json-patch+json). Only wallet_name, credit_limit and interest_product_id may be patched. If-Match must carry the wallet's ETag, or * to patch whatever is stored.
+//	@Tags			wallet
+//	@Accept			application/merge-patch+json,application/json-patch+json
+//	@Produce		json
+//	@Param			id			path		int		true	"Wallet ID"
+//	@Param			If-Match	header		string	true	"ETag of the wallet as last read"
+//	@Param			patch		body		object	true	"Merge patch or JSON patch"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; needs owner"
+//	@Success		200			{object}	Wallet
+//	@Header			200			{string}	ETag	"Version of the patched wallet"
+//	@Failure		400			{object}	Err
+//	@Failure		401			{object}	Err
+//	@Failure		403			{object}	Err
+//	@Failure		404			{object}	Err
+//	@Failure		412			{object}	Err
+//	@Failure		415			{object}	Err
+//	@Failure		422			{object}	Err
+//	@Failure		428			{object}	Err
+//	@Failure		500		{object}	Err
+//	@Router			/api/v1/wallets/:id [patch]
+func (h *Handler) PatchWalletHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	var apply func(map[string]any, []byte) (map[string]any, error)
+	switch mediaType {
+	case MIMEMergePatch:
+		apply = mergePatch
+	case MIMEJSONPatch:
+		apply = jsonPatch
+	default:
+		return c.JSON(http.StatusUnsupportedMediaType, Err{Message: "Content-Type must be " + MIMEMergePatch + " or " + MIMEJSONPatch})
+	}
+	if err := h.authorize(c, id, RoleOwner); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	version, status, err := ifMatch(c)
+	if err != nil {
+		return c.JSON(status, Err{Message: err.Error()})
+	}
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	current, err := h.store.WalletByID(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	if version != 0 && version != current.Version {
+		return c.JSON(http.StatusPreconditionFailed, Err{Message: ErrVersionMismatch.Error()})
+	}
+	doc, err := walletDocument(current)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	patched, err := apply(doc, body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	fields, err := changedFields(doc, patched)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, Err{Message: err.Error()})
+	}
+	if len(fields) == 0 {
+		setETag(c, current)
+		return c.JSON(http.StatusOK, current)
+	}
+
+	b, err := json.Marshal(patched)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	var w Wallet
+	if err := json.Unmarshal(b, &w); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	updated := current
+	updated.WalletName, updated.InterestProductID = w.WalletName, w.InterestProductID
+	if updated.CreditLimit, err = w.CreditLimit.WithCurrency(current.Currency); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := updated.validatePatch(); err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, Err{Message: err.Error()})
+	}
+	updated, err = h.store.PatchWallet(id, updated, fields, version)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	setETag(c, updated)
+	return c.JSON(http.StatusOK, updated)
+}
+
+// validatePatch checks a patched wallet with the rules creating one
+// applies to the patchable fields.
+func (w Wallet) validatePatch() error {
+	if w.WalletName == "" || len(w.WalletName) > 255 {
+		return errors.New("wallet_name is required and must be at most 255 characters")
+	}
+	if err := w.validateCredit(); err != nil {
+		return err
+	}
+	return w.validateInterest()
+}
@@ -0,0 +1,98 @@
+//go:build unit
+
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestPatchWallet(t *testing.T) {
+	product := 1
+	wallets := []Wallet{
+		{ID: 1, UserID: 1, User: User{ID: 1, Name: "John Doe"}, WalletName: "John's Savings", WalletType: WalletTypeSavings, Currency: "THB",
+			Balance: NewMoney(100000, "THB"), Available: NewMoney(100000, "THB"), InterestProductID: &product, Status: WalletStatusActive, Version: 3},
+		{ID: 2, UserID: 1, User: User{ID: 1, Name: "John Doe"}, WalletName: "John's Card", WalletType: WalletTypeCreditCard, Currency: "THB",
+			Balance: NewMoney(-20000, "THB"), Available: NewMoney(-20000, "THB"), CreditLimit: NewMoney(5000000, "THB"), Status: WalletStatusActive, Version: 3},
+	}
+	tests := []struct {
+		name        string
+		id          string
+		contentType string
+		body        string
+		want        int
+		fields      []string
+	}{
+		{"merge patch renaming wallet", "1", MIMEMergePatch, `{"wallet_name": "Rainy Day"}`, http.StatusOK, []string{"wallet_name"}},
+		{"merge patch removing interest product", "1", MIMEMergePatch, `{"interest_product_id": null}`, http.StatusOK, []string{"interest_product_id"}},
+		{"merge patch raising credit limit", "2", MIMEMergePatch, `{"credit_limit": 60000, "wallet_name": "John's Gold Card"}`, http.StatusOK, []string{"credit_limit", "wallet_name"}},
+		{"merge patch leaving wallet as it is", "1", MIMEMergePatch, `{"wallet_name": "John's Savings"}`, http.StatusOK, nil},
+		{"merge patch of balance", "1", MIMEMergePatch, `{"balance": 0}`, http.StatusUnprocessableEntity, nil},
+		{"merge patch of unknown field", "1", MIMEMergePatch, `{"nickname": "rainy"}`, http.StatusUnprocessableEntity, nil},
+		{"merge patch renaming owner", "1", MIMEMergePatch, `{"user": {"name": "John D."}}`, http.StatusUnprocessableEntity, nil},
+		{"merge patch removing wallet name", "1", MIMEMergePatch, `{"wallet_name": null}`, http.StatusUnprocessableEntity, nil},
+		{"merge patch lowering credit limit under debt", "2", MIMEMergePatch, `{"credit_limit": 100}`, http.StatusUnprocessableEntity, nil},
+		{"merge patch of an array", "1", MIMEMergePatch, `[]`, http.StatusBadRequest, nil},
+		{"json patch replacing name after test", "1", MIMEJSONPatch, `[{"op": "test", "path": "/wallet_name", "value": "John's Savings"}, {"op": "replace", "path": "/wallet_name", "value": "Rainy Day"}]`, http.StatusOK, []string{"wallet_name"}},
+		{"json patch failing test", "1", MIMEJSONPatch, `[{"op": "test", "path": "/wallet_name", "value": "Other"}, {"op": "replace", "path": "/wallet_name", "value": "Rainy Day"}]`, http.StatusBadRequest, nil},
+		{"json patch moving field", "1", MIMEJSONPatch, `[{"op": "move", "from": "/currency", "path": "/wallet_name"}]`, http.StatusBadRequest, nil},
+		{"json patch of user id", "1", MIMEJSONPatch, `[{"op": "replace", "path": "/user_id", "value": 2}]`, http.StatusUnprocessableEntity, nil},
+		{"plain json", "1", echo.MIMEApplicationJSON, `{"wallet_name": "Rainy Day"}`, http.StatusUnsupportedMediaType, nil},
+		{"unknown wallet", "9", MIMEMergePatch, `{"wallet_name": "Rainy Day"}`, http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, tt.contentType)
+			req.Header.Set(HeaderUserID, "1")
+			req.Header.Set("If-Match", `"3"`)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/wallets/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+			var patched []string
+
+			New(StubWallet{wallet: wallets, patched: &patched}).PatchWalletHandler(c)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected status code %d but got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+			if !reflect.DeepEqual(patched, tt.fields) {
+				t.Errorf("expected fields %v patched but got %v", tt.fields, patched)
+			}
+		})
+	}
+
+	t.Run("given merge patch should keep fields it leaves out", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"wallet_name": "Rainy Day"}`))
+		req.Header.Set(echo.HeaderContentType, MIMEMergePatch)
+		req.Header.Set(HeaderUserID, "1")
+		req.Header.Set("If-Match", `"3"`)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		New(StubWallet{wallet: wallets}).PatchWalletHandler(c)
+
+		var got Wallet
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		want := wallets[0]
+		want.WalletName = "Rainy Day"
+		want.Version = 4
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %+v but got %+v", want, got)
+		}
+	})
+}
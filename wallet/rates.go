@@ -0,0 +1,15 @@
+package wallet
+
+import (
+	"errors"
+	"math/big"
+)
+
+var ErrRateUnavailable = errors.New("exchange rate unavailable")
+
+// RateProvider quotes exchange rates for transfers between wallets of
+// different currencies. Rate returns how many units of to one unit of from
+// buys, or ErrRateUnavailable.
+type RateProvider interface {
+	Rate(from, to string) (*big.Rat, error)
+}
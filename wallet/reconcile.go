@@ -0,0 +1,304 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const defaultReconcileInterval = 24 * time.Hour
+
+// Correction ticket statuses.
+const (
+	CorrectionOpen     = "open"
+	CorrectionResolved = "resolved"
+)
+
+var (
+	ErrCorrectionNotFound = errors.New("correction ticket not found")
+	ErrCorrectionResolved = errors.New("correction ticket is already resolved")
+	errCorrectionStatus   = errors.New("status must be open or resolved")
+)
+
+// BalanceCheck is a wallet's stored balance alongside the balance its ledger
+// lines add up to. ForeignLines counts lines booked to the wallet in another
+// currency, which the ledger balance leaves out.
+type BalanceCheck struct {
+	WalletID     int
+	Currency     string
+	Stored       Money
+	Ledger       Money
+	ForeignLines int
+}
+
+// Discrepancy is a wallet whose stored balance disagrees with its ledger.
+// Difference is what the stored balance has over the ledger.
+type Discrepancy struct {
+	WalletID      int    `json:"wallet_id" example:"1"`
+	Currency      string `json:"currency" example:"THB"`
+	StoredBalance Money  `json:"stored_balance" swaggertype:"number" example:"1500.00"`
+	LedgerBalance Money  `json:"ledger_balance" swaggertype:"number" example:"1000.00"`
+	Difference    Money  `json:"difference" swaggertype:"number" example:"500.00"`
+	ForeignLines  int    `json:"foreign_lines,omitempty" example:"0"`
+	// CorrectionID is the correction ticket open for the wallet, if any.
+	CorrectionID *int `json:"correction_id,omitempty" example:"1"`
+}
+
+// Correction is a ticket reconciliation opened for a discrepancy. Its
+// amounts are the ones found when it was opened.
+type Correction struct {
+	ID            int    `json:"id" example:"1"`
+	WalletID      int    `json:"wallet_id" example:"1"`
+	Currency      string `json:"currency" example:"THB"`
+	StoredBalance Money  `json:"stored_balance" swaggertype:"number" example:"1500.00"`
+	LedgerBalance Money  `json:"ledger_balance" swaggertype:"number" example:"1000.00"`
+	Difference    Money  `json:"difference" swaggertype:"number" example:"500.00"`
+	ForeignLines  int    `json:"foreign_lines,omitempty" example:"0"`
+	Status        string `json:"status" example:"open"`
+	// JournalID is the adjustment that resolved the ticket. A ticket
+	// resolved once its wallet agreed with the ledger again has none.
+	JournalID  *int       `json:"journal_id,omitempty" example:"42"`
+	CreatedAt  time.Time  `json:"created_at" example:"2024-04-01T00:00:00Z"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty" example:"2024-04-02T09:30:00Z"`
+}
+
+// ReconciliationReport lists the wallets whose stored balance is not the
+// one their ledger adds up to.
+type ReconciliationReport struct {
+	GeneratedAt   time.Time     `json:"generated_at" example:"2024-04-01T00:00:00Z"`
+	Wallets       int           `json:"wallets" example:"6"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// WriteCSV writes one row per discrepancy, under a header row.
+func (r ReconciliationReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"wallet_id", "currency", "stored_balance", "ledger_balance", "difference", "foreign_lines", "correction_id"})
+	for _, d := range r.Discrepancies {
+		correction := ""
+		if d.CorrectionID != nil {
+			correction = strconv.Itoa(*d.CorrectionID)
+		}
+		cw.Write([]string{strconv.Itoa(d.WalletID), d.Currency, d.StoredBalance.String(), d.LedgerBalance.String(),
+			d.Difference.String(), strconv.Itoa(d.ForeignLines), correction})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Reconcile compares every wallet's stored balance with its ledger. With
+// openCorrections it also opens a correction ticket for each discrepancy,
+// or finds the one already open, for an admin to resolve.
+func (h *Handler) Reconcile(openCorrections bool) (ReconciliationReport, error) {
+	checks, err := h.store.BalanceChecks()
+	if err != nil {
+		return ReconciliationReport{}, err
+	}
+	report := ReconciliationReport{GeneratedAt: time.Now().UTC(), Wallets: len(checks), Discrepancies: []Discrepancy{}}
+	for _, c := range checks {
+		if c.Stored == c.Ledger && c.ForeignLines == 0 {
+			continue
+		}
+		d := Discrepancy{
+			WalletID:      c.WalletID,
+			Currency:      c.Currency,
+			StoredBalance: c.Stored,
+			LedgerBalance: c.Ledger,
+			ForeignLines:  c.ForeignLines,
+		}
+		if d.Difference, err = c.Stored.Sub(c.Ledger); err != nil {
+			return ReconciliationReport{}, err
+		}
+		if openCorrections {
+			id, err := h.store.OpenCorrection(d)
+			if err != nil {
+				return ReconciliationReport{}, err
+			}
+			d.CorrectionID = &id
+		}
+		report.Discrepancies = append(report.Discrepancies, d)
+	}
+	return report, nil
+}
+
+// Reconciler reconciles balances every interval and logs each discrepancy,
+// so that alerting on the service's logs picks them up.
+type Reconciler struct {
+	handler         *Handler
+	interval        time.Duration
+	openCorrections bool
+}
+
+func NewReconciler(h *Handler, openCorrections bool) *Reconciler {
+	return &Reconciler{
+		handler:         h,
+		interval:        defaultReconcileInterval,
+		openCorrections: openCorrections,
+	}
+}
+
+// Run reconciles every interval until ctx is done.
+func (r *Reconciler) Run(ctx context.Context) {
+	runEvery(ctx, r.interval, "reconcile", r.RunDue)
+}
+
+// RunDue reconciles once and logs what disagrees.
+func (r *Reconciler) RunDue() error {
+	report, err := r.handler.Reconcile(r.openCorrections)
+	if err != nil {
+		return err
+	}
+	for _, d := range report.Discrepancies {
+		log.Printf("reconcile: wallet %d stores %s %s but its ledger adds up to %s", d.WalletID, d.StoredBalance, d.Currency, d.LedgerBalance)
+	}
+	return nil
+}
+
+var errReportFormat = errors.New("format must be json or csv")
+
+// reportFormat reads the format query parameter, which defaults to json.
+func reportFormat(c echo.Context) (string, error) {
+	switch f := c.QueryParam("format"); f {
+	case "":
+		return "json", nil
+	case "json", "csv":
+		return f, nil
+	}
+	return "", errReportFormat
+}
+
+func writeReport(c echo.Context, format string, report ReconciliationReport) error {
+	if format == "json" {
+		return c.JSON(http.StatusOK, report)
+	}
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="reconciliation.csv"`)
+	return c.Blob(http.StatusOK, "text/csv", buf.Bytes())
+}
+
+// ReconciliationHandler
+//
+//	@Summary		Reconcile balances
+//	@Description	Compare every wallet's stored balance with the balance its ledger adds up to and list those that disagree
+//	@Tags			reconciliation
+//	@Produce		json,text/csv
+//	@Param			format	query		string	false	"json or csv"
+//	@Success		200		{object}	ReconciliationReport
+//	@Failure		400		{object}	Err
+//	@Failure		500		{object}	Err
+//	@Router			/api/v1/reconciliation [get]
+func (h *Handler) ReconciliationHandler(c echo.Context) error {
+	format, err := reportFormat(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	report, err := h.Reconcile(false)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return writeReport(c, format, report)
+}
+
+// ReconciliationCorrectionsHandler
+//
+//	@Summary		Open correction tickets
+//	@Description	Reconcile balances and open a correction ticket for each wallet that disagrees with its ledger, unless one is open already
+//	@Tags			reconciliation
+//	@Produce		json,text/csv
+//	@Param			format	query		string	false	"json or csv"
+//	@Param			Idempotency-Key	header		string	false	"Retry-safe request key"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; must be an admin"
+//	@Success		200		{object}	ReconciliationReport
+//	@Failure		400		{object}	Err
+//	@Failure		401		{object}	Err
+//	@Failure		403		{object}	Err
+//	@Failure		409		{object}	Err
+//	@Failure		422		{object}	Err
+//	@Failure		500		{object}	Err
+//	@Router			/api/v1/reconciliation/corrections [post]
+func (h *Handler) ReconciliationCorrectionsHandler(c echo.Context) error {
+	if err := h.authorizeAdmin(c); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	format, err := reportFormat(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	report, err := h.Reconcile(true)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return writeReport(c, format, report)
+}
+
+// CorrectionsHandler
+//
+//	@Summary		Get correction tickets
+//	@Description	Get the correction tickets reconciliation opened, oldest first, optionally only those open or resolved
+//	@Tags			reconciliation
+//	@Produce		json
+//	@Param			status	query		string	false	"open or resolved"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; must be an admin"
+//	@Success		200		{array}		Correction
+//	@Failure		400		{object}	Err
+//	@Failure		401		{object}	Err
+//	@Failure		403		{object}	Err
+//	@Failure		500		{object}	Err
+//	@Router			/api/v1/reconciliation/corrections [get]
+func (h *Handler) CorrectionsHandler(c echo.Context) error {
+	if err := h.authorizeAdmin(c); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	status := c.QueryParam("status")
+	if status != "" && status != CorrectionOpen && status != CorrectionResolved {
+		return c.JSON(http.StatusBadRequest, Err{Message: errCorrectionStatus.Error()})
+	}
+	corrections, err := h.store.Corrections(status)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, corrections)
+}
+
+// ResolveCorrectionHandler
+//
+//	@Summary		Resolve correction ticket
+//	@Description	Resolve an open correction ticket. Whatever the wallet's stored balance still has over its ledger is booked to the ledger in an adjustment against the suspense account, so that the two agree and a later discrepancy gets a ticket of its own. The stored balance is left as it is.
+//	@Tags			reconciliation
+//	@Produce		json
+//	@Param			id	path		int	true	"Correction ticket ID"
+//	@Param			Idempotency-Key	header		string	false	"Retry-safe request key"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; must be an admin"
+//	@Success		200	{object}	Correction
+//	@Failure		400	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		409	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/reconciliation/corrections/:id/resolve [post]
+func (h *Handler) ResolveCorrectionHandler(c echo.Context) error {
+	if err := h.authorizeAdmin(c); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	correction, err := h.store.ResolveCorrection(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, correction)
+}
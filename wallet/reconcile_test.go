@@ -0,0 +1,174 @@
+//go:build unit
+
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestReconciliation(t *testing.T) {
+	checks := []BalanceCheck{
+		{WalletID: 1, Currency: "THB", Stored: NewMoney(100000, "THB"), Ledger: NewMoney(100000, "THB")},
+		{WalletID: 2, Currency: "THB", Stored: NewMoney(150000, "THB"), Ledger: NewMoney(100000, "THB")},
+		{WalletID: 3, Currency: "BTC", Stored: NewMoney(0, "BTC"), Ledger: NewMoney(0, "BTC"), ForeignLines: 1},
+	}
+
+	reconcile := func(method, format string, corrections *[]Discrepancy) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(method, "/?format="+format, nil)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		p := New(StubWallet{checks: checks, corrections: corrections}, WithAdmins(1))
+		if method == http.MethodPost {
+			c.SetPath("/api/v1/reconciliation/corrections")
+			p.ReconciliationCorrectionsHandler(c)
+		} else {
+			c.SetPath("/api/v1/reconciliation")
+			p.ReconciliationHandler(c)
+		}
+		return rec
+	}
+
+	t.Run("given balances off their ledger should report them", func(t *testing.T) {
+		rec := reconcile(http.MethodGet, "", nil)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rec.Code)
+		}
+		var got ReconciliationReport
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if got.Wallets != 3 || len(got.Discrepancies) != 2 {
+			t.Fatalf("expected 2 of 3 wallets off but got %+v", got)
+		}
+		if d := got.Discrepancies[0]; d.WalletID != 2 || d.Difference != NewMoney(50000, "THB") || d.CorrectionID != nil {
+			t.Errorf("expected wallet 2 over by 500.00 without ticket but got %+v", d)
+		}
+		if d := got.Discrepancies[1]; d.WalletID != 3 || d.ForeignLines != 1 {
+			t.Errorf("expected wallet 3 with a foreign line but got %+v", d)
+		}
+	})
+
+	t.Run("given csv format should export report as csv", func(t *testing.T) {
+		rec := reconcile(http.MethodGet, "csv", nil)
+
+		want := "wallet_id,currency,stored_balance,ledger_balance,difference,foreign_lines,correction_id\n" +
+			"2,THB,1500.00,1000.00,500.00,0,\n" +
+			"3,BTC,0.00000000,0.00000000,0.00000000,1,\n"
+		if got := rec.Body.String(); got != want {
+			t.Errorf("expected %q but got %q", want, got)
+		}
+		if got := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(got, "text/csv") {
+			t.Errorf("expected csv content type but got %q", got)
+		}
+	})
+
+	t.Run("given corrections requested should open a ticket per discrepancy", func(t *testing.T) {
+		var opened []Discrepancy
+
+		rec := reconcile(http.MethodPost, "json", &opened)
+
+		var got ReconciliationReport
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if len(opened) != 2 || got.Discrepancies[1].CorrectionID == nil || *got.Discrepancies[1].CorrectionID != 2 {
+			t.Errorf("expected 2 tickets opened but got %+v", got)
+		}
+	})
+
+	t.Run("given unknown format should return 400 before opening tickets", func(t *testing.T) {
+		var opened []Discrepancy
+
+		rec := reconcile(http.MethodPost, "xml", &opened)
+
+		if rec.Code != http.StatusBadRequest || len(opened) != 0 {
+			t.Errorf("expected status code %d and no tickets but got %d and %d", http.StatusBadRequest, rec.Code, len(opened))
+		}
+	})
+}
+
+func TestCorrections(t *testing.T) {
+	tickets := []Correction{
+		{ID: 1, WalletID: 2, Currency: "THB", Difference: NewMoney(50000, "THB"), Status: CorrectionResolved},
+		{ID: 2, WalletID: 2, Currency: "THB", Difference: NewMoney(-100, "THB"), Status: CorrectionOpen},
+	}
+
+	t.Run("given status filter should list only those tickets", func(t *testing.T) {
+		tests := []struct {
+			status string
+			want   int
+		}{
+			{"", 2},
+			{CorrectionOpen, 1},
+			{CorrectionResolved, 1},
+		}
+		for _, tt := range tests {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/?status="+tt.status, nil)
+			req.Header.Set(HeaderUserID, "1")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/reconciliation/corrections")
+
+			New(StubWallet{tickets: tickets}, WithAdmins(1)).CorrectionsHandler(c)
+
+			var got []Correction
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("expected %d tickets with status %q but got %d", tt.want, tt.status, len(got))
+			}
+		}
+	})
+
+	tests := []struct {
+		name   string
+		userID string
+		query  string
+		id     string
+		want   int
+	}{
+		{"unknown status", "1", "?status=pending", "", http.StatusBadRequest},
+		{"list by user who is not an admin", "2", "", "", http.StatusForbidden},
+		{"resolution of open ticket", "1", "", "2", http.StatusOK},
+		{"resolution of resolved ticket", "1", "", "1", http.StatusConflict},
+		{"resolution of unknown ticket", "1", "", "3", http.StatusNotFound},
+		{"resolution by user who is not an admin", "2", "", "2", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+			req.Header.Set(HeaderUserID, tt.userID)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			h := New(StubWallet{tickets: tickets}, WithAdmins(1))
+			if tt.id == "" {
+				c.SetPath("/api/v1/reconciliation/corrections")
+				h.CorrectionsHandler(c)
+			} else {
+				req.Method = http.MethodPost
+				c.SetPath("/api/v1/reconciliation/corrections/:id/resolve")
+				c.SetParamNames("id")
+				c.SetParamValues(tt.id)
+				h.ResolveCorrectionHandler(c)
+			}
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status code %d but got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
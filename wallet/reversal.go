@@ -0,0 +1,211 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrAlreadyReversed     = errors.New("transaction is already fully reversed")
+	ErrNotReversible       = errors.New("a reversal cannot itself be reversed")
+	ErrReversalAmount      = errors.New("reversal amount must be positive and at most what is left to reverse")
+)
+
+// ReversalRequest reverses Amount of a transaction, or all of it that is
+// not reversed yet when Amount is nil. Amount is in the currency of the
+// transaction's first line.
+type ReversalRequest struct {
+	Amount *Money `json:"amount,omitempty" swaggertype:"number" example:"40.00"`
+	Reason string `json:"reason" example:"Duplicate charge"`
+}
+
+func (r ReversalRequest) Validate() error {
+	if r.Reason == "" || len(r.Reason) > 200 {
+		return errors.New("reason is required and must be at most 200 characters")
+	}
+	if r.Amount != nil && !r.Amount.IsPositive() {
+		return errors.New("amount must be greater than zero")
+	}
+	return nil
+}
+
+// Reversal is a compensating journal entry booked against a posted
+// transaction. Amount and Remaining are measured on the transaction's
+// first line, which for movements and transfers is the wallet the money
+// left or entered first.
+type Reversal struct {
+	ID            int          `json:"id" example:"57"`
+	TransactionID int          `json:"transaction_id" example:"42"`
+	Amount        Money        `json:"amount" swaggertype:"number" example:"40.00"`
+	Currency      string       `json:"currency" example:"THB"`
+	Remaining     Money        `json:"remaining" swaggertype:"number" example:"60.00"`
+	Reason        string       `json:"reason" example:"Duplicate charge"`
+	Lines         []LedgerLine `json:"lines"`
+	CreatedAt     time.Time    `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
+
+// sameAccount reports whether a and b book to the same account in the same
+// currency.
+func sameAccount(a, b LedgerLine) bool {
+	return a.Account == b.Account && a.WalletID == b.WalletID && a.Amount.Currency() == b.Amount.Currency()
+}
+
+// PlanReversal returns the entry that reverses req.Amount of original, given
+// the lines of the reversals already booked against it, together with the
+// reversal it makes. Every line of original is reversed in proportion;
+// rounding is settled on a line other than the first, so the amount
+// reversed on the first line is exact.
+func PlanReversal(original JournalEntry, reversed []LedgerLine, req ReversalRequest) (JournalEntry, Reversal, error) {
+	if original.Type == TransactionReversal {
+		return JournalEntry{}, Reversal{}, ErrNotReversible
+	}
+	if len(original.Lines) == 0 {
+		return JournalEntry{}, Reversal{}, ErrTransactionNotFound
+	}
+	first := original.Lines[0]
+	currency := first.Amount.Currency()
+	total, done := NewMoney(0, currency), NewMoney(0, currency)
+	var err error
+	for _, l := range original.Lines {
+		if sameAccount(l, first) {
+			if total, err = total.Add(l.Amount); err != nil {
+				return JournalEntry{}, Reversal{}, err
+			}
+		}
+	}
+	for _, l := range reversed {
+		if sameAccount(l, first) {
+			if done, err = done.Sub(l.Amount); err != nil {
+				return JournalEntry{}, Reversal{}, err
+			}
+		}
+	}
+	if total.IsNegative() {
+		total, done = total.Neg(), done.Neg()
+	}
+	remaining, err := total.Sub(done)
+	if err != nil {
+		return JournalEntry{}, Reversal{}, err
+	}
+	if !remaining.IsPositive() {
+		return JournalEntry{}, Reversal{}, ErrAlreadyReversed
+	}
+
+	amount := remaining
+	if req.Amount != nil {
+		if amount, err = req.Amount.WithCurrency(currency); err != nil {
+			return JournalEntry{}, Reversal{}, err
+		}
+	}
+	if !amount.IsPositive() || amount.Cmp(remaining) > 0 {
+		return JournalEntry{}, Reversal{}, ErrReversalAmount
+	}
+	ratio := new(big.Rat).Quo(amount.Rat(), total.Rat())
+
+	lines := make([]LedgerLine, 0, len(original.Lines))
+	sums := map[string]Money{}
+	for _, l := range original.Lines {
+		scaled, err := l.Amount.Neg().MulRat(ratio, RoundHalfEven)
+		if err != nil {
+			return JournalEntry{}, Reversal{}, err
+		}
+		sum, ok := sums[scaled.Currency()]
+		if !ok {
+			sum = NewMoney(0, scaled.Currency())
+		}
+		if sums[scaled.Currency()], err = sum.Add(scaled); err != nil {
+			return JournalEntry{}, Reversal{}, err
+		}
+		l.Amount = scaled
+		lines = append(lines, l)
+	}
+	// Settle what rounding left over in each currency on the last line
+	// that does not book to the first line's account.
+	for i := len(lines) - 1; i > 0; i-- {
+		c := lines[i].Amount.Currency()
+		if sum := sums[c]; !sum.IsZero() && !sameAccount(lines[i], first) {
+			if lines[i].Amount, err = lines[i].Amount.Sub(sum); err != nil {
+				return JournalEntry{}, Reversal{}, err
+			}
+			sums[c] = NewMoney(0, c)
+		}
+	}
+	nonZero := lines[:0]
+	for _, l := range lines {
+		if !l.Amount.IsZero() {
+			nonZero = append(nonZero, l)
+		}
+	}
+
+	left, err := remaining.Sub(amount)
+	if err != nil {
+		return JournalEntry{}, Reversal{}, err
+	}
+	id := original.ID
+	entry := JournalEntry{
+		Type:        TransactionReversal,
+		Reference:   original.Reference,
+		Description: fmt.Sprintf("Reversal of transaction %d: %s", original.ID, req.Reason),
+		Lines:       nonZero,
+		ReversalOf:  &id,
+	}
+	if err := entry.Validate(); err != nil {
+		return JournalEntry{}, Reversal{}, err
+	}
+	return entry, Reversal{TransactionID: original.ID, Amount: amount, Currency: currency, Remaining: left, Reason: req.Reason}, nil
+}
+
+// ReverseTransactionHandler
+//
+//	@Summary		Reverse transaction
+//	@Description	Book a compensating transaction that undoes all or part of a posted one and links back to it. Every line of the original is reversed in proportion. A transaction cannot be reversed beyond its amount, and reversals cannot be reversed.
+//	@Tags			ledger
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int				true	"Transaction ID"
+//	@Param			reversal		body		ReversalRequest	true	"Reversal"
+//	@Param			Idempotency-Key	header		string			false	"Retry-safe request key"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; needs spender or owner of every wallet the transaction booked to"
+//	@Success		201				{object}	Reversal
+//	@Failure		400				{object}	Err
+//	@Failure		401				{object}	Err
+//	@Failure		403				{object}	Err
+//	@Failure		404				{object}	Err
+//	@Failure		409				{object}	Err
+//	@Failure		422				{object}	Err
+//	@Failure		500				{object}	Err
+//	@Router			/api/v1/transactions/:id/reversal [post]
+func (h *Handler) ReverseTransactionHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	var req ReversalRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := req.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	original, err := h.store.JournalEntry(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	for _, walletID := range original.WalletIDs() {
+		if err := h.authorize(c, walletID, RoleSpender); err != nil {
+			return c.JSON(errorStatus(err), Err{Message: err.Error()})
+		}
+	}
+	r, err := h.store.ReverseTransaction(id, req)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusCreated, r)
+}
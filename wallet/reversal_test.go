@@ -0,0 +1,134 @@
+//go:build unit
+
+package wallet
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestPlanReversal(t *testing.T) {
+	usd := func(s string) Money { return MustParseMoney(s, "USD") }
+	withdrawal := JournalEntry{ID: 42, Type: TransactionWithdrawal, Reference: "INV-1", Lines: []LedgerLine{
+		WalletLine(1, *thb("-100.00")),
+		AccountLine(AccountExternal, *thb("100.00")),
+	}}
+	conversion := JournalEntry{ID: 43, Type: TransactionTransfer, Lines: []LedgerLine{
+		WalletLine(1, *thb("-100.00")),
+		AccountLine(AccountFX, *thb("100.00")),
+		AccountLine(AccountFX, usd("-2.74")),
+		WalletLine(4, usd("2.74")),
+	}}
+
+	tests := []struct {
+		name     string
+		original JournalEntry
+		reversed []LedgerLine
+		amount   *Money
+		want     []LedgerLine
+		left     string
+		err      error
+	}{
+		{"full reversal", withdrawal, nil, nil,
+			[]LedgerLine{WalletLine(1, *thb("100.00")), AccountLine(AccountExternal, *thb("-100.00"))}, "0.00", nil},
+		{"partial reversal", withdrawal, nil, thb("40.00"),
+			[]LedgerLine{WalletLine(1, *thb("40.00")), AccountLine(AccountExternal, *thb("-40.00"))}, "60.00", nil},
+		{"reversal of what is left", withdrawal, []LedgerLine{WalletLine(1, *thb("40.00")), AccountLine(AccountExternal, *thb("-40.00"))}, nil,
+			[]LedgerLine{WalletLine(1, *thb("60.00")), AccountLine(AccountExternal, *thb("-60.00"))}, "0.00", nil},
+		{"partial reversal across currencies", conversion, nil, thb("50.00"),
+			[]LedgerLine{WalletLine(1, *thb("50.00")), AccountLine(AccountFX, *thb("-50.00")), AccountLine(AccountFX, usd("1.37")), WalletLine(4, usd("-1.37"))}, "50.00", nil},
+		{"reversal beyond what is left", withdrawal, []LedgerLine{WalletLine(1, *thb("40.00")), AccountLine(AccountExternal, *thb("-40.00"))}, thb("60.01"), nil, "", ErrReversalAmount},
+		{"second full reversal", withdrawal, []LedgerLine{WalletLine(1, *thb("100.00")), AccountLine(AccountExternal, *thb("-100.00"))}, nil, nil, "", ErrAlreadyReversed},
+		{"reversal of a reversal", JournalEntry{ID: 44, Type: TransactionReversal, Lines: withdrawal.Lines}, nil, nil, nil, "", ErrNotReversible},
+		{"amount finer than the currency", withdrawal, nil, func() *Money { m, _ := ParseFractionalMoney("0.001", "THB"); return &m }(), nil, "", ErrPrecision},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name, func(t *testing.T) {
+			entry, r, err := PlanReversal(tt.original, tt.reversed, ReversalRequest{Amount: tt.amount, Reason: "Refund"})
+
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if entry.Type != TransactionReversal || entry.ReversalOf == nil || *entry.ReversalOf != tt.original.ID {
+				t.Errorf("expected reversal of %d but got %+v", tt.original.ID, entry)
+			}
+			if len(entry.Lines) != len(tt.want) {
+				t.Fatalf("expected lines %v but got %v", tt.want, entry.Lines)
+			}
+			for i := range tt.want {
+				if entry.Lines[i] != tt.want[i] {
+					t.Errorf("expected line %d to be %+v but got %+v", i, tt.want[i], entry.Lines[i])
+				}
+			}
+			if r.Remaining != *thb(tt.left) {
+				t.Errorf("expected %s left to reverse but got %s", tt.left, r.Remaining)
+			}
+		})
+	}
+
+	t.Run("given split that rounds unevenly should still balance", func(t *testing.T) {
+		split := JournalEntry{ID: 45, Type: TransactionAdjustment, Lines: []LedgerLine{
+			WalletLine(1, *thb("-100.00")),
+			WalletLine(2, *thb("50.00")),
+			WalletLine(3, *thb("50.00")),
+		}}
+
+		entry, r, err := PlanReversal(split, nil, ReversalRequest{Amount: thb("33.33"), Reason: "Refund"})
+
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if entry.Lines[0] != WalletLine(1, *thb("33.33")) || r.Amount != *thb("33.33") {
+			t.Errorf("expected 33.33 back on wallet 1 but got %+v", entry.Lines)
+		}
+	})
+}
+
+func TestReverseTransaction(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		body string
+		err  error
+		want int
+	}{
+		{"full reversal", "42", `{"reason": "Duplicate charge"}`, nil, http.StatusCreated},
+		{"partial reversal", "42", `{"amount": 40, "reason": "Partial refund"}`, nil, http.StatusCreated},
+		{"reversal without reason", "42", `{"amount": 40}`, nil, http.StatusBadRequest},
+		{"reversal of negative amount", "42", `{"amount": -40, "reason": "Refund"}`, nil, http.StatusBadRequest},
+		{"reversal of unknown transaction", "99", `{"reason": "Refund"}`, ErrTransactionNotFound, http.StatusNotFound},
+		{"second full reversal", "42", `{"reason": "Refund"}`, ErrAlreadyReversed, http.StatusConflict},
+		{"reversal beyond original amount", "42", `{"amount": 1000, "reason": "Refund"}`, ErrReversalAmount, http.StatusUnprocessableEntity},
+		{"reversal of a reversal", "57", `{"reason": "Refund"}`, ErrNotReversible, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(HeaderUserID, "1")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/transactions/:id/reversal")
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+
+			New(StubWallet{reversal: Reversal{ID: 57, TransactionID: 42}, err: tt.err}).ReverseTransactionHandler(c)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status code %d but got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,418 @@
+package wallet
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Schedule frequencies. A one-off schedule runs once at its start time;
+// recurring ones run at the start time and every day, week or month after.
+const (
+	FrequencyOnce    = "once"
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+)
+
+// Schedule statuses. Only active schedules are run. A one-off schedule is
+// completed once it has run; a schedule that cannot be run any more, for
+// example because a wallet is gone, is failed.
+const (
+	ScheduleActive    = "active"
+	SchedulePaused    = "paused"
+	ScheduleCompleted = "completed"
+	ScheduleFailed    = "failed"
+)
+
+// Execution outcomes recorded for every run of a schedule.
+const (
+	ExecutionSucceeded = "succeeded"
+	ExecutionRetrying  = "retrying"
+	ExecutionFailed    = "failed"
+)
+
+const (
+	defaultMaxRetries = 3
+	maxMaxRetries     = 10
+)
+
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// Schedule is a standing order transferring Amount, in the source wallet's
+// currency, from one wallet to another. Attempts counts the failed tries of
+// the run that is currently due. CreatedBy is the user who created or last
+// updated it, whose role on the source wallet every run is checked against.
+type Schedule struct {
+	ID           int       `json:"id" example:"1"`
+	FromWalletID int       `json:"from_wallet_id" example:"1"`
+	ToWalletID   int       `json:"to_wallet_id" example:"4"`
+	Amount       Money     `json:"amount" swaggertype:"number" example:"500.00"`
+	Currency     string    `json:"currency" example:"THB"`
+	Reference    string    `json:"reference,omitempty" example:"Monthly savings"`
+	Frequency    string    `json:"frequency" example:"monthly"`
+	StartAt      time.Time `json:"start_at" example:"2024-04-01T09:00:00Z"`
+	NextRunAt    time.Time `json:"next_run_at" example:"2024-05-01T09:00:00Z"`
+	Status       string    `json:"status" example:"active"`
+	Attempts     int       `json:"attempts" example:"0"`
+	MaxRetries   int       `json:"max_retries" example:"3"`
+	CreatedBy    int       `json:"created_by" example:"1"`
+	CreatedAt    time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
+
+// ScheduleRequest creates or replaces a schedule. StartAt defaults to now and
+// MaxRetries, the number of times a run short of funds is retried, to 3.
+// Status may only be used to pause or resume a schedule.
+type ScheduleRequest struct {
+	FromWalletID int       `json:"from_wallet_id" example:"1"`
+	ToWalletID   int       `json:"to_wallet_id" example:"4"`
+	Amount       Money     `json:"amount" swaggertype:"number" example:"500.00"`
+	Reference    string    `json:"reference,omitempty" example:"Monthly savings"`
+	Frequency    string    `json:"frequency" example:"monthly"`
+	StartAt      time.Time `json:"start_at" example:"2024-04-01T09:00:00Z"`
+	MaxRetries   int       `json:"max_retries" example:"3"`
+	Status       string    `json:"status,omitempty" example:"active"`
+}
+
+// ScheduleRun identifies one run of a schedule by the time it fell due.
+type ScheduleRun struct {
+	ScheduleID int
+	DueAt      time.Time
+}
+
+// ScheduleExecution records one run of a schedule. TransactionID is the
+// transfer it booked, if any.
+type ScheduleExecution struct {
+	ID            int       `json:"id" example:"1"`
+	ScheduleID    int       `json:"schedule_id" example:"1"`
+	DueAt         time.Time `json:"due_at" example:"2024-04-01T09:00:00Z"`
+	Attempt       int       `json:"attempt" example:"1"`
+	Status        string    `json:"status" example:"succeeded"`
+	TransactionID *int      `json:"transaction_id,omitempty" example:"42"`
+	Error         string    `json:"error,omitempty" example:"insufficient funds"`
+	ExecutedAt    time.Time `json:"executed_at" example:"2024-04-01T09:00:05Z"`
+}
+
+func isFrequency(f string) bool {
+	switch f {
+	case FrequencyOnce, FrequencyDaily, FrequencyWeekly, FrequencyMonthly:
+		return true
+	}
+	return false
+}
+
+func (r ScheduleRequest) Validate() error {
+	if err := (Transfer{FromWalletID: r.FromWalletID, ToWalletID: r.ToWalletID, Amount: r.Amount, Reference: r.Reference}).Validate(); err != nil {
+		return err
+	}
+	if !isFrequency(r.Frequency) {
+		return errors.New("frequency must be one of once, daily, weekly or monthly")
+	}
+	if r.MaxRetries < 0 || r.MaxRetries > maxMaxRetries {
+		return errors.New("max_retries must be between 0 and 10")
+	}
+	if r.Status != "" && r.Status != ScheduleActive && r.Status != SchedulePaused {
+		return errors.New("status must be active or paused")
+	}
+	return nil
+}
+
+// occurrence returns the n-th run of a schedule, counting the start as the
+// zeroth. Monthly runs keep the start's day of month, moved back to the last
+// day of shorter months.
+func occurrence(start time.Time, frequency string, n int) time.Time {
+	switch frequency {
+	case FrequencyDaily:
+		return start.AddDate(0, 0, n)
+	case FrequencyWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case FrequencyMonthly:
+		month := time.Date(start.Year(), start.Month()+time.Month(n), 1, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+		day := start.Day()
+		if last := month.AddDate(0, 1, -1).Day(); day > last {
+			day = last
+		}
+		return month.AddDate(0, 0, day-1)
+	}
+	return start
+}
+
+// nextRun returns the first run of s after t, or the zero time when a
+// one-off schedule has no run left.
+func (s Schedule) nextRun(t time.Time) time.Time {
+	if s.StartAt.After(t) {
+		return s.StartAt
+	}
+	if s.Frequency == FrequencyOnce {
+		return time.Time{}
+	}
+	// Start just short of t and step forward, which skips over runs
+	// missed while the server was down.
+	n := 0
+	switch s.Frequency {
+	case FrequencyDaily:
+		n = int(t.Sub(s.StartAt) / (24 * time.Hour))
+	case FrequencyWeekly:
+		n = int(t.Sub(s.StartAt) / (7 * 24 * time.Hour))
+	case FrequencyMonthly:
+		n = (t.Year()-s.StartAt.Year())*12 + int(t.Month()-s.StartAt.Month())
+	}
+	if n--; n < 0 {
+		n = 0
+	}
+	for !occurrence(s.StartAt, s.Frequency, n).After(t) {
+		n++
+	}
+	return occurrence(s.StartAt, s.Frequency, n)
+}
+
+// buildSchedule turns req into a schedule due at its first run at or after
+// now, priced in the source wallet's currency.
+func (h *Handler) buildSchedule(req ScheduleRequest, createdBy int, now time.Time) (Schedule, error) {
+	from, err := h.store.WalletByID(req.FromWalletID)
+	if err != nil {
+		return Schedule{}, err
+	}
+	if _, err := h.store.WalletByID(req.ToWalletID); err != nil {
+		return Schedule{}, err
+	}
+	amount, err := req.Amount.WithCurrency(from.Currency)
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	s := Schedule{
+		FromWalletID: req.FromWalletID,
+		ToWalletID:   req.ToWalletID,
+		Amount:       amount,
+		Currency:     from.Currency,
+		Reference:    req.Reference,
+		Frequency:    req.Frequency,
+		StartAt:      req.StartAt.UTC(),
+		Status:       req.Status,
+		MaxRetries:   req.MaxRetries,
+		CreatedBy:    createdBy,
+	}
+	if s.StartAt.IsZero() {
+		s.StartAt = now
+	}
+	if s.Status == "" {
+		s.Status = ScheduleActive
+	}
+	s.NextRunAt = s.StartAt
+	if s.StartAt.Before(now) && s.Frequency != FrequencyOnce {
+		s.NextRunAt = s.nextRun(now)
+	}
+	return s, nil
+}
+
+// bindSchedule reads a schedule request, defaulting fields the body leaves out.
+func bindSchedule(c echo.Context) (ScheduleRequest, error) {
+	req := ScheduleRequest{MaxRetries: defaultMaxRetries}
+	if err := c.Bind(&req); err != nil {
+		return ScheduleRequest{}, err
+	}
+	return req, req.Validate()
+}
+
+// CreateScheduleHandler
+//
+//	@Summary		Create schedule
+//	@Description	Create a standing order that transfers money once at a future time or on a daily, weekly or monthly basis. Every run needs the caller to still be allowed to spend from the source wallet, or the schedule fails.
+//	@Tags			schedules
+//	@Accept			json
+//	@Produce		json
+//	@Param			schedule		body		ScheduleRequest	true	"Schedule"
+//	@Param			Idempotency-Key	header		string			false	"Retry-safe request key"
+//	@Param			X-User-ID		header		int				true	"User the request acts for; needs spender or owner"
+//	@Success		201				{object}	Schedule
+//	@Failure		400				{object}	Err
+//	@Failure		401				{object}	Err
+//	@Failure		403				{object}	Err
+//	@Failure		404				{object}	Err
+//	@Failure		422				{object}	Err
+//	@Failure		500				{object}	Err
+//	@Router			/api/v1/schedules [post]
+func (h *Handler) CreateScheduleHandler(c echo.Context) error {
+	req, err := bindSchedule(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := h.authorize(c, req.FromWalletID, RoleSpender); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	userID, err := caller(c)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	s, err := h.buildSchedule(req, userID, time.Now().UTC())
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	s, err = h.store.CreateSchedule(s)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusCreated, s)
+}
+
+// ScheduleHandler
+//
+//	@Summary		Get schedule
+//	@Description	Get a standing order by ID
+//	@Tags			schedules
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Schedule ID"
+//	@Success		200	{object}	Schedule
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/schedules/:id [get]
+func (h *Handler) ScheduleHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	s, err := h.store.ScheduleByID(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, s)
+}
+
+// WalletSchedulesHandler
+//
+//	@Summary		Get wallet schedules
+//	@Description	Get the standing orders paying from or into a wallet
+//	@Tags			schedules
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Wallet ID"
+//	@Success		200	{array}		Schedule
+//	@Failure		400	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/wallets/:id/schedules [get]
+func (h *Handler) WalletSchedulesHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	schedules, err := h.store.Schedules(id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, schedules)
+}
+
+// UpdateScheduleHandler
+//
+//	@Summary		Update schedule
+//	@Description	Replace a standing order. The next run is worked out again from the new start time, and setting status pauses or resumes it. The caller takes over the order: its runs are checked against the caller's role from then on.
+//	@Tags			schedules
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int				true	"Schedule ID"
+//	@Param			schedule	body		ScheduleRequest	true	"Schedule"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; needs spender or owner of the old and new from_wallet_id"
+//	@Success		200			{object}	Schedule
+//	@Failure		400			{object}	Err
+//	@Failure		401			{object}	Err
+//	@Failure		403			{object}	Err
+//	@Failure		404			{object}	Err
+//	@Failure		422			{object}	Err
+//	@Failure		500			{object}	Err
+//	@Router			/api/v1/schedules/:id [put]
+func (h *Handler) UpdateScheduleHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	req, err := bindSchedule(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	current, err := h.store.ScheduleByID(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	if err := h.authorize(c, current.FromWalletID, RoleSpender); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	if err := h.authorize(c, req.FromWalletID, RoleSpender); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	userID, err := caller(c)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	s, err := h.buildSchedule(req, userID, time.Now().UTC())
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	s, err = h.store.UpdateSchedule(id, s)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, s)
+}
+
+// DeleteScheduleHandler
+//
+//	@Summary		Delete schedule
+//	@Description	Cancel a standing order together with its execution log
+//	@Tags			schedules
+//	@Param			id	path	int	true	"Schedule ID"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; needs spender or owner"
+//	@Success		204
+//	@Failure		400	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/schedules/:id [delete]
+func (h *Handler) DeleteScheduleHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	s, err := h.store.ScheduleByID(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	if err := h.authorize(c, s.FromWalletID, RoleSpender); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	if err := h.store.DeleteSchedule(id); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+// ScheduleExecutionsHandler
+//
+//	@Summary		Get schedule executions
+//	@Description	Get the execution log of a standing order, newest first
+//	@Tags			schedules
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Schedule ID"
+//	@Success		200	{array}		ScheduleExecution
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/schedules/:id/executions [get]
+func (h *Handler) ScheduleExecutionsHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	executions, err := h.store.ScheduleExecutions(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, executions)
+}
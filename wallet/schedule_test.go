@@ -0,0 +1,244 @@
+//go:build unit
+
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestScheduleNextRun(t *testing.T) {
+	at := func(s string) time.Time {
+		v, _ := time.Parse(time.RFC3339, s)
+		return v
+	}
+	tests := []struct {
+		name      string
+		frequency string
+		start     string
+		after     string
+		want      string
+	}{
+		{"monthly from the 31st should clamp to the end of february", FrequencyMonthly, "2024-01-31T09:00:00Z", "2024-01-31T09:00:00Z", "2024-02-29T09:00:00Z"},
+		{"monthly should return to the 31st after a short month", FrequencyMonthly, "2024-01-31T09:00:00Z", "2024-02-29T09:00:00Z", "2024-03-31T09:00:00Z"},
+		{"monthly should roll over the year", FrequencyMonthly, "2024-01-15T09:00:00Z", "2024-12-20T00:00:00Z", "2025-01-15T09:00:00Z"},
+		{"daily should skip runs missed while stopped", FrequencyDaily, "2024-03-01T06:00:00Z", "2024-03-10T12:00:00Z", "2024-03-11T06:00:00Z"},
+		{"weekly should keep the weekday", FrequencyWeekly, "2024-03-04T06:00:00Z", "2024-03-04T06:00:00Z", "2024-03-11T06:00:00Z"},
+		{"start in the future should be the next run", FrequencyWeekly, "2024-05-01T00:00:00Z", "2024-03-01T00:00:00Z", "2024-05-01T00:00:00Z"},
+		{"once after its start should have no next run", FrequencyOnce, "2024-03-01T00:00:00Z", "2024-03-01T00:00:00Z", "0001-01-01T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name, func(t *testing.T) {
+			s := Schedule{Frequency: tt.frequency, StartAt: at(tt.start)}
+			got := s.nextRun(at(tt.after))
+			if !got.Equal(at(tt.want)) {
+				t.Errorf("expected %v but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	t.Run("given unknown frequency should return 400", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"from_wallet_id": 1, "to_wallet_id": 4, "amount": 500, "frequency": "hourly"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/schedules")
+
+		p := New(StubWallet{wallet: transferWallets})
+
+		p.CreateScheduleHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("given unknown wallet should return 404", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"from_wallet_id": 1, "to_wallet_id": 99, "amount": 500, "frequency": "daily"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/schedules")
+
+		p := New(StubWallet{wallet: transferWallets})
+
+		p.CreateScheduleHandler(c)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+
+	t.Run("given monthly schedule started in the past should be due at its next run", func(t *testing.T) {
+		e := echo.New()
+		start := time.Date(2020, time.January, 31, 9, 0, 0, 0, time.UTC)
+		body := `{"from_wallet_id": 1, "to_wallet_id": 4, "amount": 500, "frequency": "monthly", "start_at": "` + start.Format(time.RFC3339) + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/schedules")
+
+		p := New(StubWallet{wallet: transferWallets})
+
+		p.CreateScheduleHandler(c)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d", http.StatusCreated, rec.Code)
+		}
+		var got Schedule
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("unable to unmarshal json %v", err)
+		}
+		if got.Status != ScheduleActive || got.MaxRetries != defaultMaxRetries || got.Currency != "THB" || got.CreatedBy != 1 {
+			t.Errorf("expected an active THB schedule by user 1 with default retries but got %+v", got)
+		}
+		if !got.NextRunAt.After(time.Now()) || got.NextRunAt.Hour() != 9 {
+			t.Errorf("expected next run in the future at 09:00 but got %v", got.NextRunAt)
+		}
+	})
+
+	t.Run("given unknown schedule should return 404 on delete", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/schedules/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("5")
+
+		p := New(StubWallet{})
+
+		p.DeleteScheduleHandler(c)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
+func TestScheduler(t *testing.T) {
+	now := time.Date(2024, time.April, 1, 9, 0, 30, 0, time.UTC)
+	monthly := Schedule{ID: 1, FromWalletID: 1, ToWalletID: 4, Amount: NewMoney(50000, "THB"), Currency: "THB",
+		Frequency: FrequencyMonthly, StartAt: time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC),
+		NextRunAt: time.Date(2024, time.April, 1, 9, 0, 0, 0, time.UTC), Status: ScheduleActive, MaxRetries: 2}
+	run := func(store StubWallet) *scheduleRuns {
+		store.runs = &scheduleRuns{}
+		s := NewScheduler(New(store))
+		s.now = func() time.Time { return now }
+		if err := s.RunDue(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if len(store.runs.executions) != 1 {
+			t.Fatalf("expected one execution but got %d", len(store.runs.executions))
+		}
+		return store.runs
+	}
+
+	t.Run("given due schedule should transfer and move to the next run", func(t *testing.T) {
+		runs := run(StubWallet{wallet: transferWallets, schedules: []Schedule{monthly}, transfer: TransferResult{TransactionID: 42}})
+
+		exec, sc := runs.executions[0], runs.schedules[0]
+		if exec.Status != ExecutionSucceeded || exec.TransactionID == nil || *exec.TransactionID != 42 {
+			t.Errorf("expected succeeded execution of transaction 42 but got %+v", exec)
+		}
+		if run := runs.transfers[0].Run; run == nil || run.ScheduleID != 1 || !run.DueAt.Equal(monthly.NextRunAt) {
+			t.Errorf("expected transfer keyed by the run due at %v but got %+v", monthly.NextRunAt, run)
+		}
+		if want := time.Date(2024, time.May, 1, 9, 0, 0, 0, time.UTC); !sc.NextRunAt.Equal(want) || sc.Status != ScheduleActive {
+			t.Errorf("expected active schedule due at %v but got %+v", want, sc)
+		}
+	})
+
+	t.Run("given insufficient funds should retry later", func(t *testing.T) {
+		runs := run(StubWallet{wallet: transferWallets, schedules: []Schedule{monthly}, err: ErrInsufficientFunds})
+
+		exec, sc := runs.executions[0], runs.schedules[0]
+		if exec.Status != ExecutionRetrying || exec.Error != ErrInsufficientFunds.Error() {
+			t.Errorf("expected retrying execution but got %+v", exec)
+		}
+		if want := now.Add(defaultRetryDelay); !sc.NextRunAt.Equal(want) || sc.Attempts != 1 {
+			t.Errorf("expected first retry at %v but got %+v", want, sc)
+		}
+	})
+
+	t.Run("given retries used up should skip to the next run", func(t *testing.T) {
+		exhausted := monthly
+		exhausted.Attempts = 2
+		runs := run(StubWallet{wallet: transferWallets, schedules: []Schedule{exhausted}, err: ErrInsufficientFunds})
+
+		exec, sc := runs.executions[0], runs.schedules[0]
+		if exec.Status != ExecutionFailed || exec.Attempt != 3 {
+			t.Errorf("expected failed third attempt but got %+v", exec)
+		}
+		if sc.Status != ScheduleActive || sc.Attempts != 0 || sc.NextRunAt.Month() != time.May {
+			t.Errorf("expected active schedule due in May but got %+v", sc)
+		}
+	})
+
+	t.Run("given one-off schedule out of retries should fail", func(t *testing.T) {
+		once := monthly
+		once.Frequency = FrequencyOnce
+		once.StartAt = once.NextRunAt
+		once.MaxRetries = 0
+		runs := run(StubWallet{wallet: transferWallets, schedules: []Schedule{once}, err: ErrInsufficientFunds})
+
+		if sc := runs.schedules[0]; sc.Status != ScheduleFailed {
+			t.Errorf("expected failed schedule but got %+v", sc)
+		}
+	})
+
+	t.Run("given one run cannot be recorded should still record the others", func(t *testing.T) {
+		other := monthly
+		other.ID = 2
+		store := StubWallet{wallet: transferWallets, schedules: []Schedule{monthly, other}, transfer: TransferResult{TransactionID: 42},
+			runs: &scheduleRuns{fail: 1}}
+		s := NewScheduler(New(store))
+		s.now = func() time.Time { return now }
+
+		if err := s.RunDue(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		if len(store.runs.schedules) != 1 || store.runs.schedules[0].ID != 2 {
+			t.Errorf("expected the run of schedule 2 recorded but got %+v", store.runs.schedules)
+		}
+	})
+
+	t.Run("given creator no longer allowed to spend should fail the schedule without transferring", func(t *testing.T) {
+		ordered := monthly
+		ordered.CreatedBy = 7
+		runs := run(StubWallet{wallet: transferWallets, schedules: []Schedule{ordered}, members: []Member{{WalletID: 1, UserID: 7, Role: RoleViewer}}})
+
+		if exec, sc := runs.executions[0], runs.schedules[0]; exec.Status != ExecutionFailed || exec.Error != ErrForbidden.Error() || sc.Status != ScheduleFailed {
+			t.Errorf("expected failed schedule but got %+v and %+v", exec, sc)
+		}
+		if len(runs.transfers) != 0 {
+			t.Errorf("expected no transfer but got %+v", runs.transfers)
+		}
+	})
+
+	t.Run("given missing wallet should fail the schedule", func(t *testing.T) {
+		gone := monthly
+		gone.ToWalletID = 99
+		runs := run(StubWallet{wallet: transferWallets, schedules: []Schedule{gone}})
+
+		if exec, sc := runs.executions[0], runs.schedules[0]; exec.Status != ExecutionFailed || sc.Status != ScheduleFailed {
+			t.Errorf("expected failed schedule but got %+v and %+v", exec, sc)
+		}
+	})
+}
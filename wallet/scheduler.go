@@ -0,0 +1,148 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	defaultSchedulerInterval = time.Minute
+	defaultSchedulerBatch    = 100
+	defaultRetryDelay        = time.Hour
+)
+
+// Scheduler runs due standing orders as transfers. Several servers may run
+// a scheduler against the same store; each due schedule is claimed by only
+// one of them.
+type Scheduler struct {
+	handler    *Handler
+	interval   time.Duration
+	batch      int
+	retryDelay time.Duration
+	now        func() time.Time
+}
+
+func NewScheduler(h *Handler) *Scheduler {
+	return &Scheduler{
+		handler:    h,
+		interval:   defaultSchedulerInterval,
+		batch:      defaultSchedulerBatch,
+		retryDelay: defaultRetryDelay,
+		now:        time.Now,
+	}
+}
+
+// Run executes due schedules every interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	runEvery(ctx, s.interval, "scheduler", s.RunDue)
+}
+
+// runEvery calls run straight away and then every interval until ctx is
+// done. Errors are logged and the next run goes ahead regardless.
+func runEvery(ctx context.Context, interval time.Duration, name string, run func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := run(); err != nil {
+			log.Printf("%s: %v", name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue executes every schedule that is due now and records the outcome.
+func (s *Scheduler) RunDue() error {
+	now := s.now().UTC()
+	due, err := s.handler.store.DueSchedules(now, s.batch)
+	if err != nil {
+		return err
+	}
+	for _, sc := range due {
+		sc, exec := s.execute(sc, now)
+		if err := s.handler.store.RecordScheduleExecution(sc, exec); err != nil {
+			log.Printf("recording run of schedule %d: %v", sc.ID, err)
+		}
+	}
+	return nil
+}
+
+// retryable reports whether a failed run may succeed later without anyone
+// changing the schedule.
+func retryable(err error) bool {
+	return errors.Is(err, ErrInsufficientFunds) || errors.Is(err, ErrRateUnavailable)
+}
+
+// execute runs sc once and returns the schedule as it should be stored
+// afterwards, along with the execution to log. The transfer is keyed by the
+// run, so a run that was paid but not recorded, because the process died or
+// the schedule was claimed again, is recorded as paid rather than paid twice.
+//
+// A run short of funds is retried retryDelay later, and later still on each
+// further attempt, up to MaxRetries times. When the retries run out the run
+// is skipped; recurring schedules go on to their next run while one-off
+// schedules fail. Any other error fails the schedule until it is updated,
+// including the one returned when the user behind the schedule may no
+// longer spend from its source wallet.
+func (s *Scheduler) execute(sc Schedule, now time.Time) (Schedule, ScheduleExecution) {
+	exec := ScheduleExecution{
+		ScheduleID: sc.ID,
+		DueAt:      sc.NextRunAt,
+		Attempt:    sc.Attempts + 1,
+		ExecutedAt: now,
+	}
+	reference := sc.Reference
+	if reference == "" {
+		reference = fmt.Sprintf("Schedule %d", sc.ID)
+	}
+	var result TransferResult
+	err := s.handler.allow(sc.CreatedBy, sc.FromWalletID, RoleSpender)
+	if err == nil {
+		result, err = s.handler.transfer(Transfer{
+			FromWalletID: sc.FromWalletID,
+			ToWalletID:   sc.ToWalletID,
+			Amount:       sc.Amount,
+			Reference:    reference,
+			Run:          &ScheduleRun{ScheduleID: sc.ID, DueAt: sc.NextRunAt},
+		})
+	}
+
+	switch {
+	case err == nil:
+		exec.Status = ExecutionSucceeded
+		exec.TransactionID = &result.TransactionID
+	case retryable(err) && sc.Attempts < sc.MaxRetries:
+		exec.Status = ExecutionRetrying
+		exec.Error = err.Error()
+		sc.Attempts++
+		sc.NextRunAt = now.Add(time.Duration(sc.Attempts) * s.retryDelay)
+		return sc, exec
+	case retryable(err):
+		exec.Status = ExecutionFailed
+		exec.Error = err.Error()
+	default:
+		exec.Status = ExecutionFailed
+		exec.Error = err.Error()
+		sc.Status = ScheduleFailed
+		sc.Attempts = 0
+		return sc, exec
+	}
+
+	sc.Attempts = 0
+	next := sc.nextRun(now)
+	switch {
+	case !next.IsZero():
+		sc.NextRunAt = next
+	case exec.Status == ExecutionSucceeded:
+		sc.Status = ScheduleCompleted
+	default:
+		sc.Status = ScheduleFailed
+	}
+	return sc, exec
+}
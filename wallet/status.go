@@ -0,0 +1,211 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Wallet statuses. A frozen wallet may receive money but not pay any out; a
+// closed wallet takes no movement at all and cannot be reopened.
+const (
+	WalletStatusActive = "active"
+	WalletStatusFrozen = "frozen"
+	WalletStatusClosed = "closed"
+)
+
+var (
+	ErrWalletFrozen = errors.New("wallet is frozen")
+	ErrWalletClosed = errors.New("wallet is closed")
+	// ErrStatusTransition is returned for a status change the lifecycle
+	// does not allow, such as unfreezing an active wallet.
+	ErrStatusTransition = errors.New("wallet status cannot change this way")
+	ErrWalletNotEmpty   = errors.New("wallet must have a zero balance and no active holds")
+)
+
+// transitions lists the statuses each status may change to.
+var transitions = map[string][]string{
+	WalletStatusActive: {WalletStatusFrozen, WalletStatusClosed},
+	WalletStatusFrozen: {WalletStatusActive},
+}
+
+// CheckMovement reports whether a wallet in status may have amount booked
+// to it by an entry of type kind. Charges the system books are exempt from
+// a freeze, which only stops the owner moving money out.
+func CheckMovement(status string, amount Money, kind string) error {
+	switch status {
+	case WalletStatusClosed:
+		return ErrWalletClosed
+	case WalletStatusFrozen:
+		if amount.IsNegative() && !IsCharge(kind) {
+			return ErrWalletFrozen
+		}
+	}
+	return nil
+}
+
+// CheckTransition reports whether w may change to status to. A wallet is
+// closed only once it is empty: its balance is zero and nothing is held.
+func (w Wallet) CheckTransition(to string) error {
+	allowed := false
+	for _, s := range transitions[w.Status] {
+		allowed = allowed || s == to
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s to %s", ErrStatusTransition, w.Status, to)
+	}
+	if to == WalletStatusClosed {
+		return w.CheckEmpty()
+	}
+	return nil
+}
+
+// CheckEmpty fails with ErrWalletNotEmpty unless w has a zero balance and
+// nothing held, so that closing or deleting it leaves no money behind.
+func (w Wallet) CheckEmpty() error {
+	if !w.Balance.IsZero() || w.Available != w.Balance {
+		return ErrWalletNotEmpty
+	}
+	return nil
+}
+
+// StatusChange is one change of a wallet's status, kept as an audit trail.
+type StatusChange struct {
+	ID        int       `json:"id" example:"1"`
+	WalletID  int       `json:"wallet_id" example:"1"`
+	From      string    `json:"from" example:"active"`
+	To        string    `json:"to" example:"frozen"`
+	Reason    string    `json:"reason" example:"Suspicious activity reported"`
+	CreatedAt time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
+
+// StatusRequest gives the reason for a status change.
+type StatusRequest struct {
+	Reason string `json:"reason" example:"Suspicious activity reported"`
+}
+
+func (r StatusRequest) Validate() error {
+	if r.Reason == "" || len(r.Reason) > 255 {
+		return errors.New("reason is required and must be at most 255 characters")
+	}
+	return nil
+}
+
+func (h *Handler) changeStatus(c echo.Context, to string) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	var req StatusRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := req.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := h.authorize(c, id, RoleOwner); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	w, err := h.store.ChangeWalletStatus(id, to, req.Reason)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, w)
+}
+
+// FreezeWalletHandler
+//
+//	@Summary		Freeze wallet
+//	@Description	Stop money leaving an active wallet until it is unfrozen. Money may still be paid in.
+//	@Tags			wallet
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Wallet ID"
+//	@Param			request	body		StatusRequest	true	"Reason"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; needs owner"
+//	@Success		200		{object}	Wallet
+//	@Failure		400		{object}	Err
+//	@Failure		401		{object}	Err
+//	@Failure		403		{object}	Err
+//	@Failure		404		{object}	Err
+//	@Failure		409		{object}	Err
+//	@Failure		500		{object}	Err
+//	@Router			/api/v1/wallets/:id/freeze [post]
+func (h *Handler) FreezeWalletHandler(c echo.Context) error {
+	return h.changeStatus(c, WalletStatusFrozen)
+}
+
+// UnfreezeWalletHandler
+//
+//	@Summary		Unfreeze wallet
+//	@Description	Make a frozen wallet active again
+//	@Tags			wallet
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Wallet ID"
+//	@Param			request	body		StatusRequest	true	"Reason"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; needs owner"
+//	@Success		200		{object}	Wallet
+//	@Failure		400		{object}	Err
+//	@Failure		401		{object}	Err
+//	@Failure		403		{object}	Err
+//	@Failure		404		{object}	Err
+//	@Failure		409		{object}	Err
+//	@Failure		500		{object}	Err
+//	@Router			/api/v1/wallets/:id/unfreeze [post]
+func (h *Handler) UnfreezeWalletHandler(c echo.Context) error {
+	return h.changeStatus(c, WalletStatusActive)
+}
+
+// CloseWalletHandler
+//
+//	@Summary		Close wallet
+//	@Description	Close an active wallet for good. The wallet must have a zero balance and no active holds, and must not be a house fee wallet.
+//	@Tags			wallet
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Wallet ID"
+//	@Param			request	body		StatusRequest	true	"Reason"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; needs owner"
+//	@Success		200		{object}	Wallet
+//	@Failure		400		{object}	Err
+//	@Failure		401		{object}	Err
+//	@Failure		403		{object}	Err
+//	@Failure		404		{object}	Err
+//	@Failure		409		{object}	Err
+//	@Failure		500		{object}	Err
+//	@Router			/api/v1/wallets/:id/close [post]
+func (h *Handler) CloseWalletHandler(c echo.Context) error {
+	return h.changeStatus(c, WalletStatusClosed)
+}
+
+// StatusChangesHandler
+//
+//	@Summary		Get wallet status history
+//	@Description	Get every status change of a wallet with its reason, newest first
+//	@Tags			wallet
+//	@Produce		json
+//	@Param			id	path		int	true	"Wallet ID"
+//	@Success		200	{array}		StatusChange
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/wallets/:id/status-changes [get]
+func (h *Handler) StatusChangesHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if _, err := h.store.WalletByID(id); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	changes, err := h.store.StatusChanges(id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, changes)
+}
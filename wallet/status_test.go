@@ -0,0 +1,113 @@
+//go:build unit
+
+package wallet
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestWalletStatus(t *testing.T) {
+	empty := Wallet{Status: WalletStatusActive, Balance: NewMoney(0, "THB"), Available: NewMoney(0, "THB")}
+	tests := []struct {
+		name   string
+		wallet Wallet
+		to     string
+		want   error
+	}{
+		{"active wallet frozen", empty, WalletStatusFrozen, nil},
+		{"frozen wallet unfrozen", Wallet{Status: WalletStatusFrozen}, WalletStatusActive, nil},
+		{"empty active wallet closed", empty, WalletStatusClosed, nil},
+		{"frozen wallet closed", Wallet{Status: WalletStatusFrozen}, WalletStatusClosed, ErrStatusTransition},
+		{"active wallet unfrozen", empty, WalletStatusActive, ErrStatusTransition},
+		{"closed wallet reopened", Wallet{Status: WalletStatusClosed}, WalletStatusActive, ErrStatusTransition},
+		{"wallet with money closed", Wallet{Status: WalletStatusActive, Balance: NewMoney(100, "THB"), Available: NewMoney(100, "THB")}, WalletStatusClosed, ErrWalletNotEmpty},
+		{"wallet with active hold closed", Wallet{Status: WalletStatusActive, Available: NewMoney(-100, "THB")}, WalletStatusClosed, ErrWalletNotEmpty},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name, func(t *testing.T) {
+			if err := tt.wallet.CheckTransition(tt.to); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v but got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCheckMovement(t *testing.T) {
+	debit, credit := NewMoney(-100, "THB"), NewMoney(100, "THB")
+	tests := []struct {
+		name   string
+		status string
+		amount Money
+		kind   string
+		want   error
+	}{
+		{"frozen wallet debited", WalletStatusFrozen, debit, TransactionWithdrawal, ErrWalletFrozen},
+		{"frozen wallet credited", WalletStatusFrozen, credit, TransactionDeposit, nil},
+		{"frozen wallet charged interest", WalletStatusFrozen, debit, TransactionInterest, nil},
+		{"closed wallet credited", WalletStatusClosed, credit, TransactionTransfer, ErrWalletClosed},
+		{"active wallet debited", WalletStatusActive, debit, TransactionCapture, nil},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name, func(t *testing.T) {
+			if err := CheckMovement(tt.status, tt.amount, tt.kind); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v but got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestStatusHandlers(t *testing.T) {
+	wallets := []Wallet{{ID: 1, Status: WalletStatusActive, Balance: NewMoney(100000, "THB"), Available: NewMoney(100000, "THB")}}
+	tests := []struct {
+		name    string
+		body    string
+		handler func(*Handler) echo.HandlerFunc
+		want    int
+	}{
+		{"freeze with reason", `{"reason": "Suspicious activity reported"}`, func(h *Handler) echo.HandlerFunc { return h.FreezeWalletHandler }, http.StatusOK},
+		{"freeze without reason", `{}`, func(h *Handler) echo.HandlerFunc { return h.FreezeWalletHandler }, http.StatusBadRequest},
+		{"unfreeze of active wallet", `{"reason": "Cleared"}`, func(h *Handler) echo.HandlerFunc { return h.UnfreezeWalletHandler }, http.StatusConflict},
+		{"close with money left", `{"reason": "Customer request"}`, func(h *Handler) echo.HandlerFunc { return h.CloseWalletHandler }, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(HeaderUserID, "1")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			tt.handler(New(StubWallet{wallet: wallets}))(c)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status code %d but got %d", tt.want, rec.Code)
+			}
+		})
+	}
+
+	t.Run("given close of a house fee wallet should return 409", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"reason": "Customer request"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		New(StubWallet{wallet: []Wallet{{ID: 1, Status: WalletStatusActive}}, feeWallets: []int{1}}).CloseWalletHandler(c)
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rec.Code)
+		}
+	})
+}
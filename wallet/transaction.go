@@ -0,0 +1,318 @@
+package wallet
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Transaction types, recorded on every journal entry.
+const (
+	TransactionOpening    = "opening"
+	TransactionAdjustment = "adjustment"
+	TransactionDeposit    = "deposit"
+	TransactionWithdrawal = "withdrawal"
+	TransactionTransfer   = "transfer"
+	TransactionCapture    = "capture"
+	TransactionInterest   = "interest"
+	TransactionFee        = "fee"
+	TransactionReversal   = "reversal"
+)
+
+var transactionTypes = []string{
+	TransactionOpening, TransactionAdjustment, TransactionDeposit,
+	TransactionWithdrawal, TransactionTransfer, TransactionCapture,
+	TransactionInterest, TransactionFee, TransactionReversal,
+}
+
+// IsCharge reports whether entries of type kind are charges the system
+// books on a wallet, which post even if they take it past its limit.
+func IsCharge(kind string) bool {
+	return kind == TransactionInterest
+}
+
+// Transaction is one balance movement on a wallet as seen by its owner.
+// Amount is positive for money in and negative for money out. ID is the
+// journal entry that booked it.
+type Transaction struct {
+	ID        int    `json:"id" example:"42"`
+	WalletID  int    `json:"wallet_id" example:"1"`
+	Type      string `json:"type" example:"deposit"`
+	Amount    Money  `json:"amount" swaggertype:"number" example:"100.00"`
+	Currency  string `json:"currency" example:"THB"`
+	Reference string `json:"reference,omitempty" example:"INV-2024-0001"`
+	// Description says what booked the transaction; CounterpartyWalletID is
+	// the wallet on the other side of a transfer.
+	Description          string `json:"description,omitempty" example:"Transfer from wallet 1 to wallet 4"`
+	CounterpartyWalletID *int   `json:"counterparty_wallet_id,omitempty" example:"4"`
+	// CategoryID and Category are what the transaction was filed under,
+	// by the wallet's user or, when CategoryRuleID is set, by that rule.
+	CategoryID     *int     `json:"category_id,omitempty" example:"2"`
+	Category       string   `json:"category,omitempty" example:"Rent"`
+	CategoryRuleID *int     `json:"category_rule_id,omitempty" example:"1"`
+	Tags           []string `json:"tags,omitempty" example:"home"`
+	// Fee is what a withdrawal was charged, booked as a fee transaction of
+	// its own.
+	Fee *Money `json:"fee,omitempty" swaggertype:"number" example:"15.00"`
+	// ReversalOf is the transaction a reversal undoes.
+	ReversalOf *int      `json:"reversal_of,omitempty" example:"42"`
+	CreatedAt  time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
+
+// TransactionFilter narrows a wallet's history. Zero values do not filter.
+// Amount bounds apply to the absolute amount; From is inclusive and To
+// exclusive. Cursor continues a previous page.
+type TransactionFilter struct {
+	Types     []string
+	MinAmount *Money
+	MaxAmount *Money
+	From      time.Time
+	To        time.Time
+	Cursor    int
+	Limit     int
+}
+
+// TransactionPage is one page of history, newest first. NextCursor is empty
+// on the last page.
+type TransactionPage struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"next_cursor,omitempty" example:"NDI"`
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+func encodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func decodeCursor(s string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, errors.New("invalid cursor")
+	}
+	id, err := strconv.Atoi(string(b))
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return id, nil
+}
+
+// parseTransactionFilter reads the history query string. Amounts are in the
+// wallet's currency.
+func parseTransactionFilter(c echo.Context, currency string) (TransactionFilter, error) {
+	f := TransactionFilter{Limit: defaultPageSize}
+	if v := c.QueryParam("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxPageSize {
+			return f, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		f.Limit = limit
+	}
+	if v := c.QueryParam("cursor"); v != "" {
+		cursor, err := decodeCursor(v)
+		if err != nil {
+			return f, err
+		}
+		f.Cursor = cursor
+	}
+	if v := c.QueryParam("type"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			if !slices.Contains(transactionTypes, t) {
+				return f, fmt.Errorf("unknown transaction type %q", t)
+			}
+			f.Types = append(f.Types, t)
+		}
+	}
+	for param, dst := range map[string]**Money{"min_amount": &f.MinAmount, "max_amount": &f.MaxAmount} {
+		if v := c.QueryParam(param); v != "" {
+			m, err := ParseMoney(v, currency)
+			if err != nil {
+				return f, fmt.Errorf("%s: %w", param, err)
+			}
+			*dst = &m
+		}
+	}
+	for param, dst := range map[string]*time.Time{"from": &f.From, "to": &f.To} {
+		if v := c.QueryParam(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, fmt.Errorf("%s must be an RFC 3339 timestamp", param)
+			}
+			*dst = t
+		}
+	}
+	return f, nil
+}
+
+// TransactionsHandler
+//
+//	@Summary		Get wallet transactions
+//	@Description	Get the balance movements of a wallet, newest first, a page at a time, each with the category it is filed under and its tags
+//	@Tags			wallet
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int		true	"Wallet ID"
+//	@Param			limit		query		int		false	"Page size, 1 to 100"	default(20)
+//	@Param			cursor		query		string	false	"next_cursor of the previous page"
+//	@Param			type		query		string	false	"Comma-separated types: deposit, withdrawal, transfer, capture, adjustment, opening, interest, fee, reversal"
+//	@Param			min_amount	query		number	false	"Minimum absolute amount"
+//	@Param			max_amount	query		number	false	"Maximum absolute amount"
+//	@Param			from		query		string	false	"Created at or after (RFC 3339)"
+//	@Param			to			query		string	false	"Created before (RFC 3339)"
+//	@Success		200			{object}	TransactionPage
+//	@Failure		400			{object}	Err
+//	@Failure		404			{object}	Err
+//	@Failure		500			{object}	Err
+//	@Router			/api/v1/wallets/:id/transactions [get]
+func (h *Handler) TransactionsHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	w, err := h.store.WalletByID(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	f, err := parseTransactionFilter(c, w.Currency)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	// Ask for one extra row to learn whether another page follows.
+	limit := f.Limit
+	f.Limit++
+	transactions, err := h.store.Transactions(id, f)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	if err := h.categorize(w, transactions); err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	page := TransactionPage{Transactions: transactions}
+	if len(transactions) > limit {
+		page.Transactions = transactions[:limit]
+		page.NextCursor = encodeCursor(page.Transactions[limit-1].ID)
+	}
+	if page.Transactions == nil {
+		page.Transactions = []Transaction{}
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
+// Movement is money entering or leaving a wallet. Fee, charged on top of
+// a withdrawal, is set by the handler from the fee rules.
+type Movement struct {
+	Amount    Money  `json:"amount" swaggertype:"number" example:"100.00"`
+	Reference string `json:"reference" example:"INV-2024-0001"`
+	Fee       Money  `json:"-"`
+}
+
+func (m Movement) Validate() error {
+	if !m.Amount.IsPositive() {
+		return errors.New("amount must be greater than zero")
+	}
+	if len(m.Reference) > 255 {
+		return errors.New("reference must be at most 255 characters")
+	}
+	return nil
+}
+
+// DepositHandler
+//
+//	@Summary		Deposit to wallet
+//	@Description	Add an amount to the wallet balance and record a deposit transaction
+//	@Tags			wallet
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int			true	"Wallet ID"
+//	@Param			Idempotency-Key	header		string		false	"Retry-safe request key"
+//	@Param			X-User-ID		header		int			true	"User the request acts for"
+//	@Param			deposit		body		Movement	true	"Deposit"
+//	@Success		201			{object}	Transaction
+//	@Failure		400			{object}	Err
+//	@Failure		401			{object}	Err
+//	@Failure		403			{object}	Err
+//	@Failure		404			{object}	Err
+//	@Failure		409			{object}	Err
+//	@Failure		422			{object}	Err
+//	@Failure		500			{object}	Err
+//	@Router			/api/v1/wallets/:id/deposits [post]
+func (h *Handler) DepositHandler(c echo.Context) error {
+	return h.move(c, TransactionDeposit, h.store.Deposit)
+}
+
+// WithdrawalHandler
+//
+//	@Summary		Withdraw from wallet
+//	@Description	Subtract an amount from the wallet balance and record a withdrawal transaction. The fee the fee rules set is charged on top as a fee transaction.
+//	@Tags			wallet
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int			true	"Wallet ID"
+//	@Param			Idempotency-Key	header		string		false	"Retry-safe request key"
+//	@Param			X-User-ID		header		int			true	"User the request acts for; needs spender or owner"
+//	@Param			withdrawal	body		Movement	true	"Withdrawal"
+//	@Success		201			{object}	Transaction
+//	@Failure		400			{object}	Err
+//	@Failure		401			{object}	Err
+//	@Failure		403			{object}	Err
+//	@Failure		404			{object}	Err
+//	@Failure		409			{object}	Err
+//	@Failure		422			{object}	Err
+//	@Failure		500			{object}	Err
+//	@Router			/api/v1/wallets/:id/withdrawals [post]
+func (h *Handler) WithdrawalHandler(c echo.Context) error {
+	return h.move(c, TransactionWithdrawal, h.store.Withdraw)
+}
+
+// move posts a movement of type kind, charging the fee the rules set on it.
+func (h *Handler) move(c echo.Context, kind string, post func(walletID int, m Movement) (Transaction, error)) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	var m Movement
+	if err := c.Bind(&m); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	// Anyone may pay into a wallet, but must say who they are.
+	if kind == TransactionWithdrawal {
+		err = h.authorize(c, id, RoleSpender)
+	} else {
+		_, err = caller(c)
+	}
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	if err := m.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	w, err := h.store.WalletByID(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	if m.Amount, err = m.Amount.WithCurrency(w.Currency); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	q, err := h.quote(kind, w, m.Amount)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	m.Fee = q.Fee
+	t, err := post(id, m)
+	if err != nil {
+		return c.JSON(errorStatus(err), errBody(err))
+	}
+	t.Fee = feeCharged(m.Fee)
+	return c.JSON(http.StatusCreated, t)
+}
@@ -0,0 +1,144 @@
+package wallet
+
+import (
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Transfer moves Amount, in the source wallet's currency, to another wallet.
+// Credited is what the destination receives in its own currency; it is set
+// by the handler and differs from Amount only across currencies. Fee is
+// charged to the source on top of Amount, also set by the handler.
+type Transfer struct {
+	FromWalletID int    `json:"from_wallet_id" example:"1"`
+	ToWalletID   int    `json:"to_wallet_id" example:"4"`
+	Amount       Money  `json:"amount" swaggertype:"number" example:"100.00"`
+	Reference    string `json:"reference,omitempty" example:"Rent March"`
+	Credited     Money  `json:"-"`
+	Fee          Money  `json:"-"`
+	// Run is set for the transfers of standing orders. A run that was
+	// already paid is not booked again; its transfer is returned instead.
+	Run *ScheduleRun `json:"-"`
+}
+
+type TransferResult struct {
+	TransactionID int    `json:"transaction_id" example:"42"`
+	From          Wallet `json:"from"`
+	To            Wallet `json:"to"`
+	Credited      Money  `json:"credited" swaggertype:"number" example:"3650.00"`
+	Rate          string `json:"rate,omitempty" example:"36.50"`
+	Fee           *Money `json:"fee,omitempty" swaggertype:"number" example:"10.00"`
+}
+
+func (t Transfer) Validate() error {
+	if t.FromWalletID <= 0 || t.ToWalletID <= 0 {
+		return errors.New("from_wallet_id and to_wallet_id are required")
+	}
+	if t.FromWalletID == t.ToWalletID {
+		return errors.New("cannot transfer to the same wallet")
+	}
+	if !t.Amount.IsPositive() {
+		return errors.New("amount must be greater than zero")
+	}
+	if len(t.Reference) > 255 {
+		return errors.New("reference must be at most 255 characters")
+	}
+	return nil
+}
+
+// TransferHandler
+//
+//	@Summary		Transfer between wallets
+//	@Description	Move money from one wallet to another in a single transaction. The fee the fee rules set is charged to the source wallet on top.
+//	@Tags			transfers
+//	@Accept			json
+//	@Produce		json
+//	@Param			Idempotency-Key	header		string		false	"Retry-safe request key"
+//	@Param			X-User-ID		header		int			true	"User the request acts for; needs spender or owner"
+//	@Param			transfer	body		Transfer	true	"Transfer request"
+//	@Success		200			{object}	TransferResult
+//	@Failure		400			{object}	Err
+//	@Failure		401			{object}	Err
+//	@Failure		403			{object}	Err
+//	@Failure		404			{object}	Err
+//	@Failure		409			{object}	Err
+//	@Failure		422			{object}	Err
+//	@Failure		500			{object}	Err
+//	@Router			/api/v1/transfers [post]
+func (h *Handler) TransferHandler(c echo.Context) error {
+	var t Transfer
+	if err := c.Bind(&t); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := t.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := h.authorize(c, t.FromWalletID, RoleSpender); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	result, err := h.transfer(t)
+	if err != nil {
+		return c.JSON(errorStatus(err), errBody(err))
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// transfer prices t in the wallets' currencies, converting at the current
+// rate when they differ, and hands it to the store.
+func (h *Handler) transfer(t Transfer) (TransferResult, error) {
+	from, err := h.store.WalletByID(t.FromWalletID)
+	if err != nil {
+		return TransferResult{}, err
+	}
+	to, err := h.store.WalletByID(t.ToWalletID)
+	if err != nil {
+		return TransferResult{}, err
+	}
+	if t.Amount, err = t.Amount.WithCurrency(from.Currency); err != nil {
+		return TransferResult{}, err
+	}
+	q, err := h.quote(TransactionTransfer, from, t.Amount)
+	if err != nil {
+		return TransferResult{}, err
+	}
+	t.Fee = q.Fee
+
+	t.Credited = t.Amount
+	var rate *big.Rat
+	if from.Currency != to.Currency {
+		if h.rates == nil {
+			return TransferResult{}, ErrRateUnavailable
+		}
+		if rate, err = h.rates.Rate(from.Currency, to.Currency); err != nil {
+			return TransferResult{}, err
+		}
+		if t.Credited, err = t.Amount.Convert(rate, to.Currency, RoundHalfEven); err != nil {
+			return TransferResult{}, err
+		}
+		if !t.Credited.IsPositive() {
+			return TransferResult{}, ErrConversionTooSmall
+		}
+	}
+
+	result, err := h.store.Transfer(t)
+	if err != nil {
+		return TransferResult{}, err
+	}
+	result.Credited = t.Credited
+	result.Fee = feeCharged(t.Fee)
+	if rate != nil {
+		result.Rate = formatRate(rate)
+	}
+	return result, nil
+}
+
+// formatRate prints r with up to eight decimal places and no trailing zeros.
+func formatRate(r *big.Rat) string {
+	s := r.FloatString(8)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,171 @@
+package wallet
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUnknownUser is returned when a wallet names a user_id that does
+	// not exist.
+	ErrUnknownUser = errors.New("user_id does not name an existing user")
+	// ErrUserHasWallets is returned when deleting a user who still owns
+	// wallets, including deleted ones not yet purged.
+	ErrUserHasWallets = errors.New("user still owns wallets")
+)
+
+// User owns wallets. Every wallet embeds its owner as stored here.
+type User struct {
+	ID        int       `json:"id" example:"1"`
+	Name      string    `json:"name" example:"John Doe"`
+	CreatedAt time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
+
+func (u User) Validate() error {
+	if u.Name == "" || len(u.Name) > 255 {
+		return errors.New("name is required and must be at most 255 characters")
+	}
+	return nil
+}
+
+// CreateUserHandler
+//
+//	@Summary		Create user
+//	@Description	Create a user who can own wallets
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			user	body		User	true	"User"
+//	@Success		201		{object}	User
+//	@Failure		400		{object}	Err
+//	@Failure		500		{object}	Err
+//	@Router			/api/v1/users [post]
+func (h *Handler) CreateUserHandler(c echo.Context) error {
+	var u User
+	if err := c.Bind(&u); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := u.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	u, err := h.store.CreateUser(u)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusCreated, u)
+}
+
+// UsersHandler
+//
+//	@Summary		Get users
+//	@Description	Get all users
+//	@Tags			user
+//	@Produce		json
+//	@Success		200	{array}		User
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/users [get]
+func (h *Handler) UsersHandler(c echo.Context) error {
+	users, err := h.store.Users()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, users)
+}
+
+// UserHandler
+//
+//	@Summary		Get user by id
+//	@Description	Get one user by its id
+//	@Tags			user
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	User
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/users/:id [get]
+func (h *Handler) UserHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	u, err := h.store.UserByID(id)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, u)
+}
+
+// UpdateUserHandler
+//
+//	@Summary		Update user by id
+//	@Description	Update a user. Their wallets show the change at once.
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int		true	"User ID"
+//	@Param			user	body		User	true	"User"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; must be the user"
+//	@Success		200		{object}	User
+//	@Failure		400		{object}	Err
+//	@Failure		401		{object}	Err
+//	@Failure		403		{object}	Err
+//	@Failure		404		{object}	Err
+//	@Failure		500		{object}	Err
+//	@Router			/api/v1/users/:id [put]
+func (h *Handler) UpdateUserHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	var u User
+	if err := c.Bind(&u); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := u.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := authorizeSelf(c, id); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	u, err = h.store.UpdateUser(id, u)
+	if err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, u)
+}
+
+// DeleteUserHandler
+//
+//	@Summary		Delete user by id
+//	@Description	Delete a user who owns no wallets. Wallets that are deleted but not yet purged still count.
+//	@Tags			user
+//	@Produce		json
+//	@Param			id	path	int	true	"User ID"
+//	@Param			X-User-ID	header		int		true	"User the request acts for; must be the user"
+//	@Success		204
+//	@Failure		400	{object}	Err
+//	@Failure		401	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		409	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Router			/api/v1/users/:id [delete]
+func (h *Handler) DeleteUserHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := authorizeSelf(c, id); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	if err := h.store.DeleteUser(id); err != nil {
+		return c.JSON(errorStatus(err), Err{Message: err.Error()})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
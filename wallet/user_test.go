@@ -0,0 +1,95 @@
+//go:build unit
+
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestUsers(t *testing.T) {
+	users := []User{{ID: 1, Name: "John Doe"}, {ID: 2, Name: "Jane Doe"}}
+	wallets := []Wallet{{ID: 1, UserID: 1, User: users[0], WalletName: "John's Savings", WalletType: WalletTypeSavings, Currency: "THB"}}
+
+	tests := []struct {
+		name    string
+		method  string
+		id      string
+		caller  string
+		body    string
+		handler func(*Handler) echo.HandlerFunc
+		want    int
+	}{
+		{"new user", http.MethodPost, "", "", `{"name": "Jim Doe"}`, func(h *Handler) echo.HandlerFunc { return h.CreateUserHandler }, http.StatusCreated},
+		{"new user without name", http.MethodPost, "", "", `{}`, func(h *Handler) echo.HandlerFunc { return h.CreateUserHandler }, http.StatusBadRequest},
+		{"list of users", http.MethodGet, "", "", "", func(h *Handler) echo.HandlerFunc { return h.UsersHandler }, http.StatusOK},
+		{"existing user", http.MethodGet, "1", "", "", func(h *Handler) echo.HandlerFunc { return h.UserHandler }, http.StatusOK},
+		{"unknown user", http.MethodGet, "9", "", "", func(h *Handler) echo.HandlerFunc { return h.UserHandler }, http.StatusNotFound},
+		{"rename of user", http.MethodPut, "1", "1", `{"name": "John D."}`, func(h *Handler) echo.HandlerFunc { return h.UpdateUserHandler }, http.StatusOK},
+		{"rename of unknown user", http.MethodPut, "9", "9", `{"name": "John D."}`, func(h *Handler) echo.HandlerFunc { return h.UpdateUserHandler }, http.StatusNotFound},
+		{"delete of user owning wallets", http.MethodDelete, "1", "1", "", func(h *Handler) echo.HandlerFunc { return h.DeleteUserHandler }, http.StatusConflict},
+		{"delete of user without wallets", http.MethodDelete, "2", "2", "", func(h *Handler) echo.HandlerFunc { return h.DeleteUserHandler }, http.StatusNoContent},
+		{"delete of unknown user", http.MethodDelete, "9", "9", "", func(h *Handler) echo.HandlerFunc { return h.DeleteUserHandler }, http.StatusNotFound},
+		{"rename of another user", http.MethodPut, "1", "2", `{"name": "John D."}`, func(h *Handler) echo.HandlerFunc { return h.UpdateUserHandler }, http.StatusForbidden},
+		{"rename without caller", http.MethodPut, "1", "", `{"name": "John D."}`, func(h *Handler) echo.HandlerFunc { return h.UpdateUserHandler }, http.StatusUnauthorized},
+		{"delete of another user", http.MethodDelete, "2", "1", "", func(h *Handler) echo.HandlerFunc { return h.DeleteUserHandler }, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.caller != "" {
+				req.Header.Set(HeaderUserID, tt.caller)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/users/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+
+			tt.handler(New(StubWallet{wallet: wallets, users: users}))(c)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status code %d but got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	createWallet := func(body string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets")
+
+		New(StubWallet{users: users}).CreateWalletHandler(c)
+		return rec
+	}
+
+	t.Run("given wallet for unknown user should return 422", func(t *testing.T) {
+		rec := createWallet(`{"user_id": 9, "wallet_name": "Savings", "wallet_type": "Savings"}`)
+
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d but got %d", http.StatusUnprocessableEntity, rec.Code)
+		}
+	})
+
+	t.Run("given new wallet should embed its owner as stored", func(t *testing.T) {
+		rec := createWallet(`{"user_id": 2, "user": {"name": "Someone Else"}, "wallet_name": "Savings", "wallet_type": "Savings"}`)
+
+		var got Wallet
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if got.User != users[1] {
+			t.Errorf("expected owner %+v but got %+v", users[1], got.User)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package wallet
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ErrVersionMismatch is returned when a wallet has changed since the
+// version a client sent in If-Match.
+var ErrVersionMismatch = errors.New("wallet has changed since it was read")
+
+// etag is the entity tag of a wallet at version.
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func setETag(c echo.Context, w Wallet) {
+	c.Response().Header().Set("ETag", etag(w.Version))
+}
+
+// ifMatch reads the version a write is conditional on from If-Match. The
+// header is required; "*" matches any version and is returned as 0. On
+// failure it returns the status to answer with.
+func ifMatch(c echo.Context) (int, int, error) {
+	v := strings.TrimSpace(c.Request().Header.Get("If-Match"))
+	switch {
+	case v == "":
+		return 0, http.StatusPreconditionRequired, errors.New("If-Match is required; send the ETag of the wallet as last read")
+	case v == "*":
+		return 0, 0, nil
+	}
+	// If-Match compares strongly, so a weak tag never matches.
+	version, err := strconv.Atoi(strings.Trim(v, `"`))
+	if err != nil || strings.HasPrefix(v, "W/") || version < 1 || etag(version) != v {
+		return 0, http.StatusPreconditionFailed, ErrVersionMismatch
+	}
+	return version, 0, nil
+}
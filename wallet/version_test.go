@@ -0,0 +1,89 @@
+//go:build unit
+
+package wallet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestWalletVersion(t *testing.T) {
+	wallets := []Wallet{{ID: 1, UserID: 1, WalletName: "John's Savings", WalletType: WalletTypeSavings, Currency: "THB",
+		Status: WalletStatusActive, Version: 5}}
+
+	call := func(method, ifMatch string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(method, "/", strings.NewReader(`{"wallet_name": "Rainy Day", "wallet_type": "Savings"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		if method == http.MethodPatch {
+			req.Header.Set(echo.HeaderContentType, MIMEMergePatch)
+		}
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		h := New(StubWallet{wallet: wallets})
+		switch method {
+		case http.MethodGet:
+			h.GetWalletHandler(c)
+		case http.MethodPut:
+			h.UpdateWalletHandler(c)
+		case http.MethodPatch:
+			h.PatchWalletHandler(c)
+		case http.MethodDelete:
+			h.DeleteWalletHandler(c)
+		}
+		return rec
+	}
+
+	t.Run("given wallet read should return its version as ETag", func(t *testing.T) {
+		if got := call(http.MethodGet, "").Header().Get("ETag"); got != `"5"` {
+			t.Errorf("expected ETag %q but got %q", `"5"`, got)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		method  string
+		ifMatch string
+		want    int
+	}{
+		{"update without If-Match", http.MethodPut, "", http.StatusPreconditionRequired},
+		{"update with stale ETag", http.MethodPut, `"4"`, http.StatusPreconditionFailed},
+		{"update with weak ETag", http.MethodPut, `W/"5"`, http.StatusPreconditionFailed},
+		{"update with unquoted ETag", http.MethodPut, `5`, http.StatusPreconditionFailed},
+		{"update with current ETag", http.MethodPut, `"5"`, http.StatusOK},
+		{"update with any version", http.MethodPut, "*", http.StatusOK},
+		{"patch without If-Match", http.MethodPatch, "", http.StatusPreconditionRequired},
+		{"patch with stale ETag", http.MethodPatch, `"4"`, http.StatusPreconditionFailed},
+		{"patch with current ETag", http.MethodPatch, `"5"`, http.StatusOK},
+		{"delete without If-Match", http.MethodDelete, "", http.StatusPreconditionRequired},
+		{"delete with stale ETag", http.MethodDelete, `"4"`, http.StatusPreconditionFailed},
+		{"delete with current ETag", http.MethodDelete, `"5"`, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			if rec := call(tt.method, tt.ifMatch); rec.Code != tt.want {
+				t.Errorf("expected status code %d but got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	t.Run("given write should return the new ETag", func(t *testing.T) {
+		for _, method := range []string{http.MethodPut, http.MethodPatch} {
+			if got := call(method, `"5"`).Header().Get("ETag"); got != `"6"` {
+				t.Errorf("expected %s to return ETag %q but got %q", method, `"6"`, got)
+			}
+		}
+	})
+}
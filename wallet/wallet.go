@@ -3,11 +3,37 @@ package wallet
 import "time"
 
 type Wallet struct {
-	ID         int       `json:"id" example:"1"`
-	UserID     int       `json:"user_id" example:"1"`
-	UserName   string    `json:"user_name" example:"John Doe"`
-	WalletName string    `json:"wallet_name" example:"John's Wallet"`
-	WalletType string    `json:"wallet_type" example:"Create Card"`
-	Balance    float64   `json:"balance" example:"100.00"`
-	CreatedAt  time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+	ID     int `json:"id" example:"1"`
+	UserID int `json:"user_id" example:"1"`
+	// User is the owner named by UserID. It is ignored in requests; rename
+	// a user through the users resource.
+	User User `json:"user"`
+	// Role is what the user whose wallets were listed may do with the
+	// wallet, which they own or which is shared with them. It is only set
+	// in those lists and ignored in requests.
+	Role       string `json:"role,omitempty" example:"owner"`
+	WalletName string `json:"wallet_name" example:"John's Wallet"`
+	WalletType string `json:"wallet_type" example:"Create Card"`
+	Currency   string `json:"currency" example:"THB"`
+	Balance    Money  `json:"balance" swaggertype:"number" example:"100.00"`
+	// Available is Balance less the amounts reserved by active holds.
+	Available Money `json:"available_balance" swaggertype:"number" example:"80.00"`
+	// CreditLimit is how far below zero a Credit Card wallet may go.
+	CreditLimit Money `json:"credit_limit" swaggertype:"number" example:"0.00"`
+	// Limits cap the money going out of the wallet. They are ignored in
+	// wallet requests and set through the wallet's limits.
+	Limits SpendingLimits `json:"limits"`
+	// InterestProductID is the savings rate a Savings wallet earns, if any.
+	InterestProductID *int `json:"interest_product_id,omitempty" example:"1"`
+	// Status is active, frozen or closed; StatusReason says why it last
+	// changed.
+	Status       string `json:"status" example:"active"`
+	StatusReason string `json:"status_reason,omitempty" example:"Suspicious activity reported"`
+	// DeletedAt and DeletedBy are set on a deleted wallet until it is
+	// restored or purged.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" example:"2024-03-26T09:00:00Z"`
+	DeletedBy string     `json:"deleted_by,omitempty" example:"backoffice"`
+	// Version goes up with every change to the wallet and is its ETag.
+	Version   int       `json:"version" example:"1"`
+	CreatedAt time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
 }
@@ -4,9 +4,13 @@ package wallet
 
 import (
 	"encoding/json"
+	"errors"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
 	"time"
@@ -15,30 +19,713 @@ import (
 )
 
 type StubWallet struct {
-	wallet []Wallet
-	err    error
+	wallet       []Wallet
+	transfer     TransferResult
+	journal      JournalEntry
+	transaction  Transaction
+	transactions []Transaction
+	hold         Hold
+	schedules    []Schedule
+	runs         *scheduleRuns
+	activity     StatementActivity
+	statements   []Statement
+	bills        *billingRuns
+	products     []InterestProduct
+	interest     *interestRuns
+	past         map[int]Money
+	checks       []BalanceCheck
+	corrections  *[]Discrepancy
+	tickets      []Correction
+	patched      *[]string
+	users        []User
+	spent        Spending
+	rules        []FeeRule
+	reversal     Reversal
+	members      []Member
+	categories   []Category
+	catRules     []CategoryRule
+	feeWallets   []int
+	err          error
 }
 
-func (s StubWallet) Wallets(wallet_type string) ([]Wallet, error) {
-	return s.wallet, s.err
+// interestRuns keeps the accruals recorded and the interest paid through a
+// StubWallet. The accruals of wallet fail cannot be recorded.
+type interestRuns struct {
+	accruals []Accrual
+	paid     map[int][]Money
+	fail     int
 }
 
-func (s StubWallet) WalletByUserID(id int) ([]Wallet, error) {
-	return s.wallet, s.err
+// billingRuns collects the statements cut and closed through a StubWallet.
+// The statement of wallet fail cannot be stored.
+type billingRuns struct {
+	created []Statement
+	closed  map[int]Money
+	fail    int
 }
 
-func (s StubWallet) CreateWallet(wallet Wallet) error {
+// scheduleRuns collects what the scheduler books and records through a
+// StubWallet. The run of schedule fail cannot be recorded.
+type scheduleRuns struct {
+	transfers  []Transfer
+	schedules  []Schedule
+	executions []ScheduleExecution
+	fail       int
+}
+
+func (s StubWallet) Wallets(wallet_type string, includeDeleted bool) ([]Wallet, error) {
+	return s.visible(includeDeleted), s.err
+}
+
+// WalletByUserID returns the stub's wallets or, when it has members, the
+// ones shared with user id with their role.
+func (s StubWallet) WalletByUserID(id int, includeDeleted bool) ([]Wallet, error) {
+	if s.members == nil {
+		return s.visible(includeDeleted), s.err
+	}
+	var wallets []Wallet
+	for _, w := range s.visible(includeDeleted) {
+		if role, err := s.Role(w.ID, id); err == nil {
+			w.Role = role
+			wallets = append(wallets, w)
+		}
+	}
+	return wallets, s.err
+}
+
+// visible returns the stub's wallets, leaving out deleted ones unless
+// includeDeleted is set.
+func (s StubWallet) visible(includeDeleted bool) []Wallet {
+	var wallets []Wallet
+	for _, w := range s.wallet {
+		if w.DeletedAt == nil || includeDeleted {
+			wallets = append(wallets, w)
+		}
+	}
+	return wallets
+}
+
+func (s StubWallet) WalletByID(id int) (Wallet, error) {
+	for _, w := range s.wallet {
+		if w.ID == id {
+			return w, nil
+		}
+	}
+	if s.err != nil {
+		return Wallet{}, s.err
+	}
+	return Wallet{}, ErrWalletNotFound
+}
+
+// WalletAt returns the stub's wallet with the balances in past, if any, as
+// long as it existed at at.
+func (s StubWallet) WalletAt(id int, at time.Time) (Wallet, error) {
+	w, err := s.WalletByID(id)
+	if err != nil || !w.CreatedAt.Before(at) {
+		return Wallet{}, ErrWalletNotFound
+	}
+	if b, ok := s.past[id]; ok {
+		w.Balance, w.Available = b, b
+	}
+	return w, nil
+}
+
+func (s StubWallet) WalletByUserIDAt(id int, at time.Time) ([]Wallet, error) {
+	var wallets []Wallet
+	for _, w := range s.wallet {
+		if w, err := s.WalletAt(w.ID, at); err == nil {
+			wallets = append(wallets, w)
+		}
+	}
+	return wallets, s.err
+}
+
+type StubRates map[string]*big.Rat
+
+func (s StubRates) Rate(from, to string) (*big.Rat, error) {
+	if r, ok := s[from+"/"+to]; ok {
+		return r, nil
+	}
+	return nil, ErrRateUnavailable
+}
+
+func (s StubWallet) CreateWallet(wallet Wallet) (Wallet, error) {
+	if s.users != nil {
+		u, err := s.UserByID(wallet.UserID)
+		if err != nil {
+			return Wallet{}, ErrUnknownUser
+		}
+		wallet.User = u
+	}
+	return wallet, s.err
+}
+
+// checkVersion mimics the conditional UPDATE: a stored wallet at another
+// version than the one asked for is not changed.
+func (s StubWallet) checkVersion(id, version int) (int, error) {
+	for _, w := range s.wallet {
+		if w.ID != id {
+			continue
+		}
+		if version != 0 && w.Version != version {
+			return 0, ErrVersionMismatch
+		}
+		return w.Version + 1, nil
+	}
+	return 1, nil
+}
+
+func (s StubWallet) UpdateWallet(id int, wallet Wallet, version int) (Wallet, error) {
+	next, err := s.checkVersion(id, version)
+	if err != nil {
+		return Wallet{}, err
+	}
+	for _, w := range s.wallet {
+		if w.ID != id {
+			continue
+		}
+		if w.WalletType != wallet.WalletType {
+			return Wallet{}, ErrWalletTypeChange
+		}
+		w.CreditLimit = wallet.CreditLimit
+		if err := w.CheckCreditLimit(); err != nil {
+			return Wallet{}, err
+		}
+	}
+	wallet.Version = next
+	return wallet, s.err
+}
+
+func (s StubWallet) PatchWallet(id int, w Wallet, fields []string, version int) (Wallet, error) {
+	next, err := s.checkVersion(id, version)
+	if err != nil {
+		return Wallet{}, err
+	}
+	if s.patched != nil {
+		*s.patched = fields
+	}
+	w.Version = next
+	return w, s.err
+}
+
+func (s StubWallet) DeleteWallet(id int, by string) error {
+	for _, w := range s.wallet {
+		if w.UserID != id {
+			continue
+		}
+		if err := w.CheckEmpty(); err != nil {
+			return err
+		}
+		if slices.Contains(s.feeWallets, w.ID) {
+			return ErrFeeWallet
+		}
+	}
 	return s.err
 }
 
-func (s StubWallet) UpdateWallet(id int, wallet Wallet) error {
+func (s StubWallet) DeleteWalletByID(id int, by string, version int) error {
+	w, err := s.WalletByID(id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.checkVersion(id, version); err != nil {
+		return err
+	}
+	if err := w.CheckEmpty(); err != nil {
+		return err
+	}
+	if slices.Contains(s.feeWallets, id) {
+		return ErrFeeWallet
+	}
+	return s.err
+}
+
+func (s StubWallet) RestoreWallet(id int, deletedSince time.Time) (Wallet, error) {
+	for _, w := range s.wallet {
+		switch {
+		case w.ID != id:
+		case w.DeletedAt == nil:
+			return Wallet{}, ErrWalletNotDeleted
+		case w.DeletedAt.Before(deletedSince):
+			return Wallet{}, ErrRestoreExpired
+		default:
+			w.DeletedAt, w.DeletedBy = nil, ""
+			return w, nil
+		}
+	}
+	return Wallet{}, ErrWalletNotFound
+}
+
+func (s StubWallet) PurgeWallets(deletedBefore time.Time) (int, error) {
+	n := 0
+	for _, w := range s.wallet {
+		if w.DeletedAt != nil && w.DeletedAt.Before(deletedBefore) {
+			n++
+		}
+	}
+	return n, s.err
+}
+
+func (s StubWallet) Transfer(t Transfer) (TransferResult, error) {
+	if s.runs != nil {
+		s.runs.transfers = append(s.runs.transfers, t)
+	}
+	return s.transfer, s.err
+}
+
+func (s StubWallet) Deposit(walletID int, m Movement) (Transaction, error) {
+	return s.transaction, s.err
+}
+
+func (s StubWallet) Withdraw(walletID int, m Movement) (Transaction, error) {
+	return s.transaction, s.err
+}
+
+func (s StubWallet) PlaceHold(walletID int, req HoldRequest) (Hold, error) {
+	return s.hold, s.err
+}
+
+func (s StubWallet) Holds(walletID int) ([]Hold, error) {
+	return []Hold{s.hold}, s.err
+}
+
+func (s StubWallet) CaptureHold(id int, amount *Money) (Hold, error) {
+	return s.hold, s.err
+}
+
+func (s StubWallet) ReleaseHold(id int) (Hold, error) {
+	return s.hold, s.err
+}
+
+func (s StubWallet) Transactions(walletID int, f TransactionFilter) ([]Transaction, error) {
+	if len(s.transactions) > f.Limit {
+		return s.transactions[:f.Limit], s.err
+	}
+	return s.transactions, s.err
+}
+
+func (s StubWallet) CreateSchedule(sc Schedule) (Schedule, error) {
+	sc.ID = 1
+	return sc, s.err
+}
+
+func (s StubWallet) ScheduleByID(id int) (Schedule, error) {
+	for _, sc := range s.schedules {
+		if sc.ID == id {
+			return sc, nil
+		}
+	}
+	return Schedule{}, ErrScheduleNotFound
+}
+
+func (s StubWallet) Schedules(walletID int) ([]Schedule, error) {
+	return s.schedules, s.err
+}
+
+func (s StubWallet) UpdateSchedule(id int, sc Schedule) (Schedule, error) {
+	if _, err := s.ScheduleByID(id); err != nil {
+		return Schedule{}, err
+	}
+	sc.ID = id
+	return sc, s.err
+}
+
+func (s StubWallet) DeleteSchedule(id int) error {
+	_, err := s.ScheduleByID(id)
+	return err
+}
+
+func (s StubWallet) ScheduleExecutions(scheduleID int) ([]ScheduleExecution, error) {
+	if _, err := s.ScheduleByID(scheduleID); err != nil {
+		return nil, err
+	}
+	return s.runs.executions, nil
+}
+
+func (s StubWallet) DueSchedules(now time.Time, limit int) ([]Schedule, error) {
+	return s.schedules, nil
+}
+
+func (s StubWallet) RecordScheduleExecution(sc Schedule, e ScheduleExecution) error {
+	if sc.ID == s.runs.fail {
+		return errors.New("run cannot be recorded")
+	}
+	s.runs.schedules = append(s.runs.schedules, sc)
+	s.runs.executions = append(s.runs.executions, e)
+	return nil
+}
+
+func (s StubWallet) UnbilledWallets(walletType string, periodStart time.Time) ([]Wallet, error) {
+	var wallets []Wallet
+	for _, w := range s.wallet {
+		if w.WalletType == walletType {
+			wallets = append(wallets, w)
+		}
+	}
+	return wallets, nil
+}
+
+func (s StubWallet) StatementActivity(walletID int, from, to time.Time) (StatementActivity, error) {
+	return s.activity, s.err
+}
+
+func (s StubWallet) CreateStatement(st Statement) (Statement, error) {
+	if st.WalletID == s.bills.fail {
+		return Statement{}, errors.New("statement cannot be stored")
+	}
+	s.bills.created = append(s.bills.created, st)
+	return st, s.err
+}
+
+func (s StubWallet) Statements(walletID int) ([]Statement, error) {
+	return s.statements, s.err
+}
+
+func (s StubWallet) DueStatements(now time.Time) ([]Statement, error) {
+	var due []Statement
+	for _, st := range s.statements {
+		if st.ClosedAt == nil && !st.DueDate.After(now) {
+			due = append(due, st)
+		}
+	}
+	return due, nil
+}
+
+func (s StubWallet) CloseStatement(id int, interest Money) (Statement, error) {
+	s.bills.closed[id] = interest
+	return Statement{ID: id, Interest: interest}, s.err
+}
+
+func (s StubWallet) CreateInterestProduct(p InterestProduct) (InterestProduct, error) {
+	p.ID = len(s.products) + 1
+	return p, s.err
+}
+
+func (s StubWallet) InterestProducts() ([]InterestProduct, error) {
+	return s.products, s.err
+}
+
+func (s StubWallet) InterestAccounts() ([]InterestAccount, error) {
+	var accounts []InterestAccount
+	for _, w := range s.wallet {
+		if w.InterestProductID == nil {
+			continue
+		}
+		acc := InterestAccount{Wallet: w, Product: s.products[*w.InterestProductID-1]}
+		for _, a := range s.interest.accruals {
+			if a.WalletID == w.ID && a.Date.After(acc.LastAccrual) {
+				acc.LastAccrual = a.Date
+			}
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, s.err
+}
+
+func (s StubWallet) BalanceAt(walletID int, at time.Time) (Money, error) {
+	w, err := s.WalletByID(walletID)
+	return w.Balance, err
+}
+
+func (s StubWallet) RecordAccrual(a Accrual) error {
+	if a.WalletID == s.interest.fail {
+		return errors.New("accrual not recorded")
+	}
+	for _, got := range s.interest.accruals {
+		if got.WalletID == a.WalletID && got.Date.Equal(a.Date) {
+			return nil
+		}
+	}
+	s.interest.accruals = append(s.interest.accruals, a)
 	return s.err
 }
 
-func (s StubWallet) DeleteWallet(id int) error {
+func (s StubWallet) UnpaidAccruals(walletID int) ([]Accrual, error) {
+	accruals := []Accrual{}
+	if s.interest == nil {
+		return accruals, s.err
+	}
+	for _, a := range s.interest.accruals {
+		if a.WalletID == walletID && a.CapitalizedAt == nil {
+			accruals = append(accruals, a)
+		}
+	}
+	return accruals, s.err
+}
+
+func (s StubWallet) CapitalizeInterest(walletID int, before time.Time, accruals int, amount Money) error {
+	for i, a := range s.interest.accruals {
+		if a.WalletID == walletID && a.CapitalizedAt == nil && a.Date.Before(before) {
+			s.interest.accruals[i].CapitalizedAt = &before
+			accruals--
+		}
+	}
+	if accruals != 0 {
+		return ErrCapitalizedConcurrently
+	}
+	s.interest.paid[walletID] = append(s.interest.paid[walletID], amount)
 	return s.err
 }
 
+func (s StubWallet) BalanceChecks() ([]BalanceCheck, error) {
+	return s.checks, s.err
+}
+
+func (s StubWallet) OpenCorrection(d Discrepancy) (int, error) {
+	*s.corrections = append(*s.corrections, d)
+	return len(*s.corrections), s.err
+}
+
+func (s StubWallet) Corrections(status string) ([]Correction, error) {
+	corrections := []Correction{}
+	for _, c := range s.tickets {
+		if status == "" || c.Status == status {
+			corrections = append(corrections, c)
+		}
+	}
+	return corrections, s.err
+}
+
+func (s StubWallet) ResolveCorrection(id int) (Correction, error) {
+	for _, c := range s.tickets {
+		if c.ID != id {
+			continue
+		}
+		if c.Status != CorrectionOpen {
+			return Correction{}, ErrCorrectionResolved
+		}
+		c.Status = CorrectionResolved
+		return c, s.err
+	}
+	return Correction{}, ErrCorrectionNotFound
+}
+
+func (s StubWallet) ChangeWalletStatus(id int, to, reason string) (Wallet, error) {
+	w, err := s.WalletByID(id)
+	if err != nil {
+		return Wallet{}, err
+	}
+	if err := w.CheckTransition(to); err != nil {
+		return Wallet{}, err
+	}
+	if to == WalletStatusClosed && slices.Contains(s.feeWallets, id) {
+		return Wallet{}, ErrFeeWallet
+	}
+	w.Status, w.StatusReason = to, reason
+	return w, nil
+}
+
+func (s StubWallet) StatusChanges(walletID int) ([]StatusChange, error) {
+	return []StatusChange{}, s.err
+}
+
+func (s StubWallet) CreateUser(u User) (User, error) {
+	u.ID = len(s.users) + 1
+	return u, s.err
+}
+
+func (s StubWallet) Users() ([]User, error) {
+	return s.users, s.err
+}
+
+func (s StubWallet) UserByID(id int) (User, error) {
+	for _, u := range s.users {
+		if u.ID == id {
+			return u, s.err
+		}
+	}
+	return User{}, ErrUserNotFound
+}
+
+func (s StubWallet) UpdateUser(id int, u User) (User, error) {
+	if _, err := s.UserByID(id); err != nil {
+		return User{}, err
+	}
+	u.ID = id
+	return u, s.err
+}
+
+func (s StubWallet) SetLimits(walletID int, limits SpendingLimits) (Wallet, error) {
+	w, err := s.WalletByID(walletID)
+	if err != nil {
+		return Wallet{}, err
+	}
+	w.Limits = limits
+	return w, s.err
+}
+
+func (s StubWallet) Spending(walletID int, now time.Time) (Spending, error) {
+	return s.spent, s.err
+}
+
+func (s StubWallet) DeleteUser(id int) error {
+	if _, err := s.UserByID(id); err != nil {
+		return err
+	}
+	for _, w := range s.wallet {
+		if w.UserID == id {
+			return ErrUserHasWallets
+		}
+	}
+	return s.err
+}
+
+func (s StubWallet) CreateFeeRule(r FeeRule) (FeeRule, error) {
+	r.ID = len(s.rules) + 1
+	return r, s.err
+}
+
+// FeeRules never fails, leaving s.err to the movement the rules price.
+func (s StubWallet) FeeRules() ([]FeeRule, error) {
+	return s.rules, nil
+}
+
+func (s StubWallet) UpdateFeeRule(id int, r FeeRule) (FeeRule, error) {
+	for _, existing := range s.rules {
+		if existing.ID == id {
+			r.ID = id
+			return r, s.err
+		}
+	}
+	return FeeRule{}, ErrFeeRuleNotFound
+}
+
+func (s StubWallet) DeleteFeeRule(id int) error {
+	for _, r := range s.rules {
+		if r.ID == id {
+			return s.err
+		}
+	}
+	return ErrFeeRuleNotFound
+}
+
+// HoldByID never fails, leaving s.err to the capture or release.
+func (s StubWallet) HoldByID(id int) (Hold, error) {
+	h := s.hold
+	h.ID = id
+	return h, nil
+}
+
+// JournalEntry never fails, leaving s.err to the reversal.
+func (s StubWallet) JournalEntry(id int) (JournalEntry, error) {
+	j := s.journal
+	j.ID = id
+	return j, nil
+}
+
+func (s StubWallet) ReverseTransaction(id int, req ReversalRequest) (Reversal, error) {
+	return s.reversal, s.err
+}
+
+func (s StubWallet) Members(walletID int) ([]Member, error) {
+	var members []Member
+	for _, m := range s.members {
+		if m.WalletID == walletID {
+			members = append(members, m)
+		}
+	}
+	return members, s.err
+}
+
+// Role makes every caller an owner unless the stub has members.
+func (s StubWallet) Role(walletID, userID int) (string, error) {
+	if s.members == nil {
+		return RoleOwner, nil
+	}
+	for _, m := range s.members {
+		if m.WalletID == walletID && m.UserID == userID {
+			return m.Role, s.err
+		}
+	}
+	return "", ErrMemberNotFound
+}
+
+func (s StubWallet) SetMember(walletID, userID int, role string) (Member, error) {
+	w, err := s.WalletByID(walletID)
+	if err != nil {
+		return Member{}, err
+	}
+	if w.UserID == userID && role != RoleOwner {
+		return Member{}, ErrPrimaryOwner
+	}
+	return Member{WalletID: walletID, UserID: userID, Role: role}, s.err
+}
+
+func (s StubWallet) RemoveMember(walletID, userID int) error {
+	w, err := s.WalletByID(walletID)
+	if err != nil {
+		return err
+	}
+	if w.UserID == userID {
+		return ErrPrimaryOwner
+	}
+	if _, err := s.Role(walletID, userID); err != nil {
+		return err
+	}
+	return s.err
+}
+
+func (s StubWallet) Categories(userID int) ([]Category, error) {
+	return s.categories, s.err
+}
+
+func (s StubWallet) CreateCategory(c Category) (Category, error) {
+	if _, err := s.UserByID(*c.UserID); err != nil {
+		return Category{}, err
+	}
+	for _, existing := range s.categories {
+		if strings.EqualFold(existing.Name, c.Name) {
+			return Category{}, ErrCategoryExists
+		}
+	}
+	c.ID = len(s.categories) + 1
+	return c, s.err
+}
+
+func (s StubWallet) DeleteCategory(userID, id int) error {
+	for _, c := range s.categories {
+		if c.ID == id && c.UserID != nil && *c.UserID == userID {
+			return s.err
+		}
+	}
+	return ErrCategoryNotFound
+}
+
+// CategoryRules never fails, leaving s.err to the transactions the rules
+// file.
+func (s StubWallet) CategoryRules(userID int) ([]CategoryRule, error) {
+	return s.catRules, nil
+}
+
+func (s StubWallet) CreateCategoryRule(r CategoryRule) (CategoryRule, error) {
+	for _, c := range s.categories {
+		if c.ID == r.CategoryID {
+			r.ID, r.Category = len(s.catRules)+1, c.Name
+			return r, s.err
+		}
+	}
+	return CategoryRule{}, ErrUnknownCategory
+}
+
+func (s StubWallet) DeleteCategoryRule(userID, id int) error {
+	for _, r := range s.catRules {
+		if r.ID == id && r.UserID != nil && *r.UserID == userID {
+			return s.err
+		}
+	}
+	return ErrCategoryRuleNotFound
+}
+
+func (s StubWallet) LabelTransaction(walletID, transactionID int, l Labels) (Transaction, error) {
+	for _, t := range s.transactions {
+		if t.ID == transactionID {
+			t.CategoryID, t.Tags = l.CategoryID, l.Tags
+			return t, s.err
+		}
+	}
+	return Transaction{}, ErrTransactionNotFound
+}
+
 func TestWallet(t *testing.T) {
 	t.Run("given unable to get wallets should return 500 and error message", func(t *testing.T) {
 		e := echo.New()
@@ -70,12 +757,12 @@ func TestWallet(t *testing.T) {
 		c.SetPath("/api/v1/wallets")
 
 		createdAt, _ := time.Parse(time.RFC3339, "2024-03-25T14:19:00.729237Z")
-		stubError := StubWallet{wallet: []Wallet{{ID: 1, UserID: 1, UserName: "John Doe", WalletName: "John's Savings", WalletType: "Savings", Balance: 1000.00, CreatedAt: createdAt}}}
+		stubError := StubWallet{wallet: []Wallet{{ID: 1, UserID: 1, User: User{ID: 1, Name: "John Doe"}, WalletName: "John's Savings", WalletType: "Savings", Balance: NewMoney(100000, "THB"), CreatedAt: createdAt}}}
 		p := New(stubError)
 
 		p.GetAllWalletsHandler(c)
 
-		want := []Wallet{{ID: 1, UserID: 1, UserName: "John Doe", WalletName: "John's Savings", WalletType: "Savings", Balance: 1000.00, CreatedAt: createdAt}}
+		want := []Wallet{{ID: 1, UserID: 1, User: User{ID: 1, Name: "John Doe"}, WalletName: "John's Savings", WalletType: "Savings", Balance: NewMoney(100000, "THB"), CreatedAt: createdAt}}
 		gotJSON := rec.Body.Bytes()
 		var got []Wallet
 		if err := json.Unmarshal(gotJSON, &got); err != nil {
@@ -97,12 +784,12 @@ func TestWallet(t *testing.T) {
 		c.SetParamValues("Savings")
 
 		createdAt, _ := time.Parse(time.RFC3339, "2024-03-25T14:19:00.729237Z")
-		stubError := StubWallet{wallet: []Wallet{{ID: 1, UserID: 1, UserName: "John Doe", WalletName: "John's Savings", WalletType: "Savings", Balance: 1000.00, CreatedAt: createdAt}}}
+		stubError := StubWallet{wallet: []Wallet{{ID: 1, UserID: 1, User: User{ID: 1, Name: "John Doe"}, WalletName: "John's Savings", WalletType: "Savings", Balance: NewMoney(100000, "THB"), CreatedAt: createdAt}}}
 		p := New(stubError)
 
 		p.GetAllWalletsHandler(c)
 
-		want := []Wallet{{ID: 1, UserID: 1, UserName: "John Doe", WalletName: "John's Savings", WalletType: "Savings", Balance: 1000.00, CreatedAt: createdAt}}
+		want := []Wallet{{ID: 1, UserID: 1, User: User{ID: 1, Name: "John Doe"}, WalletName: "John's Savings", WalletType: "Savings", Balance: NewMoney(100000, "THB"), CreatedAt: createdAt}}
 		gotJSON := rec.Body.Bytes()
 		var got []Wallet
 		if err := json.Unmarshal(gotJSON, &got); err != nil {
@@ -124,12 +811,12 @@ func TestWallet(t *testing.T) {
 		c.SetParamValues("1")
 
 		createdAt, _ := time.Parse(time.RFC3339, "2024-03-25T14:19:00.729237Z")
-		stubError := StubWallet{wallet: []Wallet{{ID: 1, UserID: 1, UserName: "John Doe", WalletName: "John's Savings", WalletType: "Savings", Balance: 1000.00, CreatedAt: createdAt}}}
+		stubError := StubWallet{wallet: []Wallet{{ID: 1, UserID: 1, User: User{ID: 1, Name: "John Doe"}, WalletName: "John's Savings", WalletType: "Savings", Balance: NewMoney(100000, "THB"), CreatedAt: createdAt}}}
 		p := New(stubError)
 
 		p.GetWalletByIDHandler(c)
 
-		want := []Wallet{{ID: 1, UserID: 1, UserName: "John Doe", WalletName: "John's Savings", WalletType: "Savings", Balance: 1000.00, CreatedAt: createdAt}}
+		want := []Wallet{{ID: 1, UserID: 1, User: User{ID: 1, Name: "John Doe"}, WalletName: "John's Savings", WalletType: "Savings", Balance: NewMoney(100000, "THB"), CreatedAt: createdAt}}
 		gotJSON := rec.Body.Bytes()
 		var got []Wallet
 		if err := json.Unmarshal(gotJSON, &got); err != nil {
@@ -167,7 +854,7 @@ func TestWallet(t *testing.T) {
 		c.SetPath("/api/v1/wallets")
 
 		createdAt, _ := time.Parse(time.RFC3339, "2024-03-25T14:19:00.729237Z")
-		stubError := StubWallet{wallet: []Wallet{{ID: 1, UserID: 1, UserName: "John Doe", WalletName: "John's Savings", WalletType: "Savings", Balance: 1000.00, CreatedAt: createdAt}}}
+		stubError := StubWallet{wallet: []Wallet{{ID: 1, UserID: 1, User: User{ID: 1, Name: "John Doe"}, WalletName: "John's Savings", WalletType: "Savings", Balance: NewMoney(100000, "THB"), CreatedAt: createdAt}}}
 		p := New(stubError)
 
 		p.CreateWalletHandler(c)
@@ -181,6 +868,8 @@ func TestWallet(t *testing.T) {
 		e := echo.New()
 		req := httptest.NewRequest(http.MethodPut, "/", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		req.Header.Set("If-Match", "*")
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 		c.SetPath("/api/v1/wallets/:id")
@@ -200,8 +889,10 @@ func TestWallet(t *testing.T) {
 
 	t.Run("given user able to update wallet should return 200", func(t *testing.T) {
 		e := echo.New()
-		req := httptest.NewRequest(http.MethodPut, "/", nil)
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"user_id": 1, "wallet_name": "Rainy Day", "wallet_type": "Savings"}`))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		req.Header.Set("If-Match", `"1"`)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 		c.SetPath("/api/v1/wallets/:id")
@@ -209,7 +900,7 @@ func TestWallet(t *testing.T) {
 		c.SetParamValues("1")
 
 		createdAt, _ := time.Parse(time.RFC3339, "2024-03-25T14:19:00.729237Z")
-		stubError := StubWallet{wallet: []Wallet{{ID: 1, UserID: 1, UserName: "John Doe", WalletName: "John's Savings", WalletType: "Savings", Balance: 1000.00, CreatedAt: createdAt}}}
+		stubError := StubWallet{wallet: []Wallet{{ID: 1, UserID: 1, User: User{ID: 1, Name: "John Doe"}, WalletName: "John's Savings", WalletType: "Savings", Balance: NewMoney(100000, "THB"), Version: 1, CreatedAt: createdAt}}}
 		p := New(stubError)
 
 		p.UpdateWalletHandler(c)
@@ -223,6 +914,7 @@ func TestWallet(t *testing.T) {
 		e := echo.New()
 		req := httptest.NewRequest(http.MethodDelete, "/", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 		c.SetPath("/api/v1/users/:id/wallets")
@@ -243,6 +935,7 @@ func TestWallet(t *testing.T) {
 		e := echo.New()
 		req := httptest.NewRequest(http.MethodDelete, "/", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 		c.SetPath("/api/v1/users/:id/wallets")
@@ -250,7 +943,7 @@ func TestWallet(t *testing.T) {
 		c.SetParamValues("1")
 
 		createdAt, _ := time.Parse(time.RFC3339, "2024-03-25T14:19:00.729237Z")
-		stubError := StubWallet{wallet: []Wallet{{ID: 1, UserID: 1, UserName: "John Doe", WalletName: "John's Savings", WalletType: "Savings", Balance: 1000.00, CreatedAt: createdAt}}}
+		stubError := StubWallet{wallet: []Wallet{{ID: 1, UserID: 1, User: User{ID: 1, Name: "John Doe"}, WalletName: "John's Savings", WalletType: "Savings", CreatedAt: createdAt}}}
 		p := New(stubError)
 
 		p.DeleteWalletByIDHandler(c)
@@ -259,4 +952,547 @@ func TestWallet(t *testing.T) {
 			t.Errorf("expected status code %d but got %d", http.StatusNoContent, rec.Code)
 		}
 	})
+
+	t.Run("given user with a funded wallet should return 409", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/users/:id/wallets")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		p := New(StubWallet{wallet: []Wallet{
+			{ID: 1, UserID: 1, WalletName: "John's Savings", WalletType: "Savings"},
+			{ID: 2, UserID: 1, WalletName: "John's Current", WalletType: "Savings", Balance: NewMoney(100000, "THB"), Available: NewMoney(100000, "THB")},
+		}})
+
+		p.DeleteWalletByIDHandler(c)
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rec.Code)
+		}
+	})
+
+	t.Run("given user with a house fee wallet should return 409", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/users/:id/wallets")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		p := New(StubWallet{wallet: []Wallet{{ID: 1, UserID: 1, WalletName: "House THB", WalletType: "Savings"}}, feeWallets: []int{1}})
+
+		p.DeleteWalletByIDHandler(c)
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rec.Code)
+		}
+	})
+}
+
+func TestSingleWallet(t *testing.T) {
+	wallets := []Wallet{
+		{ID: 1, UserID: 1, WalletName: "John's Savings", WalletType: "Savings", Currency: "THB", Balance: NewMoney(100000, "THB"), Available: NewMoney(100000, "THB"), Version: 2},
+		{ID: 2, UserID: 1, WalletName: "John's Spare", WalletType: "Savings", Currency: "THB", Version: 2},
+		{ID: 3, UserID: 1, WalletName: "John's Held", WalletType: "Savings", Currency: "THB", Balance: NewMoney(100000, "THB"), Version: 2},
+	}
+	tests := []struct {
+		name    string
+		method  string
+		id      string
+		handler func(*Handler) echo.HandlerFunc
+		want    int
+	}{
+		{"existing wallet", http.MethodGet, "1", func(h *Handler) echo.HandlerFunc { return h.GetWalletHandler }, http.StatusOK},
+		{"unknown wallet", http.MethodGet, "9", func(h *Handler) echo.HandlerFunc { return h.GetWalletHandler }, http.StatusNotFound},
+		{"invalid id", http.MethodGet, "one", func(h *Handler) echo.HandlerFunc { return h.GetWalletHandler }, http.StatusBadRequest},
+		{"delete of empty wallet", http.MethodDelete, "2", func(h *Handler) echo.HandlerFunc { return h.DeleteWalletHandler }, http.StatusNoContent},
+		{"delete of funded wallet", http.MethodDelete, "1", func(h *Handler) echo.HandlerFunc { return h.DeleteWalletHandler }, http.StatusConflict},
+		{"delete of wallet with money held", http.MethodDelete, "3", func(h *Handler) echo.HandlerFunc { return h.DeleteWalletHandler }, http.StatusConflict},
+		{"delete of unknown wallet", http.MethodDelete, "9", func(h *Handler) echo.HandlerFunc { return h.DeleteWalletHandler }, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should return "+http.StatusText(tt.want), func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(tt.method, "/", nil)
+			req.Header.Set(HeaderUserID, "1")
+			req.Header.Set("If-Match", `"2"`)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/wallets/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+
+			tt.handler(New(StubWallet{wallet: wallets}))(c)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status code %d but got %d", tt.want, rec.Code)
+			}
+		})
+	}
+
+	t.Run("given existing wallet should return it", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		New(StubWallet{wallet: wallets}).GetWalletHandler(c)
+
+		var got Wallet
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if !reflect.DeepEqual(got, wallets[0]) {
+			t.Errorf("expected %+v but got %+v", wallets[0], got)
+		}
+	})
+}
+
+var transferWallets = []Wallet{
+	{ID: 1, UserID: 1, User: User{ID: 1, Name: "John Doe"}, WalletName: "John's Savings", WalletType: "Savings", Currency: "THB", Balance: NewMoney(100000, "THB")},
+	{ID: 4, UserID: 2, User: User{ID: 2, Name: "Jane Doe"}, WalletName: "Jane's Savings", WalletType: "Savings", Currency: "THB", Balance: NewMoney(200000, "THB")},
+	{ID: 7, UserID: 2, User: User{ID: 2, Name: "Jane Doe"}, WalletName: "Jane's Dollars", WalletType: "Savings", Currency: "USD", Balance: NewMoney(50000, "USD")},
+}
+
+func TestTransfer(t *testing.T) {
+	t.Run("given invalid transfer should return 400", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"from_wallet_id": 1, "to_wallet_id": 1, "amount": 100}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/transfers")
+
+		p := New(StubWallet{})
+
+		p.TransferHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("given insufficient funds should return 422", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"from_wallet_id": 1, "to_wallet_id": 4, "amount": 100}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/transfers")
+
+		p := New(StubWallet{wallet: transferWallets, err: ErrInsufficientFunds})
+
+		p.TransferHandler(c)
+
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d but got %d", http.StatusUnprocessableEntity, rec.Code)
+		}
+	})
+
+	t.Run("given unknown wallet should return 404", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"from_wallet_id": 1, "to_wallet_id": 99, "amount": 100}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/transfers")
+
+		p := New(StubWallet{wallet: transferWallets})
+
+		p.TransferHandler(c)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+
+	t.Run("given user able to transfer should return both balances", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"from_wallet_id": 1, "to_wallet_id": 4, "amount": 100}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/transfers")
+
+		want := TransferResult{
+			From:     Wallet{ID: 1, UserID: 1, User: User{ID: 1, Name: "John Doe"}, WalletName: "John's Savings", WalletType: "Savings", Currency: "THB", Balance: NewMoney(90000, "THB")},
+			To:       Wallet{ID: 4, UserID: 2, User: User{ID: 2, Name: "Jane Doe"}, WalletName: "Jane's Savings", WalletType: "Savings", Currency: "THB", Balance: NewMoney(210000, "THB")},
+			Credited: NewMoney(10000, "THB"),
+		}
+		p := New(StubWallet{wallet: transferWallets, transfer: TransferResult{From: want.From, To: want.To}})
+
+		p.TransferHandler(c)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, rec.Code)
+		}
+		var got TransferResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("unable to unmarshal json %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+}
+
+func TestTransferAcrossCurrencies(t *testing.T) {
+	t.Run("given wallets in different currencies should convert at provider rate", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"from_wallet_id": 7, "to_wallet_id": 4, "amount": 100}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/transfers")
+
+		p := New(StubWallet{wallet: transferWallets}, WithRates(StubRates{"USD/THB": big.NewRat(73, 2)}))
+
+		p.TransferHandler(c)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rec.Code)
+		}
+		var got TransferResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("unable to unmarshal json %v", err)
+		}
+		if got.Credited.String() != "3650.00" || got.Rate != "36.5" {
+			t.Errorf("expected 3650.00 at 36.5 but got %s at %s", got.Credited, got.Rate)
+		}
+	})
+
+	t.Run("given no rate provider should return 422", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"from_wallet_id": 7, "to_wallet_id": 4, "amount": 100}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/transfers")
+
+		p := New(StubWallet{wallet: transferWallets})
+
+		p.TransferHandler(c)
+
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d but got %d", http.StatusUnprocessableEntity, rec.Code)
+		}
+	})
+}
+
+func TestJournalEntryValidate(t *testing.T) {
+	t.Run("given lines that do not sum to zero should fail", func(t *testing.T) {
+		entry := JournalEntry{Type: TransactionAdjustment, Description: "Adjustment", Lines: []LedgerLine{
+			WalletLine(1, *thb("10.10")),
+			AccountLine(AccountEquity, *thb("-10.00")),
+		}}
+
+		if err := entry.Validate(); !errors.Is(err, ErrUnbalancedJournal) {
+			t.Errorf("expected error %v but got %v", ErrUnbalancedJournal, err)
+		}
+	})
+
+	t.Run("given balanced lines should pass", func(t *testing.T) {
+		entry := JournalEntry{Type: TransactionAdjustment, Description: "Adjustment", Lines: []LedgerLine{
+			WalletLine(1, *thb("0.10")),
+			WalletLine(2, *thb("0.20")),
+			AccountLine(AccountEquity, *thb("-0.30")),
+		}}
+
+		if err := entry.Validate(); err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+}
+
+func TestDepositAndWithdrawal(t *testing.T) {
+	t.Run("given user able to deposit should return 201 and transaction", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 250.50, "reference": "INV-1"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/deposits")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		createdAt, _ := time.Parse(time.RFC3339, "2024-03-25T14:19:00.729237Z")
+		want := Transaction{ID: 9, WalletID: 1, Type: TransactionDeposit, Amount: NewMoney(25050, "THB"), Currency: "THB", Reference: "INV-1", CreatedAt: createdAt}
+		p := New(StubWallet{wallet: transferWallets, transaction: want})
+
+		p.DepositHandler(c)
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("expected status code %d but got %d", http.StatusCreated, rec.Code)
+		}
+		var got Transaction
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("unable to unmarshal json %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given non-positive amount should return 400", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": -5}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/deposits")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		p := New(StubWallet{wallet: transferWallets})
+
+		p.DepositHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("given unknown wallet should return 404", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 5}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/withdrawals")
+		c.SetParamNames("id")
+		c.SetParamValues("99")
+
+		p := New(StubWallet{wallet: transferWallets})
+
+		p.WithdrawalHandler(c)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+
+	t.Run("given insufficient funds should return 422", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 5000}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/withdrawals")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		p := New(StubWallet{wallet: transferWallets, err: ErrInsufficientFunds})
+
+		p.WithdrawalHandler(c)
+
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d but got %d", http.StatusUnprocessableEntity, rec.Code)
+		}
+	})
+}
+
+func TestTransactions(t *testing.T) {
+	history := []Transaction{
+		{ID: 12, WalletID: 1, Type: TransactionWithdrawal, Amount: NewMoney(-5000, "THB"), Currency: "THB"},
+		{ID: 10, WalletID: 1, Type: TransactionTransfer, Amount: NewMoney(-10000, "THB"), Currency: "THB"},
+		{ID: 7, WalletID: 1, Type: TransactionDeposit, Amount: NewMoney(25000, "THB"), Currency: "THB"},
+	}
+
+	t.Run("given more transactions than the limit should return a page and next cursor", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/?limit=2", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/transactions")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		p := New(StubWallet{wallet: transferWallets, transactions: history})
+
+		p.TransactionsHandler(c)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rec.Code)
+		}
+		var got TransactionPage
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("unable to unmarshal json %v", err)
+		}
+		if !reflect.DeepEqual(got.Transactions, history[:2]) {
+			t.Errorf("expected %v but got %v", history[:2], got.Transactions)
+		}
+		if cursor, err := decodeCursor(got.NextCursor); err != nil || cursor != 10 {
+			t.Errorf("expected cursor for id 10 but got %q", got.NextCursor)
+		}
+	})
+
+	t.Run("given last page should return no next cursor", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/?limit=5&type=deposit,withdrawal&min_amount=10&from=2024-03-01T00:00:00Z", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/transactions")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		p := New(StubWallet{wallet: transferWallets, transactions: history})
+
+		p.TransactionsHandler(c)
+
+		var got TransactionPage
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("unable to unmarshal json %v", err)
+		}
+		if len(got.Transactions) != 3 || got.NextCursor != "" {
+			t.Errorf("expected 3 transactions and no cursor but got %d and %q", len(got.Transactions), got.NextCursor)
+		}
+	})
+
+	t.Run("given invalid filter should return 400", func(t *testing.T) {
+		for _, query := range []string{"limit=0", "limit=101", "cursor=!!", "type=refund", "min_amount=abc", "to=yesterday"} {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/wallets/:id/transactions")
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			p := New(StubWallet{wallet: transferWallets})
+
+			p.TransactionsHandler(c)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status code %d but got %d", query, http.StatusBadRequest, rec.Code)
+			}
+		}
+	})
+}
+
+func TestHolds(t *testing.T) {
+	t.Run("given available balance should place hold and return 201", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 150, "reference": "AUTH-1", "expires_in": 3600}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/holds")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		want := Hold{ID: 3, WalletID: 1, Amount: NewMoney(15000, "THB"), Captured: NewMoney(0, "THB"), Currency: "THB", Status: HoldActive, Reference: "AUTH-1"}
+		p := New(StubWallet{wallet: transferWallets, hold: want})
+
+		p.PlaceHoldHandler(c)
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("expected status code %d but got %d", http.StatusCreated, rec.Code)
+		}
+		var got Hold
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Errorf("unable to unmarshal json %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given expiry beyond 30 days should return 400", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 150, "expires_in": 31536000}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/holds")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		p := New(StubWallet{wallet: transferWallets})
+
+		p.PlaceHoldHandler(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("given hold amount above available balance should return 422", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 1000000}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/wallets/:id/holds")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		p := New(StubWallet{wallet: transferWallets, err: ErrInsufficientFunds})
+
+		p.PlaceHoldHandler(c)
+
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d but got %d", http.StatusUnprocessableEntity, rec.Code)
+		}
+	})
+
+	t.Run("given hold no longer active should not capture and return 409", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 100}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/holds/:id/capture")
+		c.SetParamNames("id")
+		c.SetParamValues("3")
+
+		p := New(StubWallet{err: ErrHoldNotActive})
+
+		p.CaptureHoldHandler(c)
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rec.Code)
+		}
+	})
+
+	t.Run("given unknown hold should not release and return 404", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set(HeaderUserID, "1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/v1/holds/:id/release")
+		c.SetParamNames("id")
+		c.SetParamValues("99")
+
+		p := New(StubWallet{err: ErrHoldNotFound})
+
+		p.ReleaseHoldHandler(c)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rec.Code)
+		}
+	})
 }